@@ -0,0 +1,106 @@
+package auth
+
+import (
+	v1 "app/api/http/v1"
+	"app/middleware"
+	"app/model/input"
+	"app/serv"
+	"app/util/httputil"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ApiKeyContro struct {
+	apiKeyServ serv.ApiKeyServ
+}
+
+func NewApiKeyController(apiKeyServ serv.ApiKeyServ) v1.BaseContro {
+	return &ApiKeyContro{
+		apiKeyServ: apiKeyServ,
+	}
+}
+
+// RegisterRoute 密钥管理只接受访问令牌，避免泄露的密钥签发新的密钥
+func (o *ApiKeyContro) RegisterRoute(api fiber.Router) {
+	api.Get("/apikey", middleware.JwtAuth(), o.Select)
+	api.Post("/apikey", middleware.JwtAuth(), o.Insert)
+	api.Delete("/apikey/:id", middleware.JwtAuth(), o.Revoke)
+	api.Get("/user/:id/apikeys", middleware.JwtAuth(), middleware.RequirePermission("apikey:manage"), o.SelectByUserId)
+}
+
+func (o *ApiKeyContro) Name() string {
+	return "ApiKey"
+}
+
+// Select @Summary		查找 API 密钥
+// @Description	查找当前用户的 API 密钥，不返回密钥明文
+// @Tags			apikey
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Router			/apikey	[get]
+func (o *ApiKeyContro) Select(c *fiber.Ctx) error {
+	apiKeys, err := o.apiKeyServ.Select(c)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, apiKeys)
+}
+
+// Insert @Summary		签发 API 密钥
+// @Description	为当前用户签发 API 密钥，密钥明文只在创建时返回一次，调用接口时通过 X-API-Key 请求头传入
+// @Tags			apikey
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			apikey	body		input.ApiKeyInput	true	"密钥信息"
+// @Router			/apikey	[post]
+func (o *ApiKeyContro) Insert(c *fiber.Ctx) error {
+	apiKeyInput, err := httputil.BindBody[input.ApiKeyInput](c)
+	if err != nil {
+		return err
+	}
+	apiKey, err := o.apiKeyServ.Insert(c, apiKeyInput)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, apiKey)
+}
+
+// Revoke @Summary		吊销 API 密钥
+// @Description	吊销 API 密钥，立即生效；拥有 apikey:manage 权限的用户可以吊销其他用户的密钥
+// @Tags			apikey
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			id		path		int	true	"密钥的 id"
+// @Router			/apikey/{id}	[delete]
+func (o *ApiKeyContro) Revoke(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.IdParam](c)
+	if err != nil {
+		return err
+	}
+	if err := o.apiKeyServ.Revoke(c, param.Id); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
+
+// SelectByUserId @Summary		查找用户的 API 密钥
+// @Description	查找指定用户的 API 密钥，不返回密钥明文
+// @Tags			apikey
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			id		path		int	true	"用户的 id"
+// @Router			/user/{id}/apikeys	[get]
+func (o *ApiKeyContro) SelectByUserId(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.IdParam](c)
+	if err != nil {
+		return err
+	}
+	apiKeys, err := o.apiKeyServ.SelectByUserId(c, param.Id)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, apiKeys)
+}
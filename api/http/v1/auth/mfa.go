@@ -0,0 +1,122 @@
+package auth
+
+import (
+	v1 "app/api/http/v1"
+	"app/middleware"
+	"app/model/input"
+	"app/serv"
+	"app/util/httputil"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MfaContro struct {
+	mfaServ serv.MfaServ
+}
+
+func NewMfaController(mfaServ serv.MfaServ) v1.BaseContro {
+	return &MfaContro{
+		mfaServ: mfaServ,
+	}
+}
+
+// RegisterRoute 两步验证的管理只接受访问令牌
+func (o *MfaContro) RegisterRoute(api fiber.Router) {
+	api.Get("/me/mfa", middleware.JwtAuth(), o.Status)
+	api.Post("/me/mfa/totp", middleware.JwtAuth(), o.Enroll)
+	api.Post("/me/mfa/totp/confirm", middleware.JwtAuth(), o.Confirm)
+	api.Delete("/me/mfa/totp", middleware.JwtAuth(), o.Disable)
+	api.Post("/me/mfa/recovery-codes", middleware.JwtAuth(), o.RegenerateRecoveryCodes)
+}
+
+func (o *MfaContro) Name() string {
+	return "Mfa"
+}
+
+// Status @Summary		两步验证状态
+// @Description	当前用户是否已启用两步验证与剩余可用的恢复码数量
+// @Tags			mfa
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Router			/me/mfa	[get]
+func (o *MfaContro) Status(c *fiber.Ctx) error {
+	status, err := o.mfaServ.Status(c)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, status)
+}
+
+// Enroll @Summary		绑定认证器
+// @Description	生成 TOTP 密钥与 otpauth 地址，使用认证器中的验证码确认后才会启用两步验证
+// @Tags			mfa
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Router			/me/mfa/totp	[post]
+func (o *MfaContro) Enroll(c *fiber.Ctx) error {
+	enrollment, err := o.mfaServ.Enroll(c)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, enrollment)
+}
+
+// Confirm @Summary		启用两步验证
+// @Description	使用认证器中的验证码确认绑定，返回恢复码明文，只返回一次
+// @Tags			mfa
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			code	body		input.MfaCode	true	"验证码"
+// @Router			/me/mfa/totp/confirm	[post]
+func (o *MfaContro) Confirm(c *fiber.Ctx) error {
+	in, err := httputil.BindBody[input.MfaCode](c)
+	if err != nil {
+		return err
+	}
+	codes, err := o.mfaServ.Confirm(c, in)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, codes)
+}
+
+// Disable @Summary		关闭两步验证
+// @Description	使用验证码或恢复码关闭两步验证，同时删除所有恢复码
+// @Tags			mfa
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			code	body		input.MfaCode	true	"验证码或恢复码"
+// @Router			/me/mfa/totp	[delete]
+func (o *MfaContro) Disable(c *fiber.Ctx) error {
+	in, err := httputil.BindBody[input.MfaCode](c)
+	if err != nil {
+		return err
+	}
+	if err := o.mfaServ.Disable(c, in); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
+
+// RegenerateRecoveryCodes @Summary		重新生成恢复码
+// @Description	使用验证码或恢复码重新生成恢复码，原有的恢复码全部失效
+// @Tags			mfa
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			code	body		input.MfaCode	true	"验证码或恢复码"
+// @Router			/me/mfa/recovery-codes	[post]
+func (o *MfaContro) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	in, err := httputil.BindBody[input.MfaCode](c)
+	if err != nil {
+		return err
+	}
+	codes, err := o.mfaServ.RegenerateRecoveryCodes(c, in)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, codes)
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	v1 "app/api/http/v1"
+	"app/middleware"
+	"app/model/input"
+	"app/serv"
+	"app/util/httputil"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RoleContro struct {
+	roleServ serv.RoleServ
+}
+
+func NewRoleController(roleServ serv.RoleServ) v1.BaseContro {
+	return &RoleContro{
+		roleServ: roleServ,
+	}
+}
+
+func (o *RoleContro) RegisterRoute(api fiber.Router) {
+	api.Get("/role", middleware.Authenticate(), middleware.RequirePermission("role:read"), o.Select)
+	api.Post("/role", middleware.Authenticate(), middleware.RequirePermission("role:manage"), o.Insert)
+	api.Put("/role/:id", middleware.Authenticate(), middleware.RequirePermission("role:manage"), o.Update)
+	api.Delete("/role/:id", middleware.Authenticate(), middleware.RequirePermission("role:manage"), o.Delete)
+	api.Get("/permission", middleware.Authenticate(), middleware.RequirePermission("role:read"), o.SelectPermissions)
+	api.Get("/user/:id/roles", middleware.Authenticate(), middleware.RequirePermission("role:read"), o.SelectUserRoles)
+	api.Put("/user/:id/roles", middleware.Authenticate(), middleware.RequirePermission("role:manage"), o.SetUserRoles)
+}
+
+func (o *RoleContro) Name() string {
+	return "Role"
+}
+
+// Select @Summary		查找角色
+// @Description	查找所有角色及其权限
+// @Tags			role
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Router			/role	[get]
+func (o *RoleContro) Select(c *fiber.Ctx) error {
+	roles, err := o.roleServ.Select(c)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, roles)
+}
+
+// Insert @Summary		新增角色
+// @Description	新增角色并设置权限
+// @Tags			role
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			role	body		input.RoleInput	true	"角色信息"
+// @Router			/role	[post]
+func (o *RoleContro) Insert(c *fiber.Ctx) error {
+	role, err := httputil.BindBody[input.RoleInput](c)
+	if err != nil {
+		return err
+	}
+	if err := o.roleServ.Insert(c, role); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
+
+// Update @Summary		更新角色
+// @Description	更新角色并替换其权限，管理员角色只允许修改描述
+// @Tags			role
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			id		path		int	true	"角色的 id"
+// @Param			role	body		input.RoleInput	true	"角色信息"
+// @Router			/role/{id}	[put]
+func (o *RoleContro) Update(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.IdParam](c)
+	if err != nil {
+		return err
+	}
+	role, err := httputil.BindBody[input.RoleInput](c)
+	if err != nil {
+		return err
+	}
+	if err := o.roleServ.Update(c, param.Id, role); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
+
+// Delete @Summary		删除角色
+// @Description	删除角色及其权限与用户关联，管理员角色不允许删除
+// @Tags			role
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			id		path		int	true	"角色的 id"
+// @Router			/role/{id}	[delete]
+func (o *RoleContro) Delete(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.IdParam](c)
+	if err != nil {
+		return err
+	}
+	if err := o.roleServ.Delete(c, param.Id); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
+
+// SelectPermissions @Summary		查找权限
+// @Description	查找所有权限
+// @Tags			role
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Router			/permission	[get]
+func (o *RoleContro) SelectPermissions(c *fiber.Ctx) error {
+	permissions, err := o.roleServ.SelectPermissions(c)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, permissions)
+}
+
+// SelectUserRoles @Summary		查找用户角色
+// @Description	查找用户拥有的角色
+// @Tags			role
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			id		path		int	true	"用户的 id"
+// @Router			/user/{id}/roles	[get]
+func (o *RoleContro) SelectUserRoles(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.IdParam](c)
+	if err != nil {
+		return err
+	}
+	roles, err := o.roleServ.SelectUserRoles(c, param.Id)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, roles)
+}
+
+// SetUserRoles @Summary		分配用户角色
+// @Description	替换用户的角色，用户重新登录或刷新令牌后生效
+// @Tags			role
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			id		path		int	true	"用户的 id"
+// @Param			roles	body		input.UserRoles	true	"角色编号列表"
+// @Router			/user/{id}/roles	[put]
+func (o *RoleContro) SetUserRoles(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.IdParam](c)
+	if err != nil {
+		return err
+	}
+	userRoles, err := httputil.BindBody[input.UserRoles](c)
+	if err != nil {
+		return err
+	}
+	if err := o.roleServ.SetUserRoles(c, param.Id, userRoles.RoleIds); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
@@ -2,15 +2,13 @@ package auth
 
 import (
 	v1 "app/api/http/v1"
-	"app/code"
-	"app/log"
 	"app/middleware"
 	"app/model"
 	"app/model/input"
+	"app/model/output"
 	"app/serv"
 	"app/util/httputil"
 	"github.com/gofiber/fiber/v2"
-	"strconv"
 )
 
 type UserContro struct {
@@ -23,12 +21,16 @@ func NewUserController(userServ serv.UserServ) v1.BaseContro {
 	}
 }
 func (o *UserContro) RegisterRoute(api fiber.Router) {
-	api.Post("/user", middleware.JwtAuth(), o.Insert)
-	api.Delete("/user/:id", middleware.JwtAuth(), o.Delete)
-	api.Put("/user", middleware.JwtAuth(), o.Update)
-	api.Get("/user", middleware.JwtAuth(), o.Select)
-	api.Get("/user/:id", middleware.JwtAuth(), o.SelectById)
-	api.Get("/user/pagination/:size/:page", middleware.JwtAuth(), o.SelectWithPagination)
+	api.Post("/user", middleware.Authenticate(), middleware.RequirePermission("user:create"), o.Insert)
+	api.Delete("/user/:id", middleware.Authenticate(), middleware.RequirePermission("user:delete"), o.Delete)
+	api.Get("/me", middleware.Authenticate(), o.Me)
+	api.Put("/user", middleware.Authenticate(), o.Update)
+	api.Get("/user", middleware.Authenticate(), middleware.RequirePermission("user:read"), o.Select)
+	api.Get("/user/trashed", middleware.Authenticate(), middleware.RequirePermission("user:restore"), o.SelectTrashed)
+	api.Put("/user/:id/restore", middleware.Authenticate(), middleware.RequirePermission("user:restore"), o.Restore)
+	api.Put("/user/:id/unlock", middleware.Authenticate(), middleware.RequirePermission("user:unlock"), o.Unlock)
+	api.Get("/user/:id", middleware.Authenticate(), middleware.RequirePermission("user:read"), o.SelectById)
+	api.Get("/user/pagination/:size/:page", middleware.Authenticate(), middleware.RequirePermission("user:read"), o.SelectWithPagination)
 }
 
 func (o *UserContro) Name() string {
@@ -41,18 +43,16 @@ func (o *UserContro) Name() string {
 // @Accept			json
 // @Produce		json
 // @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
-// @Param			user	body		model.User	true	"用户信息"
+// @Param			user	body		input.UserCreate	true	"用户信息"
 // @Router			/user	[post]
 func (o *UserContro) Insert(c *fiber.Ctx) error {
-	user := new(model.User)
-	if err := c.BodyParser(user); err != nil {
-		log.F(c).Error(err)
-		return code.ParamError
-	}
-	err := o.userServ.Insert(c, user)
+	in, err := httputil.BindBody[input.UserCreate](c)
 	if err != nil {
 		return err
 	}
+	if err := o.userServ.Insert(c, &model.User{Username: in.Username, Password: in.Password}); err != nil {
+		return err
+	}
 	return httputil.JsonSuccess(c, "")
 }
 
@@ -65,41 +65,47 @@ func (o *UserContro) Insert(c *fiber.Ctx) error {
 // @Param			id		path		int	true	"用户的 id"
 // @Router			/user/{id}	[delete]
 func (o *UserContro) Delete(c *fiber.Ctx) error {
-	idStr := c.Params("id")
-	if idStr == "" {
-		return code.ParamError
-	}
-	id, err := strconv.Atoi(idStr)
+	param, err := httputil.BindParams[input.IdParam](c)
 	if err != nil {
-		log.F(c).Error(err)
-		return code.ParamError
+		return err
 	}
-	if err := o.userServ.Delete(c, id); err != nil {
-		log.F(c).Error(err)
-		return code.ParamError
+	if err := o.userServ.Delete(c, param.Id); err != nil {
+		return err
 	}
 	return httputil.JsonSuccess(c, nil)
 }
 
+// Me @Summary		当前用户
+// @Description	查询当前登录用户的信息与角色
+// @Tags			user
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Router			/me	[get]
+func (o *UserContro) Me(c *fiber.Ctx) error {
+	me, err := o.userServ.Me(c)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, me)
+}
+
 // Update @Summary		更新用户
-// @Description	更新用户
+// @Description	更新用户，没有 user:update 权限时只能更新自己
 // @Tags			user
 // @Accept			json
 // @Produce		json
 // @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
-// @Param			user	body		model.User	true	"用户信息"
+// @Param			user	body		input.UserUpdate	true	"用户信息"
 // @Router			/user	[put]
 func (o *UserContro) Update(c *fiber.Ctx) error {
-	user := &model.User{}
-	if err := c.BodyParser(user); err != nil {
-		log.F(c).Error(err)
-		return code.ParamError
-	}
-
-	err := o.userServ.Update(c, user)
+	in, err := httputil.BindBody[input.UserUpdate](c)
 	if err != nil {
 		return err
 	}
+	if err := o.userServ.Update(c, &model.User{Id: in.Id, Username: in.Username, Password: in.Password}); err != nil {
+		return err
+	}
 	return httputil.JsonSuccess(c, nil)
 }
 
@@ -109,21 +115,100 @@ func (o *UserContro) Update(c *fiber.Ctx) error {
 // @Accept			json
 // @Produce		json
 // @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
-// @Param	username	query	string	false	"用户账户"
+// @Param	username	query	string	false	"用户账户，模糊匹配"
+// @Param	ids	query	[]int	false	"编号列表"	collectionFormat(multi)
+// @Param	createdFrom	query	string	false	"创建时间起，如 2006-01-02 15:04:05"
+// @Param	createdTo	query	string	false	"创建时间止，如 2006-01-02 15:04:05"
+// @Param	sort	query	string	false	"排序，多个列以逗号分隔，- 前缀表示倒序，如 -created_at,username"
+// @Param	fields	query	string	false	"返回的字段，多个列以逗号分隔，如 id,username"
+// @Param	cursor	query	string	false	"分页游标，传入 cursor 或 limit 时使用游标分页"
+// @Param	limit	query	int	false	"游标分页大小，默认 20，最大 100"
 // @Router			/user	[get]
 func (o *UserContro) Select(c *fiber.Ctx) error {
-	userFilter := &input.UserFilter{}
-	if err := c.QueryParser(userFilter); err != nil {
-		log.F(c).Error(err)
-		return code.ParamError
+	userFilter, err := httputil.BindQuery[input.UserFilter](c)
+	if err != nil {
+		return err
 	}
-	users, err := o.userServ.Select(c, userFilter)
+	opts, err := httputil.ParseListOptions(c, &output.UserOutput{})
+	if err != nil {
+		return err
+	}
+	if c.Query("cursor") != "" || c.Query("limit") != "" {
+		p, err := httputil.BindQuery[model.CursorPagination](c)
+		if err != nil {
+			return err
+		}
+		if err := o.userServ.SelectWithCursor(c, userFilter, opts, p); err != nil {
+			return err
+		}
+		return httputil.JsonSuccess(c, p)
+	}
+	users, err := o.userServ.Select(c, userFilter, opts)
 	if err != nil {
 		return err
 	}
 	return httputil.JsonSuccess(c, users)
 }
 
+// SelectTrashed @Summary		查找已删除用户
+// @Description	查找已删除用户
+// @Tags			user
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param	sort	query	string	false	"排序，多个列以逗号分隔，- 前缀表示倒序，如 -created_at,username"
+// @Param	fields	query	string	false	"返回的字段，多个列以逗号分隔，如 id,username"
+// @Router			/user/trashed	[get]
+func (o *UserContro) SelectTrashed(c *fiber.Ctx) error {
+	opts, err := httputil.ParseListOptions(c, &output.UserOutput{})
+	if err != nil {
+		return err
+	}
+	users, err := o.userServ.SelectTrashed(c, opts)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, users)
+}
+
+// Restore @Summary		恢复已删除用户
+// @Description	恢复已删除用户
+// @Tags			user
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			id		path		int	true	"用户的 id"
+// @Router			/user/{id}/restore	[put]
+func (o *UserContro) Restore(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.IdParam](c)
+	if err != nil {
+		return err
+	}
+	if err := o.userServ.Restore(c, param.Id); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
+
+// Unlock @Summary		解锁用户
+// @Description	解除登录失败次数过多导致的账户锁定
+// @Tags			user
+// @Accept			json
+// @Produce		json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param			id		path		int	true	"用户的 id"
+// @Router			/user/{id}/unlock	[put]
+func (o *UserContro) Unlock(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.IdParam](c)
+	if err != nil {
+		return err
+	}
+	if err := o.userServ.Unlock(c, param.Id); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
+
 // SelectById @Summary		按id查找用户
 // @Description	按id查找用户
 // @Tags			user
@@ -133,16 +218,11 @@ func (o *UserContro) Select(c *fiber.Ctx) error {
 // @Param			id			path		int	true	"用户的 id"
 // @Router			/user/{id}	[get]
 func (o *UserContro) SelectById(c *fiber.Ctx) error {
-	idStr := c.Params("id")
-	if idStr == "" {
-		return code.ParamError
-	}
-	id, err := strconv.Atoi(idStr)
+	param, err := httputil.BindParams[input.IdParam](c)
 	if err != nil {
-		log.F(c).Error(err)
-		return code.ParamError
+		return err
 	}
-	userOutput, err := o.userServ.SelectById(c, id)
+	userOutput, err := o.userServ.SelectById(c, param.Id)
 	if err != nil {
 		return err
 	}
@@ -157,14 +237,19 @@ func (o *UserContro) SelectById(c *fiber.Ctx) error {
 // @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
 // @Param			size							path		int	true	"分页大小"
 // @Param			page							path		int	true	"查询页号"
+// @Param	sort	query	string	false	"排序，多个列以逗号分隔，- 前缀表示倒序，如 -created_at,username"
+// @Param	fields	query	string	false	"返回的字段，多个列以逗号分隔，如 id,username"
 // @Router			/user/pagination/{size}/{page}	[get]
 func (o *UserContro) SelectWithPagination(c *fiber.Ctx) error {
-	p := &model.Pagination{}
-	if err := c.ParamsParser(p); err != nil {
-		log.F(c).Error(err)
-		return code.ParamError
+	p, err := httputil.BindParams[model.Pagination](c)
+	if err != nil {
+		return err
+	}
+	opts, err := httputil.ParseListOptions(c, &output.UserOutput{})
+	if err != nil {
+		return err
 	}
-	err := o.userServ.SelectWithPagination(c, p)
+	err = o.userServ.SelectWithPagination(c, p, opts)
 	if err != nil {
 		return err
 	}
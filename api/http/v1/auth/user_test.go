@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"app/conf"
+	"app/db"
+	"app/i18n"
+	"app/log"
+	"app/middleware"
+	"app/model"
+	"app/repo"
+	"app/serv"
+	"app/util"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_UserTrashedPermission(t *testing.T) {
+	conf.Initialize()
+	log.Initialize()
+	db.Initialize()
+	i18n.Initialize()
+	userRepo, roleRepo := repo.NewUserRepo(), repo.NewRoleRepo()
+	tokenServ := serv.NewTokenService(userRepo, repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), roleRepo)
+	userServ := serv.NewUserService(userRepo, roleRepo, repo.NewLoginAttemptRepo(), serv.NewMfaService(userRepo, repo.NewRecoveryCodeRepo()), tokenServ)
+
+	// 普通用户只有 user:read 权限
+	user := &model.User{
+		Username: util.EnPointer("plain_" + util.RandString(8)),
+		Password: util.EnPointer("password"),
+	}
+	if err := userRepo.Insert(nil, user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *user.Id) })
+	role, err := roleRepo.SelectByName(nil, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := roleRepo.SetUserRoles(nil, *user.Id, []int{*role.Id}); err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	tokens, err := tokenServ.Issue(app.AcquireCtx(&fasthttp.RequestCtx{}), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.Use(middleware.TraceId(), middleware.ErrorParse())
+	NewUserController(userServ).RegisterRoute(app)
+	request := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	if status := request("/user"); status != fiber.StatusOK {
+		t.Fatalf("user:read should be granted, got %d", status)
+	}
+	if status := request("/user/trashed"); status != fiber.StatusForbidden {
+		t.Fatalf("trashed users should require user:restore, got %d", status)
+	}
+}
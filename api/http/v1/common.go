@@ -3,6 +3,7 @@ package v1
 import (
 	"app/code"
 	"app/log"
+	"app/middleware"
 	"app/model/input"
 	"app/serv"
 	"app/util/httputil"
@@ -11,12 +12,14 @@ import (
 )
 
 type CommonContro struct {
-	userServ serv.UserServ
+	userServ  serv.UserServ
+	tokenServ serv.TokenServ
 }
 
-func NewCommonController(userServ serv.UserServ) BaseContro {
+func NewCommonController(userServ serv.UserServ, tokenServ serv.TokenServ) BaseContro {
 	return &CommonContro{
-		userServ: userServ,
+		userServ:  userServ,
+		tokenServ: tokenServ,
 	}
 }
 
@@ -27,7 +30,10 @@ func (o *CommonContro) Name() string {
 func (o *CommonContro) RegisterRoute(api fiber.Router) {
 	api.Get("/ping", o.ping)
 	api.Post("/login", o.login)
+	api.Post("/login/mfa", o.loginMfa)
 	api.Post("/register", o.register)
+	api.Post("/token/refresh", o.refresh)
+	api.Post("/logout", middleware.JwtAuth(), o.logout)
 }
 
 // @Summary			测试
@@ -49,10 +55,9 @@ func (o *CommonContro) ping(c *fiber.Ctx) error {
 // @Param	user	body	input.UserLogin	true	"登录信息"
 // @Router	/login	[post]
 func (o *CommonContro) login(c *fiber.Ctx) error {
-	user := &input.UserLogin{}
-	if err := c.BodyParser(user); err != nil {
-		log.F(c).Error(err)
-		return code.ParamError
+	user, err := httputil.BindBody[input.UserLogin](c)
+	if err != nil {
+		return err
 	}
 	token, err := o.userServ.Login(c, user)
 	if err != nil {
@@ -61,6 +66,25 @@ func (o *CommonContro) login(c *fiber.Ctx) error {
 	return httputil.JsonSuccess(c, token)
 }
 
+// @Summary	两步验证登录
+// @Description	已启用两步验证的用户登录时只返回 mfaToken，使用 mfaToken 与认证器中的验证码或一个恢复码换取访问令牌
+// @Tags	common
+// @Accept	json
+// @Produce	json
+// @Param	user	body	input.MfaLogin	true	"两步验证信息"
+// @Router	/login/mfa	[post]
+func (o *CommonContro) loginMfa(c *fiber.Ctx) error {
+	in, err := httputil.BindBody[input.MfaLogin](c)
+	if err != nil {
+		return err
+	}
+	token, err := o.userServ.LoginMfa(c, in)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, token)
+}
+
 // @Summary	注册
 // @Description	注册
 // @Tags	common
@@ -69,18 +93,54 @@ func (o *CommonContro) login(c *fiber.Ctx) error {
 // @Param	user	body	input.UserRegister	true	"用户信息"
 // @Router	/register	[post]
 func (o *CommonContro) register(c *fiber.Ctx) error {
-	user := &input.UserRegister{}
-	if err := c.BodyParser(user); err != nil {
-		log.F(c).Error(err)
-		return code.ParamError
+	user, err := httputil.BindBody[input.UserRegister](c)
+	if err != nil {
+		return err
+	}
+	err = o.userServ.Register(c, user)
+	if err != nil {
+		return err
 	}
-	if *user.Username == "" || *user.Password == "" {
-		log.F(c).Error("用户名或密码不能为空")
-		return code.ParamError
+	return httputil.JsonSuccess(c, nil)
+}
+
+// @Summary	刷新令牌
+// @Description	使用刷新令牌换取新的访问令牌与刷新令牌，旧的刷新令牌随即失效，重复使用会吊销该次登录的所有令牌
+// @Tags	common
+// @Accept	json
+// @Produce	json
+// @Param	token	body	input.TokenRefresh	true	"刷新令牌"
+// @Router	/token/refresh	[post]
+func (o *CommonContro) refresh(c *fiber.Ctx) error {
+	in, err := httputil.BindBody[input.TokenRefresh](c)
+	if err != nil {
+		return err
 	}
-	err := o.userServ.Register(c, user)
+	token, err := o.tokenServ.Refresh(c, *in.RefreshToken)
 	if err != nil {
 		return err
 	}
+	return httputil.JsonSuccess(c, token)
+}
+
+// @Summary	注销
+// @Description	注销当前访问令牌，传入刷新令牌时同时吊销该次登录的所有刷新令牌
+// @Tags	common
+// @Accept	json
+// @Produce	json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param	token	body	input.Logout	false	"刷新令牌"
+// @Router	/logout	[post]
+func (o *CommonContro) logout(c *fiber.Ctx) error {
+	in := &input.Logout{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(in); err != nil {
+			log.F(c).Error(err)
+			return code.ParamError
+		}
+	}
+	if err := o.tokenServ.Logout(c, in.RefreshToken); err != nil {
+		return err
+	}
 	return httputil.JsonSuccess(c, nil)
 }
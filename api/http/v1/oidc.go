@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"app/middleware"
+	"app/model/input"
+	"app/model/output"
+	"app/serv"
+	"app/util/httputil"
+	"github.com/gofiber/fiber/v2"
+)
+
+type OidcContro struct {
+	oidcServ serv.OidcServ
+}
+
+func NewOidcController(oidcServ serv.OidcServ) BaseContro {
+	return &OidcContro{
+		oidcServ: oidcServ,
+	}
+}
+
+func (o *OidcContro) Name() string {
+	return "Oidc"
+}
+
+func (o *OidcContro) RegisterRoute(api fiber.Router) {
+	api.Get("/oidc/:provider/login", o.login)
+	api.Get("/oidc/:provider/callback", o.callback)
+	api.Post("/oidc/:provider/link", middleware.JwtAuth(), o.link)
+}
+
+// @Summary	外部账户登录
+// @Description	跳转至身份提供方授权 (授权码模式 + PKCE)，授权后回调 /oidc/{provider}/callback
+// @Tags	oidc
+// @Param	provider	path	string	true	"身份提供方名称"
+// @Router	/oidc/{provider}/login	[get]
+func (o *OidcContro) login(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.OidcProvider](c)
+	if err != nil {
+		return err
+	}
+	authUrl, err := o.oidcServ.AuthUrl(c, param.Provider, false)
+	if err != nil {
+		return err
+	}
+	return c.Redirect(authUrl, fiber.StatusFound)
+}
+
+// @Summary	外部账户登录回调
+// @Description	校验 state 与 ID Token，登录已关联的用户或自动创建用户，返回访问令牌与刷新令牌
+// @Tags	oidc
+// @Produce	json
+// @Param	provider	path	string	true	"身份提供方名称"
+// @Param	code	query	string	false	"授权码"
+// @Param	state	query	string	true	"state"
+// @Router	/oidc/{provider}/callback	[get]
+func (o *OidcContro) callback(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.OidcProvider](c)
+	if err != nil {
+		return err
+	}
+	callback, err := httputil.BindQuery[input.OidcCallback](c)
+	if err != nil {
+		return err
+	}
+	token, err := o.oidcServ.Callback(c, param.Provider, callback)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, token)
+}
+
+// @Summary	关联外部账户
+// @Description	返回身份提供方的授权地址，浏览器跳转授权后外部账户关联到当前用户
+// @Tags	oidc
+// @Produce	json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param	provider	path	string	true	"身份提供方名称"
+// @Router	/oidc/{provider}/link	[post]
+func (o *OidcContro) link(c *fiber.Ctx) error {
+	param, err := httputil.BindParams[input.OidcProvider](c)
+	if err != nil {
+		return err
+	}
+	authUrl, err := o.oidcServ.AuthUrl(c, param.Provider, true)
+	if err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, output.OidcAuthUrl{AuthUrl: authUrl})
+}
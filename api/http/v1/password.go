@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"app/middleware"
+	"app/model/input"
+	"app/serv"
+	"app/util/httputil"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PasswordContro struct {
+	passwordServ serv.PasswordServ
+}
+
+func NewPasswordController(passwordServ serv.PasswordServ) BaseContro {
+	return &PasswordContro{
+		passwordServ: passwordServ,
+	}
+}
+
+func (o *PasswordContro) Name() string {
+	return "Password"
+}
+
+func (o *PasswordContro) RegisterRoute(api fiber.Router) {
+	api.Put("/user/password", middleware.JwtAuth(), o.change)
+	api.Post("/password/forgot", o.forgot)
+	api.Post("/password/reset", o.reset)
+}
+
+// @Summary	修改密码
+// @Description	校验原密码后修改当前用户的密码，该用户的所有刷新令牌随即失效
+// @Tags	password
+// @Accept	json
+// @Produce	json
+// @Param	Authorization	header	string	true	"Authentication header" default(Bearer xxxx)
+// @Param	password	body	input.PasswordChange	true	"原密码与新密码"
+// @Router	/user/password	[put]
+func (o *PasswordContro) change(c *fiber.Ctx) error {
+	in, err := httputil.BindBody[input.PasswordChange](c)
+	if err != nil {
+		return err
+	}
+	if err := o.passwordServ.Change(c, in); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
+
+// @Summary	忘记密码
+// @Description	向用户发送一次性的密码重置令牌，账户不存在时同样返回成功
+// @Tags	password
+// @Accept	json
+// @Produce	json
+// @Param	user	body	input.PasswordForgot	true	"用户账户"
+// @Router	/password/forgot	[post]
+func (o *PasswordContro) forgot(c *fiber.Ctx) error {
+	in, err := httputil.BindBody[input.PasswordForgot](c)
+	if err != nil {
+		return err
+	}
+	if err := o.passwordServ.Forgot(c, in); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
+
+// @Summary	重置密码
+// @Description	使用重置令牌设置新密码并解除账户锁定，令牌只能使用一次
+// @Tags	password
+// @Accept	json
+// @Produce	json
+// @Param	password	body	input.PasswordReset	true	"重置令牌与新密码"
+// @Router	/password/reset	[post]
+func (o *PasswordContro) reset(c *fiber.Ctx) error {
+	in, err := httputil.BindBody[input.PasswordReset](c)
+	if err != nil {
+		return err
+	}
+	if err := o.passwordServ.Reset(c, in); err != nil {
+		return err
+	}
+	return httputil.JsonSuccess(c, nil)
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"app/conf"
+	"app/db"
+	"app/log"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrMiss 缓存中不存在该键
+var ErrMiss = errors.New("cache: miss")
+
+// Cache 缓存存储，值为序列化后的字节
+type Cache interface {
+	// Get 读取缓存，不存在时返回 ErrMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set 写入缓存，ttl 为 0 时不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete 删除缓存，键不存在时不报错
+	Delete(ctx context.Context, keys ...string) error
+}
+
+const (
+	TypeMemory = "memory"
+	TypeRedis  = "redis"
+	TypeTiered = "tiered"
+)
+
+// DefaultChannel 未配置 cache.channel 时广播失效的 Redis 频道
+var DefaultChannel = "cache:invalidate"
+
+var (
+	current Cache
+	mu      sync.RWMutex
+)
+
+// Initialize 按 conf.Cache 创建缓存，需在 db.Initialize 之后调用
+// 未配置类型时启用 Redis 则使用 redis，否则使用 memory；redis 与 tiered 在未启用 Redis 时退化为 memory
+func Initialize() {
+	c := New()
+	mu.Lock()
+	current = c
+	mu.Unlock()
+}
+
+// New 按 conf.Cache 创建缓存
+func New() Cache {
+	typ := conf.Cache.Type
+	if typ == "" && conf.Redis.Enable {
+		typ = TypeRedis
+	}
+	if (typ == TypeRedis || typ == TypeTiered) && (!conf.Redis.Enable || db.RDB == nil) {
+		log.Warnf("cache type %s requires redis, fallback to %s", typ, TypeMemory)
+		typ = TypeMemory
+	}
+	switch typ {
+	case TypeRedis:
+		return NewRedisCache(db.RDB.Client)
+	case TypeTiered:
+		channel := conf.Cache.Channel
+		if channel == "" {
+			channel = DefaultChannel
+		}
+		return NewTieredCache(db.RDB.Client, NewMemoryCache(conf.Cache.MaxEntries), channel, time.Duration(conf.Cache.LocalExpire)*time.Second)
+	case "", TypeMemory:
+	default:
+		log.Warnf("unknown cache type %s, fallback to %s", typ, TypeMemory)
+	}
+	return NewMemoryCache(conf.Cache.MaxEntries)
+}
+
+// Default 返回当前使用的缓存，未初始化时返回 nil，此时 Loader 直接加载数据
+func Default() Cache {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Close 释放缓存占用的资源 (如 tiered 的订阅)，需在关闭 Redis 之前调用
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	c := current
+	current = nil
+	if closer, ok := c.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"app/conf"
+	"app/db"
+	"app/log"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// DefaultNegativeTTL 未配置 LoaderOptions.NegativeTTL 时不存在的记录的缓存时长
+	DefaultNegativeTTL = 30 * time.Second
+	// DefaultJitter 未配置 LoaderOptions.Jitter 时过期时间的随机浮动比例，避免大量缓存同时过期
+	DefaultJitter = 0.1
+)
+
+// tombstone 不存在的记录在缓存中的值
+var tombstone = []byte("null")
+
+// LoadFunc 缓存未命中时加载数据，记录不存在时返回 sql.ErrNoRows
+type LoadFunc[K comparable, V any] func(ctx context.Context, key K) (*V, error)
+
+// LoaderOptions 缓存配置，零值使用默认值
+type LoaderOptions struct {
+	Cache       Cache         // 缓存存储，为 nil 时使用 Default()
+	TTL         time.Duration // 缓存时长，为 0 时使用 cache.expire
+	NegativeTTL time.Duration // 不存在的记录的缓存时长，为 0 时使用 DefaultNegativeTTL，小于 0 时不缓存
+	Jitter      float64       // 缓存时长的随机浮动比例，为 0 时使用 DefaultJitter，小于 0 时不浮动
+}
+
+// Loader 旁路缓存：先读缓存，未命中时加载并写入缓存
+// 同一个键并发未命中时只加载一次；记录不存在时缓存空值，避免穿透；事务中直接加载，不读写缓存
+// 缓存使用 JSON 序列化，json:"-" 的字段不会被缓存
+// e.g., users := cache.NewLoader("user:id", loadUser, cache.LoaderOptions{}); users.Get(ctx, 1)
+type Loader[K comparable, V any] struct {
+	prefix string
+	load   LoadFunc[K, V]
+	opts   LoaderOptions
+	group  singleflight.Group
+	// gens 按键的哈希分组的失效版本，Invalidate 时递增；加载期间版本变化时不写入缓存
+	// 分组有限，不同的键可能共用同一个版本，只会多跳过几次写入
+	gens [loaderGenerations]atomic.Uint64
+}
+
+// loaderGenerations 失效版本的分组数
+const loaderGenerations = 256
+
+func NewLoader[K comparable, V any](prefix string, load LoadFunc[K, V], opts LoaderOptions) *Loader[K, V] {
+	return &Loader[K, V]{prefix: prefix, load: load, opts: opts}
+}
+
+// Key 缓存键: <prefix>:<key>
+func (o *Loader[K, V]) Key(key K) string {
+	return fmt.Sprintf("%s:%v", o.prefix, key)
+}
+
+// Get 读取缓存，未命中时加载；返回值是副本，可以修改
+// 缓存读写出错时只记录日志并直接加载，不影响请求
+func (o *Loader[K, V]) Get(ctx context.Context, key K) (*V, error) {
+	c := o.cache()
+	if c == nil || db.TxFrom(ctx) != nil {
+		// 事务中可能读到未提交的数据，不能写入缓存
+		return o.load(ctx, key)
+	}
+	cacheKey := o.Key(key)
+	data, err := c.Get(ctx, cacheKey)
+	if err == nil {
+		v, err := decode[V](data)
+		if err == nil {
+			return v, nil
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			log.T(ctx).Errorf("decode cache %s: %v", cacheKey, err)
+		} else {
+			return nil, err
+		}
+	} else if !errors.Is(err, ErrMiss) {
+		log.T(ctx).Errorf("get cache %s: %v", cacheKey, err)
+	}
+
+	// 并发未命中时只有一个请求加载，其余等待结果；加载不随单个请求取消
+	// 写入缓存的数据从主库读取，避免失效后从延迟的只读副本读到旧数据并缓存
+	result, err, _ := o.group.Do(cacheKey, func() (any, error) {
+		loadCtx := db.WithPrimary(context.WithoutCancel(ctx))
+		gen := o.generation(cacheKey)
+		v, err := o.load(loadCtx, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			if ttl := o.negativeTTL(); ttl > 0 {
+				o.set(loadCtx, c, cacheKey, gen, tombstone, ttl)
+			}
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			log.T(ctx).Errorf("encode cache %s: %v", cacheKey, err)
+			return v, nil
+		}
+		o.set(loadCtx, c, cacheKey, gen, data, o.ttl())
+		return v, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	v := *result.(*V)
+	return &v, nil
+}
+
+// Invalidate 删除缓存，写入数据后调用；事务中应在提交后调用 (db.AfterCommit)
+func (o *Loader[K, V]) Invalidate(ctx context.Context, keys ...K) error {
+	c := o.cache()
+	if c == nil || len(keys) == 0 {
+		return nil
+	}
+	cacheKeys := make([]string, len(keys))
+	for i, key := range keys {
+		cacheKeys[i] = o.Key(key)
+		// 正在进行的加载可能读到了旧数据，递增版本使其不写入缓存，之后的请求重新加载
+		o.gen(cacheKeys[i]).Add(1)
+		o.group.Forget(cacheKeys[i])
+	}
+	if err := c.Delete(ctx, cacheKeys...); err != nil {
+		log.T(ctx).Errorf("invalidate cache %v: %v", cacheKeys, err)
+		return err
+	}
+	return nil
+}
+
+func (o *Loader[K, V]) cache() Cache {
+	if o.opts.Cache != nil {
+		return o.opts.Cache
+	}
+	return Default()
+}
+
+// set 写入加载结果，gen 为加载开始时的版本；加载期间已失效时不写入，写入期间失效时删除刚写入的值
+func (o *Loader[K, V]) set(ctx context.Context, c Cache, key string, gen uint64, data []byte, ttl time.Duration) {
+	if o.generation(key) != gen {
+		return
+	}
+	if err := c.Set(ctx, key, data, ttl); err != nil {
+		log.T(ctx).Errorf("set cache %s: %v", key, err)
+		return
+	}
+	if o.generation(key) != gen {
+		if err := c.Delete(ctx, key); err != nil {
+			log.T(ctx).Errorf("invalidate cache %s: %v", key, err)
+		}
+	}
+}
+
+func (o *Loader[K, V]) gen(key string) *atomic.Uint64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &o.gens[h.Sum32()%loaderGenerations]
+}
+
+func (o *Loader[K, V]) generation(key string) uint64 {
+	return o.gen(key).Load()
+}
+
+func (o *Loader[K, V]) ttl() time.Duration {
+	ttl := o.opts.TTL
+	if ttl == 0 {
+		ttl = time.Duration(conf.Cache.Expire) * time.Second
+	}
+	if ttl == 0 {
+		ttl = time.Duration(conf.Redis.Expire) * time.Second
+	}
+	return o.jitter(ttl)
+}
+
+func (o *Loader[K, V]) negativeTTL() time.Duration {
+	ttl := o.opts.NegativeTTL
+	if ttl == 0 {
+		ttl = DefaultNegativeTTL
+	}
+	return o.jitter(ttl)
+}
+
+// jitter 在 ttl 上随机浮动 ±Jitter
+func (o *Loader[K, V]) jitter(ttl time.Duration) time.Duration {
+	j := o.opts.Jitter
+	if j == 0 {
+		j = DefaultJitter
+	}
+	if ttl <= 0 || j <= 0 {
+		return ttl
+	}
+	delta := time.Duration((rand.Float64()*2 - 1) * j * float64(ttl))
+	return max(ttl+delta, time.Second)
+}
+
+// decode 解析缓存值，空值表示记录不存在，返回 sql.ErrNoRows
+func decode[V any](data []byte) (*V, error) {
+	if bytes.Equal(data, tombstone) {
+		return nil, sql.ErrNoRows
+	}
+	v := new(V)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mapCache 记录写入时长的内存缓存，仅用于测试
+type mapCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttls map[string]time.Duration
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{data: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (o *mapCache) Get(_ context.Context, key string) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	v, ok := o.data[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	return v, nil
+}
+
+func (o *mapCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.data[key], o.ttls[key] = value, ttl
+	return nil
+}
+
+func (o *mapCache) Delete(_ context.Context, keys ...string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, key := range keys {
+		delete(o.data, key)
+	}
+	return nil
+}
+
+type item struct {
+	Id   int
+	Name string
+}
+
+func Test_LoaderSingleflight(t *testing.T) {
+	var loads atomic.Int32
+	loader := NewLoader("item:id", func(ctx context.Context, id int) (*item, error) {
+		loads.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return &item{Id: id, Name: "a"}, nil
+	}, LoaderOptions{Cache: newMapCache(), TTL: time.Minute})
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := loader.Get(context.Background(), 1)
+			if err != nil || v.Name != "a" {
+				t.Errorf("Get() = %v, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loads = %d, want 1", n)
+	}
+
+	// 返回值互相独立
+	v, _ := loader.Get(context.Background(), 1)
+	v.Name = "b"
+	if v, _ := loader.Get(context.Background(), 1); v.Name != "a" {
+		t.Fatalf("cached value modified: %v", v)
+	}
+}
+
+func Test_LoaderNegativeAndInvalidate(t *testing.T) {
+	c := newMapCache()
+	var loads atomic.Int32
+	exists := false
+	loader := NewLoader("item:id", func(ctx context.Context, id int) (*item, error) {
+		loads.Add(1)
+		if !exists {
+			return nil, sql.ErrNoRows
+		}
+		return &item{Id: id}, nil
+	}, LoaderOptions{Cache: c, TTL: time.Minute, NegativeTTL: 10 * time.Second, Jitter: -1})
+
+	for range 2 {
+		if _, err := loader.Get(context.Background(), 1); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("Get() error = %v, want sql.ErrNoRows", err)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loads = %d, want 1", n)
+	}
+	if ttl := c.ttls["item:id:1"]; ttl != 10*time.Second {
+		t.Fatalf("negative ttl = %v", ttl)
+	}
+
+	exists = true
+	if err := loader.Invalidate(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	v, err := loader.Get(context.Background(), 1)
+	if err != nil || v.Id != 1 {
+		t.Fatalf("Get() = %v, %v", v, err)
+	}
+	if ttl := c.ttls["item:id:1"]; ttl != time.Minute {
+		t.Fatalf("ttl = %v", ttl)
+	}
+}
+
+func Test_LoaderInvalidateDuringLoad(t *testing.T) {
+	c := newMapCache()
+	started, release := make(chan struct{}), make(chan struct{})
+	var name atomic.Value
+	name.Store("old")
+	loader := NewLoader("item:id", func(ctx context.Context, id int) (*item, error) {
+		v := &item{Id: id, Name: name.Load().(string)}
+		if v.Name == "old" {
+			close(started)
+			<-release
+		}
+		return v, nil
+	}, LoaderOptions{Cache: c, TTL: time.Minute})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = loader.Get(context.Background(), 1)
+	}()
+	// 加载读到旧数据后写入并失效，旧数据不能写入缓存
+	<-started
+	name.Store("new")
+	if err := loader.Invalidate(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-done
+	if _, err := c.Get(context.Background(), "item:id:1"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("stale load should not be cached, got %v", err)
+	}
+	if v, err := loader.Get(context.Background(), 1); err != nil || v.Name != "new" {
+		t.Fatalf("Get() = %v, %v", v, err)
+	}
+}
+
+func Test_LoaderJitter(t *testing.T) {
+	loader := NewLoader("item:id", func(ctx context.Context, id int) (*item, error) {
+		return &item{Id: id}, nil
+	}, LoaderOptions{TTL: 100 * time.Second, Jitter: 0.2})
+	for range 100 {
+		if ttl := loader.ttl(); ttl < 80*time.Second || ttl > 120*time.Second {
+			t.Fatalf("ttl = %v, want within ±20%%", ttl)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries 未配置 cache.maxEntries 时进程内缓存的最大条目数
+var DefaultMaxEntries = 10000
+
+// MemoryCache 进程内的 LRU 缓存，超过 maxEntries 时淘汰最久未使用的条目，过期条目在读取时删除
+// 多实例部署时各实例的缓存互不同步，应使用 redis 或 tiered
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryCache maxEntries 小于等于 0 时使用 DefaultMaxEntries
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+	}
+}
+
+func (o *MemoryCache) Get(_ context.Context, key string) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	elem, ok := o.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !time.Now().Before(entry.expiresAt) {
+		o.remove(elem)
+		return nil, ErrMiss
+	}
+	o.ll.MoveToFront(elem)
+	return entry.value, nil
+}
+
+func (o *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := &memoryEntry{key: key, value: bytes.Clone(value)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if elem, ok := o.items[key]; ok {
+		elem.Value = entry
+		o.ll.MoveToFront(elem)
+		return nil
+	}
+	o.items[key] = o.ll.PushFront(entry)
+	for o.ll.Len() > o.maxEntries {
+		o.remove(o.ll.Back())
+	}
+	return nil
+}
+
+func (o *MemoryCache) Delete(_ context.Context, keys ...string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := o.items[key]; ok {
+			o.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Len 当前的条目数，包括已过期但尚未删除的
+func (o *MemoryCache) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.ll.Len()
+}
+
+func (o *MemoryCache) remove(elem *list.Element) {
+	o.ll.Remove(elem)
+	delete(o.items, elem.Value.(*memoryEntry).key)
+}
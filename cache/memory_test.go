@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_MemoryCacheEvict(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), 0)
+	// 读取 a 后 b 成为最久未使用的条目
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Set(ctx, "c", []byte("3"), 0)
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("Get(b) error = %v, want ErrMiss", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := c.Get(ctx, key); err != nil {
+			t.Fatalf("Get(%s) error = %v", key, err)
+		}
+	}
+	if n := c.Len(); n != 2 {
+		t.Fatalf("Len() = %d, want 2", n)
+	}
+}
+
+func Test_MemoryCacheExpire(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(0)
+	_ = c.Set(ctx, "a", []byte("1"), 20*time.Millisecond)
+	if v, err := c.Get(ctx, "a"); err != nil || string(v) != "1" {
+		t.Fatalf("Get() = %s, %v", v, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("Get() error = %v, want ErrMiss", err)
+	}
+	if n := c.Len(); n != 0 {
+		t.Fatalf("Len() = %d, want 0", n)
+	}
+
+	_ = c.Set(ctx, "b", []byte("2"), 0)
+	_ = c.Delete(ctx, "b", "missing")
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("Get() error = %v, want ErrMiss", err)
+	}
+}
+
+func Test_NewWithoutRedis(t *testing.T) {
+	if _, ok := New().(*MemoryCache); !ok {
+		t.Fatalf("New() = %T, want *MemoryCache", New())
+	}
+}
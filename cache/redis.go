@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisCache 基于 Redis 的缓存，多个实例共享
+type RedisCache struct {
+	client *redis.Client
+}
+
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{client: client}
+}
+
+func (o *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := o.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	return value, err
+}
+
+func (o *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return o.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (o *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return o.client.Del(ctx, keys...).Err()
+}
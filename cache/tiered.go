@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"app/log"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultLocalExpire 未配置 cache.localExpire 时 TieredCache 进程内缓存的最长过期时间
+var DefaultLocalExpire = time.Minute
+
+// TieredCache 两级缓存：进程内 LRU 为一级，Redis 为二级
+// 删除时通过 Redis 发布订阅通知所有实例删除一级缓存；订阅断开期间错过的通知由一级缓存的过期时间兜底
+type TieredCache struct {
+	local    *MemoryCache
+	remote   *RedisCache
+	client   *redis.Client
+	channel  string
+	localTTL time.Duration
+	pubsub   *redis.PubSub
+}
+
+// NewTieredCache 创建两级缓存并订阅 channel，localTTL 小于等于 0 时使用 DefaultLocalExpire
+func NewTieredCache(client *redis.Client, local *MemoryCache, channel string, localTTL time.Duration) *TieredCache {
+	if localTTL <= 0 {
+		localTTL = DefaultLocalExpire
+	}
+	o := &TieredCache{
+		local:    local,
+		remote:   NewRedisCache(client),
+		client:   client,
+		channel:  channel,
+		localTTL: localTTL,
+		pubsub:   client.Subscribe(context.Background(), channel),
+	}
+	go o.listen()
+	return o
+}
+
+func (o *TieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, err := o.local.Get(ctx, key); err == nil {
+		return value, nil
+	}
+	value, err := o.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	_ = o.local.Set(ctx, key, value, o.localTTL)
+	return value, nil
+}
+
+func (o *TieredCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := o.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	localTTL := o.localTTL
+	if ttl > 0 {
+		localTTL = min(ttl, localTTL)
+	}
+	return o.local.Set(ctx, key, value, localTTL)
+}
+
+// Delete 删除两级缓存，并通知其他实例删除一级缓存
+func (o *TieredCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_ = o.local.Delete(ctx, keys...)
+	if err := o.remote.Delete(ctx, keys...); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return o.client.Publish(ctx, o.channel, payload).Err()
+}
+
+// Close 取消订阅
+func (o *TieredCache) Close() error {
+	return o.pubsub.Close()
+}
+
+// listen 接收失效通知并删除一级缓存，包括本实例发出的通知
+func (o *TieredCache) listen() {
+	for msg := range o.pubsub.Channel() {
+		var keys []string
+		if err := json.Unmarshal([]byte(msg.Payload), &keys); err != nil {
+			log.Errorf("decode cache invalidation %q: %v", msg.Payload, err)
+			continue
+		}
+		_ = o.local.Delete(context.Background(), keys...)
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+// TTLMap 有上限的进程内键值表，条目在 ttl 之后过期，超过 maxEntries 时淘汰最早写入的条目
+// 用于 Redis 未启用时的登录失败计数、限流计数与 OpenID Connect 状态；值通常为指针，可在取出后原地修改
+// 非并发安全，由调用方加锁
+type TTLMap[V any] struct {
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type ttlEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// NewTTLMap maxEntries 小于等于 0 时使用 DefaultMaxEntries
+func NewTTLMap[V any](maxEntries int) *TTLMap[V] {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &TTLMap[V]{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+	}
+}
+
+// Get 读取未过期的条目，已过期的条目在读取时删除
+func (o *TTLMap[V]) Get(key string) (V, bool) {
+	elem, ok := o.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	entry := elem.Value.(*ttlEntry[V])
+	if !time.Now().Before(entry.expiresAt) {
+		o.remove(elem)
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set 写入条目并重新计算过期时间，超过上限时淘汰最早写入的条目
+// 同一个表中的 ttl 通常相同，最早写入的条目也最早过期
+func (o *TTLMap[V]) Set(key string, value V, ttl time.Duration) {
+	entry := &ttlEntry[V]{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+	if elem, ok := o.items[key]; ok {
+		elem.Value = entry
+		o.ll.MoveToFront(elem)
+		return
+	}
+	o.items[key] = o.ll.PushFront(entry)
+	for o.ll.Len() > o.maxEntries {
+		o.remove(o.ll.Back())
+	}
+}
+
+// Delete 删除条目，键不存在时不做任何事
+func (o *TTLMap[V]) Delete(key string) {
+	if elem, ok := o.items[key]; ok {
+		o.remove(elem)
+	}
+}
+
+// Len 当前的条目数，包括已过期但尚未删除的
+func (o *TTLMap[V]) Len() int {
+	return o.ll.Len()
+}
+
+func (o *TTLMap[V]) remove(elem *list.Element) {
+	o.ll.Remove(elem)
+	delete(o.items, elem.Value.(*ttlEntry[V]).key)
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_TTLMapEvict(t *testing.T) {
+	m := NewTTLMap[int](2)
+	m.Set("a", 1, time.Minute)
+	m.Set("b", 2, time.Minute)
+	m.Set("c", 3, time.Minute)
+	// 超过上限时淘汰最早写入的 a
+	if _, ok := m.Get("a"); ok {
+		t.Fatal("oldest entry should be evicted")
+	}
+	for key, want := range map[string]int{"b": 2, "c": 3} {
+		if v, ok := m.Get(key); !ok || v != want {
+			t.Fatalf("Get(%s) = %d, %v", key, v, ok)
+		}
+	}
+	// 重新写入 b 后 c 成为最早写入的条目
+	m.Set("b", 4, time.Minute)
+	m.Set("d", 5, time.Minute)
+	if _, ok := m.Get("c"); ok {
+		t.Fatal("c should be evicted after b is rewritten")
+	}
+	if n := m.Len(); n != 2 {
+		t.Fatalf("Len() = %d, want 2", n)
+	}
+}
+
+func Test_TTLMapExpire(t *testing.T) {
+	m := NewTTLMap[*int](0)
+	v := 1
+	m.Set("a", &v, 20*time.Millisecond)
+	if got, ok := m.Get("a"); !ok || got != &v {
+		t.Fatalf("Get() = %v, %v", got, ok)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := m.Get("a"); ok {
+		t.Fatal("expired entry should not be returned")
+	}
+	if n := m.Len(); n != 0 {
+		t.Fatalf("Len() = %d, want 0", n)
+	}
+
+	m.Set("b", &v, time.Minute)
+	m.Delete("b")
+	m.Delete("missing")
+	if _, ok := m.Get("b"); ok {
+		t.Fatal("deleted entry should not be returned")
+	}
+}
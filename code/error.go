@@ -1,9 +1,12 @@
 package code
 
 import (
+	"database/sql"
 	"errors"
+	"net/http"
 )
 
+// Error 错误标识，同时作为 i18n 消息 ID；HTTP 状态码与业务码通过 Register 登记
 type Error string
 
 func (e Error) Error() string {
@@ -45,12 +48,138 @@ const (
 
 	// 认证模块错误
 	AuthFailed               Error = "AuthFailed"
+	PermissionDenied         Error = "PermissionDenied"
 	UsernameOrPasswordFailed Error = "UsernameOrPasswordFailed"
 	TokenGenerateFailed      Error = "TokenGenerateFailed"
+	TooManyAttempts          Error = "TooManyAttempts"
+	AccountLocked            Error = "AccountLocked"
+	IdentityNotLinked        Error = "IdentityNotLinked"
+	MfaCodeInvalid           Error = "MfaCodeInvalid"
+	WeakPassword             Error = "WeakPassword"
+	PasswordResetInvalid     Error = "PasswordResetInvalid"
 
 	// 用户侧错误
-	ParamError Error = "ParamError"
+	ParamError      Error = "ParamError"
+	NotFound        Error = "NotFound"
+	Conflict        Error = "Conflict"
+	TooManyRequests Error = "TooManyRequests"
 
 	// 三方问题
 	ExternalError Error = "ExternalError"
 )
+
+// meta 错误对应的 HTTP 状态码与业务码
+type meta struct {
+	status int
+	code   int
+}
+
+// registry 错误登记表，业务码按模块分段: 1xxxx 服务 2xxxx 数据库 3xxxx 认证 4xxxx 用户侧 5xxxx 三方
+var registry = map[Error]meta{
+	ServerError:         {http.StatusInternalServerError, 10000},
+	PasswordCryptFailed: {http.StatusInternalServerError, 10001},
+	JsonMarshalFailed:   {http.StatusInternalServerError, 10002},
+	JsonUnmarshalFailed: {http.StatusInternalServerError, 10003},
+
+	DatabaseError:         {http.StatusInternalServerError, 20000},
+	RedisGetDataFailed:    {http.StatusInternalServerError, 20001},
+	RedisSetDataFailed:    {http.StatusInternalServerError, 20002},
+	RedisDeleteDataFailed: {http.StatusInternalServerError, 20003},
+	RedisKeyNotExist:      {http.StatusNotFound, 20004},
+
+	AuthFailed:               {http.StatusUnauthorized, 30000},
+	UsernameOrPasswordFailed: {http.StatusUnauthorized, 30001},
+	TokenGenerateFailed:      {http.StatusInternalServerError, 30002},
+	PermissionDenied:         {http.StatusForbidden, 30003},
+	TooManyAttempts:          {http.StatusTooManyRequests, 30004},
+	AccountLocked:            {http.StatusLocked, 30005},
+	IdentityNotLinked:        {http.StatusUnauthorized, 30006},
+	MfaCodeInvalid:           {http.StatusUnauthorized, 30007},
+	WeakPassword:             {http.StatusBadRequest, 30008},
+	PasswordResetInvalid:     {http.StatusBadRequest, 30009},
+
+	ParamError:      {http.StatusBadRequest, 40000},
+	NotFound:        {http.StatusNotFound, 40001},
+	Conflict:        {http.StatusConflict, 40002},
+	TooManyRequests: {http.StatusTooManyRequests, 40003},
+
+	ExternalError: {http.StatusBadGateway, 50000},
+}
+
+// Register 登记错误的 HTTP 状态码与业务码，供各模块扩展错误，需在初始化阶段调用
+func Register(e Error, status, code int) Error {
+	registry[e] = meta{status: status, code: code}
+	return e
+}
+
+// Status 返回错误对应的 HTTP 状态码，未登记的错误为 500
+func (e Error) Status() int {
+	if m, ok := registry[e]; ok {
+		return m.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Code 返回错误对应的业务码，未登记的错误使用 ServerError 的业务码
+func (e Error) Code() int {
+	if m, ok := registry[e]; ok {
+		return m.code
+	}
+	return registry[ServerError].code
+}
+
+// WithDetails 返回携带详细信息的结构化错误，详细信息会在响应的 details 字段中返回
+func (e Error) WithDetails(details any) *BizError {
+	return &BizError{Err: e, Status: e.Status(), Code: e.Code(), Details: details}
+}
+
+// Wrap 返回包装了底层原因的结构化错误，原因只记录日志，不返回给客户端
+func (e Error) Wrap(cause error) *BizError {
+	return &BizError{Err: e, Status: e.Status(), Code: e.Code(), Cause: cause}
+}
+
+// BizError 结构化错误，携带 HTTP 状态码、业务码、详细信息与底层原因
+// 支持 errors.Is(err, code.ParamError) 与 errors.As(err, &bizErr)
+type BizError struct {
+	Err     Error // 错误标识，作为 i18n 消息 ID
+	Status  int   // HTTP 状态码
+	Code    int   // 业务码
+	Details any   // 返回给客户端的详细信息
+	Cause   error // 底层原因
+}
+
+func (e *BizError) Error() string {
+	if e.Cause != nil {
+		return e.Err.String() + ": " + e.Cause.Error()
+	}
+	return e.Err.String()
+}
+
+func (e *BizError) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Err, e.Cause}
+	}
+	return []error{e.Err}
+}
+
+// From 将任意错误转换为结构化错误：Error 按登记表转换，sql.ErrNoRows 视为 NotFound，其他错误视为 ServerError
+func From(err error) *BizError {
+	if err == nil {
+		return nil
+	}
+	var bizErr *BizError
+	if errors.As(err, &bizErr) {
+		return bizErr
+	}
+	var e Error
+	if errors.As(err, &e) {
+		if e == err {
+			return &BizError{Err: e, Status: e.Status(), Code: e.Code()}
+		}
+		return e.Wrap(err)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound.Wrap(err)
+	}
+	return ServerError.Wrap(err)
+}
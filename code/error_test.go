@@ -1,7 +1,10 @@
 package code
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 )
 
@@ -9,3 +12,51 @@ func Test_Error(t *testing.T) {
 	err := fmt.Errorf("")
 	t.Log(IsSuccess(err))
 }
+
+func Test_BizError(t *testing.T) {
+	if ParamError.Status() != http.StatusBadRequest || ParamError.Code() != 40000 {
+		t.Errorf("unexpected ParamError meta: %d %d", ParamError.Status(), ParamError.Code())
+	}
+	if Error("Unknown").Status() != http.StatusInternalServerError || Error("Unknown").Code() != ServerError.Code() {
+		t.Error("unregistered error should fallback to ServerError")
+	}
+
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("select user: %w", DatabaseError.Wrap(cause))
+	if !errors.Is(err, DatabaseError) || !errors.Is(err, cause) {
+		t.Error("wrapped error should match both the code and the cause")
+	}
+	var bizErr *BizError
+	if !errors.As(err, &bizErr) || bizErr.Status != http.StatusInternalServerError || bizErr.Code != 20000 {
+		t.Errorf("unexpected biz error: %#v", bizErr)
+	}
+	if bizErr.Error() != "DatabaseError: connection refused" {
+		t.Errorf("unexpected message: %s", bizErr.Error())
+	}
+
+	details := map[string]string{"id": "required"}
+	if e := ParamError.WithDetails(details); e.Status != http.StatusBadRequest || e.Details == nil {
+		t.Errorf("unexpected details error: %#v", e)
+	}
+
+	tests := []struct {
+		err    error
+		expect Error
+		status int
+	}{
+		{AuthFailed, AuthFailed, http.StatusUnauthorized},
+		{fmt.Errorf("wrap: %w", NotFound), NotFound, http.StatusNotFound},
+		{sql.ErrNoRows, NotFound, http.StatusNotFound},
+		{cause, ServerError, http.StatusInternalServerError},
+		{fmt.Errorf("insert user: %w", Conflict.Wrap(errors.New("duplicate"))), Conflict, http.StatusConflict},
+	}
+	for _, test := range tests {
+		e := From(test.err)
+		if e.Err != test.expect || e.Status != test.status {
+			t.Errorf("From(%v) = %#v, expected %s %d", test.err, e, test.expect, test.status)
+		}
+	}
+	if From(nil) != nil {
+		t.Error("From(nil) should be nil")
+	}
+}
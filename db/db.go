@@ -3,19 +3,19 @@ package db
 import (
 	"app/conf"
 	"app/log"
-	"context"
+	"app/util/dbutil"
 	"database/sql"
 	"database/sql/driver"
 	"embed"
 	"github.com/jmoiron/sqlx"
 	"github.com/qustavo/sqlhooks/v2"
-	"time"
 )
 
 func Initialize() {
 	InitializeRedis()
 	InitializeSqlite()
 	InitializeMysql()
+	InitializePostgres()
 	if conf.DB.EnableMigrate {
 		Migrate()
 	}
@@ -24,30 +24,17 @@ func Initialize() {
 func Migrate() {
 	MigrateSqlite()
 	MigrateMysql()
+	MigratePostgres()
 }
 
 //go:embed migrate_sqlite/*.sql
 //go:embed migrate_mysql/*.sql
+//go:embed migrate_postgres/*.sql
 var MigrationFS embed.FS
 
 var DB *sqlx.DB
 var RDB *RedisDB
 
-type Hooks struct{}
-
-// Before hook will print the query with it's args and return the context with the timestamp
-func (h *Hooks) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
-	log.Infof("Exec SQL: \n%s %v", query, args)
-	return context.WithValue(ctx, "beginTime", time.Now()), nil
-}
-
-// After hook will get the timestamp registered on the Before hook and print the elapsed time
-func (h *Hooks) After(ctx context.Context, query string, args ...any) (context.Context, error) {
-	begin := ctx.Value("beginTime").(time.Time)
-	log.Infof("Above SQL Used Time: %s", time.Since(begin))
-	return ctx, nil
-}
-
 func getDBConnection(driverName, dataSourceName string) *sqlx.DB {
 	sqlDB, err := sql.Open(driverName, dataSourceName)
 	if err != nil {
@@ -70,4 +57,6 @@ func registerHooks(driverName string, driver driver.Driver) {
 	if !driverIsRegistered {
 		sql.Register(driverName, sqlhooks.Wrap(driver, &Hooks{}))
 	}
+	// 包装后的驱动名 sqlx 无法识别，按当前方言注册占位符类型，使命名查询生成正确的占位符
+	sqlx.BindDriver(driverName, dbutil.CurrentDialect().BindType())
 }
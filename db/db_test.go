@@ -4,8 +4,12 @@ import (
 	"app/conf"
 	"app/log"
 	"context"
+	"errors"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 func TestMigrateSqlite(t *testing.T) {
@@ -22,6 +26,9 @@ func TestMigrateMysql(t *testing.T) {
 
 func Test_Redis(t *testing.T) {
 	InitializeRedis()
+	if RDB == nil {
+		t.Skip("redis is not enabled")
+	}
 	RDB.Set(context.Background(), "test1", "value1", time.Second*30)
 	val, err := RDB.Get(context.Background(), "test1").Result()
 	if err != nil {
@@ -45,3 +52,163 @@ func Test_Mysql(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func Test_WithTx(t *testing.T) {
+	conf.Initialize()
+	log.Initialize()
+	InitializeSqlite()
+	DB.MustExec("CREATE TABLE IF NOT EXISTS tx_test (id INTEGER PRIMARY KEY)")
+	DB.MustExec("DELETE FROM tx_test")
+	defer DB.MustExec("DROP TABLE tx_test")
+
+	count := func() int {
+		var total int
+		if err := DB.Get(&total, "SELECT COUNT(*) FROM tx_test"); err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+
+	// 返回错误时回滚，嵌套调用加入外层事务
+	err := WithTx(context.Background(), func(ctx context.Context) error {
+		sqlx.MustExec(Conn(ctx), "INSERT INTO tx_test(id) VALUES (1)")
+		_ = WithTx(ctx, func(ctx context.Context) error {
+			sqlx.MustExec(Conn(ctx), "INSERT INTO tx_test(id) VALUES (2)")
+			return nil
+		})
+		return errors.New("rollback")
+	})
+	if err == nil || count() != 0 {
+		t.Fatalf("expected rollback, err: %v, count: %d", err, count())
+	}
+
+	// panic 时回滚
+	func() {
+		defer func() { _ = recover() }()
+		_ = WithTx(context.Background(), func(ctx context.Context) error {
+			sqlx.MustExec(Conn(ctx), "INSERT INTO tx_test(id) VALUES (3)")
+			panic("rollback")
+		})
+	}()
+	if count() != 0 {
+		t.Fatalf("expected rollback after panic, count: %d", count())
+	}
+
+	// 正常提交并执行提交后回调
+	committed := false
+	err = WithTx(context.Background(), func(ctx context.Context) error {
+		sqlx.MustExec(Conn(ctx), "INSERT INTO tx_test(id) VALUES (4)")
+		AfterCommit(ctx, func() { committed = true })
+		return nil
+	})
+	if err != nil || count() != 1 || !committed {
+		t.Fatalf("expected commit, err: %v, count: %d, committed: %v", err, count(), committed)
+	}
+}
+
+func Test_ConnContext(t *testing.T) {
+	conf.Initialize()
+	log.Initialize()
+	InitializeSqlite()
+	var n int
+	if err := Conn(context.Background()).Get(&n, "SELECT 1"); err != nil || n != 1 {
+		t.Fatalf("Get() = %d, %v", n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Conn(ctx).Get(&n, "SELECT 1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Get() error = %v, want context.Canceled", err)
+	}
+
+	// 每条语句的截止时间为 db.queryTimeout，ctx 的截止时间更早时以 ctx 为准
+	timeout := conf.DB.QueryTimeout
+	conf.DB.QueryTimeout = 5
+	defer func() { conf.DB.QueryTimeout = timeout }()
+	queryCtx, cancel := queryContext(context.Background())
+	defer cancel()
+	if deadline, ok := queryCtx.Deadline(); !ok || time.Until(deadline) > 5*time.Second {
+		t.Fatalf("deadline = %v, %v", deadline, ok)
+	}
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Second)
+	defer cancelParent()
+	queryCtx, cancel = queryContext(parent)
+	defer cancel()
+	if deadline, _ := queryCtx.Deadline(); time.Until(deadline) > time.Second {
+		t.Fatalf("deadline = %v, want parent deadline", deadline)
+	}
+}
+
+func Test_SqliteReplicas(t *testing.T) {
+	conf.Initialize()
+	log.Initialize()
+	replicaDSNs := conf.DB.Replicas
+	conf.DB.Replicas = []string{filepath.Join(t.TempDir(), "replica.db"), filepath.Join(t.TempDir(), "missing", "replica.db")}
+	defer func() {
+		conf.DB.Replicas = replicaDSNs
+		if set := replicas.Swap(nil); set != nil {
+			_ = set.close()
+		}
+	}()
+	InitializeSqlite()
+	set := replicas.Load()
+	if set == nil || len(set.list) != 2 || !set.list[0].healthy.Load() || set.list[1].healthy.Load() {
+		t.Fatal("replica #0 should be up and #1 down")
+	}
+	DB.MustExec("CREATE TABLE IF NOT EXISTS replica_test (id INTEGER PRIMARY KEY)")
+	defer DB.MustExec("DROP TABLE replica_test")
+	DB.MustExec("DELETE FROM replica_test")
+	DB.MustExec("INSERT INTO replica_test(id) VALUES (1)")
+	set.list[0].db.MustExec("CREATE TABLE replica_test (id INTEGER PRIMARY KEY)")
+	set.list[0].db.MustExec("INSERT INTO replica_test(id) VALUES (2)")
+
+	read := func(ctx context.Context) int {
+		var id int
+		if err := Conn(ctx).Get(&id, "SELECT MAX(id) FROM replica_test"); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	if id := read(context.Background()); id != 2 {
+		t.Fatalf("read = %d, want 2 from replica", id)
+	}
+	if id := read(WithPrimary(context.Background())); id != 1 {
+		t.Fatalf("read with primary = %d, want 1", id)
+	}
+	_ = WithTx(context.Background(), func(ctx context.Context) error {
+		if id := read(ctx); id != 1 {
+			t.Fatalf("read in tx = %d, want 1", id)
+		}
+		return nil
+	})
+	// 写入使用主库
+	if _, err := Conn(context.Background()).Exec("INSERT INTO replica_test(id) VALUES (3)"); err != nil {
+		t.Fatal(err)
+	}
+	if id := read(WithPrimary(context.Background())); id != 3 {
+		t.Fatalf("read with primary = %d, want 3", id)
+	}
+
+	// 副本不可用时改用主库，健康检查成功后恢复
+	set.list[0].markDown(errors.New("test"))
+	if id := read(context.Background()); id != 3 {
+		t.Fatalf("read = %d, want 3 from primary", id)
+	}
+	set.check()
+	if id := read(context.Background()); id != 2 {
+		t.Fatalf("read = %d, want 2 from replica", id)
+	}
+}
+
+func TestMigratePostgres(t *testing.T) {
+	InitializePostgres()
+	MigratePostgres()
+}
+
+func Test_Postgres(t *testing.T) {
+	InitializePostgres()
+	err := DB.Ping()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
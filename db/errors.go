@@ -0,0 +1,40 @@
+package db
+
+import (
+	"errors"
+
+	sqlite "github.com/glebarez/go-sqlite"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// sqlServerError SQL Server 驱动错误，按方法匹配以免引入驱动依赖
+type sqlServerError interface {
+	SQLErrorNumber() int32
+}
+
+// IsUniqueViolation 是否为违反唯一约束或主键约束的数据库错误，如重复的用户账户，覆盖所有支持的方言
+func IsUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// SQLITE_CONSTRAINT_UNIQUE, SQLITE_CONSTRAINT_PRIMARYKEY
+		return sqliteErr.Code() == 2067 || sqliteErr.Code() == 1555
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	var mssqlErr sqlServerError
+	if errors.As(err, &mssqlErr) {
+		// 违反 UNIQUE 约束, 唯一索引中存在重复键
+		return mssqlErr.SQLErrorNumber() == 2627 || mssqlErr.SQLErrorNumber() == 2601
+	}
+	return false
+}
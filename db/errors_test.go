@@ -0,0 +1,54 @@
+package db
+
+import (
+	"app/conf"
+	"app/log"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type fakeSqlServerError int32
+
+func (e fakeSqlServerError) Error() string         { return "mssql error" }
+func (e fakeSqlServerError) SQLErrorNumber() int32 { return int32(e) }
+
+func Test_IsUniqueViolation(t *testing.T) {
+	conf.Initialize()
+	log.Initialize()
+	InitializeSqlite()
+	DB.MustExec("CREATE TABLE IF NOT EXISTS unique_test (id INTEGER PRIMARY KEY, name TEXT UNIQUE, ref TEXT NOT NULL)")
+	DB.MustExec("DELETE FROM unique_test")
+	defer DB.MustExec("DROP TABLE unique_test")
+
+	DB.MustExec("INSERT INTO unique_test(id, name, ref) VALUES (1, 'a', 'x')")
+	_, dupName := DB.Exec("INSERT INTO unique_test(id, name, ref) VALUES (2, 'a', 'x')")
+	_, dupId := DB.Exec("INSERT INTO unique_test(id, name, ref) VALUES (1, 'b', 'x')")
+	_, notNull := DB.Exec("INSERT INTO unique_test(id, name, ref) VALUES (3, 'c', NULL)")
+
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("boom"), false},
+		{dupName, true},
+		{fmt.Errorf("insert: %w", dupId), true},
+		{notNull, false},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, true},
+		{&mysql.MySQLError{Number: 1045}, false},
+		{&pgconn.PgError{Code: "23505"}, true},
+		{&pgconn.PgError{Code: "23503"}, false},
+		{fakeSqlServerError(2627), true},
+		{fakeSqlServerError(2601), true},
+		{fakeSqlServerError(547), false},
+	}
+	for i, tc := range cases {
+		if got := IsUniqueViolation(tc.err); got != tc.want {
+			t.Errorf("case %d: IsUniqueViolation(%v) = %v, want %v", i, tc.err, got, tc.want)
+		}
+	}
+}
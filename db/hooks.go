@@ -0,0 +1,284 @@
+package db
+
+import (
+	"app/conf"
+	"app/log"
+	"app/metrics"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// redacted 日志中替代敏感参数的值
+const redacted = "***"
+
+// queryDuration SQL 语句耗时，按操作与表名分组，通过 /metrics 输出
+var queryDuration = metrics.NewHistogram("db_query_duration_seconds", "SQL statement latency in seconds.",
+	metrics.DefaultBuckets, "operation", "table", "status")
+
+type beginTimeKey struct{}
+
+type queryStatKey struct{}
+
+// queryStat 由 executor 放入 context，Hooks 记录实际执行的语句与参数，executor 在读取完结果后统一记录耗时与行数
+type queryStat struct {
+	query string
+	args  []any
+}
+
+type Hooks struct{}
+
+// Before hook will print the query with it's args and return the context with the timestamp
+// 只有开启 db.logQueries 时才记录，敏感列的参数被隐藏
+func (h *Hooks) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
+	if conf.DB.LogQueries {
+		log.T(ctx).Infow("sql", "query", query, "args", redactArgs(query, args))
+	}
+	if stat, ok := ctx.Value(queryStatKey{}).(*queryStat); ok {
+		stat.query, stat.args = query, args
+	}
+	return context.WithValue(ctx, beginTimeKey{}, time.Now()), nil
+}
+
+// After hook 记录不经过 executor 的语句 (如迁移) 的耗时，经过 executor 的语句由 executor 记录
+func (h *Hooks) After(ctx context.Context, query string, args ...any) (context.Context, error) {
+	h.finish(ctx, query, args, nil)
+	return ctx, nil
+}
+
+// OnError 与 After 相同，返回 nil 时保留原错误
+func (h *Hooks) OnError(ctx context.Context, err error, query string, args ...any) error {
+	h.finish(ctx, query, args, err)
+	return nil
+}
+
+func (h *Hooks) finish(ctx context.Context, query string, args []any, err error) {
+	if _, ok := ctx.Value(queryStatKey{}).(*queryStat); ok {
+		return
+	}
+	begin, ok := ctx.Value(beginTimeKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	observeQuery(ctx, query, args, time.Since(begin), -1, err)
+}
+
+// observeQuery 记录语句耗时，超过 db.slowThreshold 时记录一条慢查询日志，rows 小于 0 时表示未知
+func observeQuery(ctx context.Context, query string, args []any, elapsed time.Duration, rows int64, err error) {
+	operation, table := statementOf(query)
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	queryDuration.Observe(elapsed.Seconds(), operation, table, status)
+
+	threshold := time.Duration(conf.DB.SlowThreshold) * time.Millisecond
+	if threshold <= 0 || elapsed < threshold {
+		return
+	}
+	fields := []any{"query", query, "args", redactArgs(query, args), "duration", elapsed.String()}
+	if rows >= 0 {
+		fields = append(fields, "rows", rows)
+	}
+	if err != nil {
+		fields = append(fields, "error", err.Error())
+	}
+	log.T(ctx).Warnw("slow sql", fields...)
+}
+
+// statementOf 返回语句的操作与主表，用作指标标签，如 ("select", "user")
+func statementOf(query string) (operation, table string) {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return "", ""
+	}
+	operation = strings.ToLower(words[0])
+	if operation == "with" {
+		operation = "select"
+	}
+	for i := 0; i+1 < len(words); i++ {
+		switch strings.ToUpper(words[i]) {
+		case "FROM", "INTO", "UPDATE":
+			name := words[i+1]
+			if j := strings.IndexAny(name, "(,;"); j >= 0 {
+				name = name[:j]
+			}
+			return operation, unquote(name)
+		}
+	}
+	return operation, ""
+}
+
+// redactArgs 将敏感列 (db.redactColumns) 对应的参数替换为 ***，参数按占位符前最近的列名对应，INSERT 按列的顺序对应
+func redactArgs(query string, args []any) []any {
+	if len(args) == 0 || len(conf.DB.RedactColumns) == 0 {
+		return args
+	}
+	columns := placeholderColumns(query)
+	redactedArgs := make([]any, len(args))
+	copy(redactedArgs, args)
+	for i, column := range columns {
+		if i < len(redactedArgs) && isSensitive(column) {
+			redactedArgs[i] = redacted
+		}
+	}
+	return redactedArgs
+}
+
+func isSensitive(column string) bool {
+	for _, c := range conf.DB.RedactColumns {
+		if strings.EqualFold(c, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholderColumns 返回每个参数对应的列名，下标为参数位置，无法判断时为空
+// 支持 ? 与 $n / @pn 占位符，忽略字符串字面量中的内容
+func placeholderColumns(query string) []string {
+	var columns []string
+	set := func(index int, column string) {
+		for len(columns) <= index {
+			columns = append(columns, "")
+		}
+		columns[index] = column
+	}
+	insertColumns, valuesAt := insertColumnList(query)
+	var lastIdent string
+	next, inValues, valuesIndex, depth := 0, false, 0, 0
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == '\'':
+			// 跳过字符串字面量
+			i++
+			for i < len(query) && !(query[i] == '\'' && (i+1 >= len(query) || query[i+1] != '\'')) {
+				if query[i] == '\'' {
+					i++
+				}
+				i++
+			}
+			i++
+			continue
+		case ch == '(' && valuesAt >= 0 && i >= valuesAt && !inValues && depth == 0:
+			inValues, valuesIndex = true, 0
+			depth++
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth == 0 {
+				inValues = false
+			}
+		case ch == ',' && inValues && depth == 1:
+			valuesIndex++
+		case ch == '?' || ch == '$' || (ch == '@' && i+1 < len(query) && (query[i+1] == 'p' || query[i+1] == 'P')):
+			j := i + 1
+			if ch == '@' {
+				j++
+			}
+			k := j
+			for k < len(query) && query[k] >= '0' && query[k] <= '9' {
+				k++
+			}
+			index := next
+			if ch != '?' {
+				if k == j {
+					break
+				}
+				n, _ := strconv.Atoi(query[j:k])
+				index = n - 1
+			}
+			next++
+			column := lastIdent
+			if inValues && valuesIndex < len(insertColumns) {
+				column = insertColumns[valuesIndex]
+			}
+			set(index, column)
+			i = k
+			continue
+		case isIdentStart(rune(ch)) || ch == '"' || ch == '`' || ch == '[':
+			j := identEnd(query, i)
+			if ident := unquote(query[i:j]); !isKeyword(ident) {
+				lastIdent = ident
+			}
+			i = j
+			continue
+		}
+		i++
+	}
+	return columns
+}
+
+// insertColumnList 解析 INSERT INTO t (a, b) VALUES (...) 的列名列表，返回列名与 VALUES 的位置，非 INSERT 时位置为 -1
+func insertColumnList(query string) ([]string, int) {
+	upper := strings.ToUpper(query)
+	if !strings.HasPrefix(strings.TrimSpace(upper), "INSERT") {
+		return nil, -1
+	}
+	start := strings.Index(query, "(")
+	values := strings.Index(upper, "VALUES")
+	if start < 0 || values < 0 || start > values {
+		return nil, -1
+	}
+	end := strings.LastIndex(query[:values], ")")
+	if end < start {
+		return nil, -1
+	}
+	var columns []string
+	for _, column := range strings.Split(query[start+1:end], ",") {
+		columns = append(columns, unquote(strings.TrimSpace(column)))
+	}
+	return columns, values
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// identEnd 返回从 i 开始的标识符 (可带引号与 table. 前缀) 的结束位置
+func identEnd(query string, i int) int {
+	for i < len(query) {
+		switch query[i] {
+		case '"', '`', '[':
+			closing := map[byte]byte{'"': '"', '`': '`', '[': ']'}[query[i]]
+			end := strings.IndexByte(query[i+1:], closing)
+			if end < 0 {
+				return len(query)
+			}
+			i += end + 2
+		default:
+			for i < len(query) && (isIdentStart(rune(query[i])) || (query[i] >= '0' && query[i] <= '9')) {
+				i++
+			}
+		}
+		if i < len(query) && query[i] == '.' {
+			i++
+			continue
+		}
+		return i
+	}
+	return i
+}
+
+// unquote 去掉标识符的引号与表名前缀，如 u."password" -> password
+func unquote(ident string) string {
+	if i := strings.LastIndexByte(ident, '.'); i >= 0 {
+		ident = ident[i+1:]
+	}
+	return strings.Trim(ident, "\"`[]")
+}
+
+var keywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IN": true, "LIKE": true, "ILIKE": true, "IS": true, "NULL": true,
+	"BETWEEN": true, "ESCAPE": true, "SET": true, "WHERE": true, "VALUES": true, "LIMIT": true, "OFFSET": true,
+	"ROWS": true, "FETCH": true, "NEXT": true, "ONLY": true, "ASC": true, "DESC": true, "AS": true, "ON": true,
+	"CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true, "LOWER": true, "UPPER": true,
+}
+
+func isKeyword(ident string) bool {
+	return keywords[strings.ToUpper(ident)]
+}
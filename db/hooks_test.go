@@ -0,0 +1,50 @@
+package db
+
+import (
+	"app/conf"
+	"reflect"
+	"testing"
+)
+
+func Test_RedactArgs(t *testing.T) {
+	columns := conf.DB.RedactColumns
+	conf.DB.RedactColumns = []string{"password", "token_hash"}
+	defer func() { conf.DB.RedactColumns = columns }()
+
+	tests := []struct {
+		query string
+		args  []any
+		want  []any
+	}{
+		{`INSERT INTO "user" ("username", "password", "created_at") VALUES (?, ?, ?)`,
+			[]any{"admin", "$2a$10$hash", "now"}, []any{"admin", redacted, "now"}},
+		{`UPDATE "user" SET "password" = ?, "updated_at" = ? WHERE ("id" = ?)`,
+			[]any{"$2a$10$hash", "now", 1}, []any{redacted, "now", 1}},
+		{`SELECT "id" AS "id" FROM "refresh_token" WHERE token_hash = $1 AND "deleted_at" IS NULL LIMIT $2`,
+			[]any{"abc", 10}, []any{redacted, 10}},
+		{`SELECT * FROM t WHERE u.password IN (?, ?) AND name = 'password = ?' AND id = ?`,
+			[]any{"a", "b", 1}, []any{redacted, redacted, 1}},
+		{`SELECT * FROM t WHERE id = ?`, []any{1}, []any{1}},
+	}
+	for _, tt := range tests {
+		if got := redactArgs(tt.query, tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("redactArgs(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func Test_StatementOf(t *testing.T) {
+	tests := []struct {
+		query, operation, table string
+	}{
+		{`SELECT "id" AS "id" FROM "user" WHERE id = ?`, "select", "user"},
+		{`INSERT INTO "api_key"("name") VALUES (?)`, "insert", "api_key"},
+		{`update role set name = ?`, "update", "role"},
+		{`DELETE FROM [token_blacklist] WHERE expires_at < @p1`, "delete", "token_blacklist"},
+	}
+	for _, tt := range tests {
+		if operation, table := statementOf(tt.query); operation != tt.operation || table != tt.table {
+			t.Errorf("statementOf(%s) = %s, %s", tt.query, operation, table)
+		}
+	}
+}
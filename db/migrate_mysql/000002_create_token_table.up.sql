@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS `refresh_token`
+(
+    `id`         INT PRIMARY KEY AUTO_INCREMENT COMMENT '编号',
+    `token_hash` VARCHAR(64)  NOT NULL UNIQUE COMMENT '刷新令牌的 SHA-256 摘要',
+    `family`     VARCHAR(64)  NOT NULL COMMENT '令牌族，同一次登录轮换出的令牌属于同一族',
+    `user_id`    INT          NOT NULL COMMENT '用户编号',
+    `expires_at` TIMESTAMP    NOT NULL COMMENT '过期时间',
+    `revoked_at` TIMESTAMP    NULL DEFAULT NULL COMMENT '吊销时间',
+    `created_at` TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建日期',
+    INDEX `idx_refresh_token_family` (`family`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='刷新令牌表';
+
+CREATE TABLE IF NOT EXISTS `token_blacklist`
+(
+    `id`         INT PRIMARY KEY AUTO_INCREMENT COMMENT '编号',
+    `jti`        VARCHAR(64) NOT NULL UNIQUE COMMENT '访问令牌编号',
+    `expires_at` TIMESTAMP   NOT NULL COMMENT '访问令牌过期时间，过期后可清理',
+    `created_at` TIMESTAMP   NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建日期'
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='访问令牌黑名单';
@@ -0,0 +1,66 @@
+CREATE TABLE IF NOT EXISTS `role`
+(
+    `id`          INT PRIMARY KEY AUTO_INCREMENT COMMENT '编号',
+    `name`        VARCHAR(64)  NOT NULL UNIQUE COMMENT '角色名称',
+    `description` VARCHAR(255) NULL COMMENT '描述',
+    `created_at`  TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建日期',
+    `updated_at`  TIMESTAMP    NULL DEFAULT NULL COMMENT '更新日期'
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='角色表';
+
+CREATE TABLE IF NOT EXISTS `permission`
+(
+    `id`          INT PRIMARY KEY AUTO_INCREMENT COMMENT '编号',
+    `name`        VARCHAR(64)  NOT NULL UNIQUE COMMENT '权限名称，如 user:delete',
+    `description` VARCHAR(255) NULL COMMENT '描述',
+    `created_at`  TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建日期'
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='权限表，* 表示所有权限';
+
+CREATE TABLE IF NOT EXISTS `role_permission`
+(
+    `role_id`       INT NOT NULL COMMENT '角色编号',
+    `permission_id` INT NOT NULL COMMENT '权限编号',
+    PRIMARY KEY (`role_id`, `permission_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='角色权限关联表';
+
+CREATE TABLE IF NOT EXISTS `user_role`
+(
+    `user_id` INT NOT NULL COMMENT '用户编号',
+    `role_id` INT NOT NULL COMMENT '角色编号',
+    PRIMARY KEY (`user_id`, `role_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='用户角色关联表';
+
+INSERT INTO `permission` (name, description)
+VALUES ('*', '所有权限'),
+       ('user:create', '新增用户'),
+       ('user:read', '查看用户'),
+       ('user:update', '更新用户'),
+       ('user:delete', '删除用户'),
+       ('user:restore', '恢复已删除用户'),
+       ('role:read', '查看角色与权限'),
+       ('role:manage', '管理角色并为用户分配角色');
+
+INSERT INTO `role` (name, description)
+VALUES ('admin', '管理员'),
+       ('user', '普通用户');
+
+INSERT INTO `role_permission` (role_id, permission_id)
+SELECT r.id, p.id
+FROM `role` r,
+     `permission` p
+WHERE (r.name = 'admin' AND p.name = '*')
+   OR (r.name = 'user' AND p.name = 'user:read');
+
+-- 已有用户默认为普通用户
+INSERT INTO `user_role` (user_id, role_id)
+SELECT u.id, r.id
+FROM `user` u,
+     `role` r
+WHERE r.name = 'user';
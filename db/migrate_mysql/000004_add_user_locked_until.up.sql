@@ -0,0 +1,5 @@
+ALTER TABLE `user`
+    ADD COLUMN `locked_until` TIMESTAMP NULL DEFAULT NULL COMMENT '登录失败次数过多时锁定至该时间';
+
+INSERT INTO `permission` (name, description)
+VALUES ('user:unlock', '解锁被锁定的用户');
@@ -0,0 +1,3 @@
+DELETE FROM `role_permission` WHERE permission_id IN (SELECT id FROM `permission` WHERE name = 'apikey:manage');
+DELETE FROM `permission` WHERE name = 'apikey:manage';
+DROP TABLE IF EXISTS `api_key`;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS `api_key`
+(
+    `id`           INT PRIMARY KEY AUTO_INCREMENT COMMENT '编号',
+    `name`         VARCHAR(64)   NOT NULL COMMENT '名称',
+    `key_prefix`   VARCHAR(16)   NOT NULL COMMENT '密钥前缀，用于识别密钥',
+    `key_hash`     VARCHAR(64)   NOT NULL UNIQUE COMMENT '密钥的 SHA-256 摘要',
+    `user_id`      INT           NOT NULL COMMENT '所属用户编号',
+    `scopes`       VARCHAR(1024) NOT NULL DEFAULT '' COMMENT '授权范围，空格分隔的权限名称',
+    `expires_at`   TIMESTAMP     NULL DEFAULT NULL COMMENT '过期时间，为空时不过期',
+    `last_used_at` TIMESTAMP     NULL DEFAULT NULL COMMENT '最后使用时间',
+    `revoked_at`   TIMESTAMP     NULL DEFAULT NULL COMMENT '吊销时间',
+    `created_at`   TIMESTAMP     NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建日期',
+    INDEX `idx_api_key_user_id` (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='API 密钥表';
+
+INSERT INTO `permission` (name, description)
+VALUES ('apikey:manage', '查看与吊销其他用户的 API 密钥');
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `user_identity`
+(
+    `id`         INT PRIMARY KEY AUTO_INCREMENT COMMENT '编号',
+    `user_id`    INT          NOT NULL COMMENT '用户编号',
+    `provider`   VARCHAR(64)  NOT NULL COMMENT '身份提供方名称',
+    `subject`    VARCHAR(255) NOT NULL COMMENT '身份提供方的用户标识 (ID Token 的 sub)',
+    `email`      VARCHAR(255) NULL DEFAULT NULL COMMENT '身份提供方返回的邮箱',
+    `created_at` TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建日期',
+    UNIQUE KEY `uk_user_identity_provider_subject` (`provider`, `subject`),
+    INDEX `idx_user_identity_user_id` (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='外部身份提供方账户关联表';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `user_recovery_code`;
+ALTER TABLE `user`
+    DROP COLUMN `totp_last_step`,
+    DROP COLUMN `totp_enabled_at`,
+    DROP COLUMN `totp_secret`;
@@ -0,0 +1,16 @@
+ALTER TABLE `user`
+    ADD COLUMN `totp_secret`     VARCHAR(255) NULL DEFAULT NULL COMMENT '加密后的 TOTP 密钥，确认前为待启用状态',
+    ADD COLUMN `totp_enabled_at` TIMESTAMP    NULL DEFAULT NULL COMMENT '启用两步验证的时间，为空时未启用',
+    ADD COLUMN `totp_last_step`  BIGINT       NULL DEFAULT NULL COMMENT '最后一次通过验证的 TOTP 时间步，防止验证码重放';
+
+CREATE TABLE IF NOT EXISTS `user_recovery_code`
+(
+    `id`         INT PRIMARY KEY AUTO_INCREMENT COMMENT '编号',
+    `user_id`    INT         NOT NULL COMMENT '用户编号',
+    `code_hash`  VARCHAR(64) NOT NULL COMMENT '恢复码的 SHA-256 摘要',
+    `used_at`    TIMESTAMP   NULL DEFAULT NULL COMMENT '使用时间，使用后失效',
+    `created_at` TIMESTAMP   NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建日期',
+    INDEX `idx_user_recovery_code_user_id` (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='两步验证恢复码表';
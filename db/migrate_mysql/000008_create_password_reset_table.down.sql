@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `password_reset_token`;
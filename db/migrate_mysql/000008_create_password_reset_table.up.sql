@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `password_reset_token`
+(
+    `id`         INT PRIMARY KEY AUTO_INCREMENT COMMENT '编号',
+    `token_hash` VARCHAR(64) NOT NULL UNIQUE COMMENT '重置令牌的 SHA-256 摘要',
+    `user_id`    INT         NOT NULL COMMENT '用户编号',
+    `expires_at` TIMESTAMP   NOT NULL COMMENT '过期时间',
+    `used_at`    TIMESTAMP   NULL DEFAULT NULL COMMENT '使用时间，使用后失效',
+    `created_at` TIMESTAMP   NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建日期',
+    INDEX `idx_password_reset_token_user_id` (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_ai_ci COMMENT ='密码重置令牌表';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "user";
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "user"
+(
+    id         SERIAL PRIMARY KEY,
+    username   VARCHAR(255) NOT NULL UNIQUE,
+    password   VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+COMMENT ON TABLE "user" IS '用户表';
+COMMENT ON COLUMN "user".id IS '编号';
+COMMENT ON COLUMN "user".username IS '用户账户';
+COMMENT ON COLUMN "user".password IS '用户密码';
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS token_blacklist;
+DROP TABLE IF EXISTS refresh_token;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS refresh_token
+(
+    id         SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    family     VARCHAR(64) NOT NULL,
+    user_id    INTEGER     NOT NULL,
+    expires_at TIMESTAMP   NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_token_family ON refresh_token (family);
+
+COMMENT ON TABLE refresh_token IS '刷新令牌表';
+COMMENT ON COLUMN refresh_token.token_hash IS '刷新令牌的 SHA-256 摘要';
+COMMENT ON COLUMN refresh_token.family IS '令牌族，同一次登录轮换出的令牌属于同一族';
+
+CREATE TABLE IF NOT EXISTS token_blacklist
+(
+    id         SERIAL PRIMARY KEY,
+    jti        VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP   NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE token_blacklist IS '访问令牌黑名单';
@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS role
+(
+    id          SERIAL PRIMARY KEY,
+    name        VARCHAR(64) NOT NULL UNIQUE,
+    description VARCHAR(255),
+    created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS permission
+(
+    id          SERIAL PRIMARY KEY,
+    name        VARCHAR(64) NOT NULL UNIQUE,
+    description VARCHAR(255),
+    created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permission
+(
+    role_id       INTEGER NOT NULL,
+    permission_id INTEGER NOT NULL,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_role
+(
+    user_id INTEGER NOT NULL,
+    role_id INTEGER NOT NULL,
+    PRIMARY KEY (user_id, role_id)
+);
+
+COMMENT ON TABLE role IS '角色表';
+COMMENT ON TABLE permission IS '权限表，* 表示所有权限';
+COMMENT ON TABLE role_permission IS '角色权限关联表';
+COMMENT ON TABLE user_role IS '用户角色关联表';
+
+INSERT INTO permission (name, description)
+VALUES ('*', '所有权限'),
+       ('user:create', '新增用户'),
+       ('user:read', '查看用户'),
+       ('user:update', '更新用户'),
+       ('user:delete', '删除用户'),
+       ('user:restore', '恢复已删除用户'),
+       ('role:read', '查看角色与权限'),
+       ('role:manage', '管理角色并为用户分配角色');
+
+INSERT INTO role (name, description)
+VALUES ('admin', '管理员'),
+       ('user', '普通用户');
+
+INSERT INTO role_permission (role_id, permission_id)
+SELECT r.id, p.id
+FROM role r,
+     permission p
+WHERE (r.name = 'admin' AND p.name = '*')
+   OR (r.name = 'user' AND p.name = 'user:read');
+
+-- 已有用户默认为普通用户
+INSERT INTO user_role (user_id, role_id)
+SELECT u.id, r.id
+FROM "user" u,
+     role r
+WHERE r.name = 'user';
@@ -0,0 +1,3 @@
+DELETE FROM role_permission WHERE permission_id IN (SELECT id FROM permission WHERE name = 'user:unlock');
+DELETE FROM permission WHERE name = 'user:unlock';
+ALTER TABLE "user" DROP COLUMN IF EXISTS locked_until;
@@ -0,0 +1,6 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+COMMENT ON COLUMN "user".locked_until IS '登录失败次数过多时锁定至该时间';
+
+INSERT INTO permission (name, description)
+VALUES ('user:unlock', '解锁被锁定的用户');
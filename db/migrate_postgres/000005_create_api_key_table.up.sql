@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS api_key
+(
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(64)   NOT NULL,
+    key_prefix   VARCHAR(16)   NOT NULL,
+    key_hash     VARCHAR(64)   NOT NULL UNIQUE,
+    user_id      INTEGER       NOT NULL,
+    scopes       VARCHAR(1024) NOT NULL DEFAULT '',
+    expires_at   TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at   TIMESTAMP,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_api_key_user_id ON api_key (user_id);
+
+COMMENT ON TABLE api_key IS 'API 密钥表';
+COMMENT ON COLUMN api_key.key_prefix IS '密钥前缀，用于识别密钥';
+COMMENT ON COLUMN api_key.key_hash IS '密钥的 SHA-256 摘要';
+COMMENT ON COLUMN api_key.scopes IS '授权范围，空格分隔的权限名称';
+
+INSERT INTO permission (name, description)
+VALUES ('apikey:manage', '查看与吊销其他用户的 API 密钥');
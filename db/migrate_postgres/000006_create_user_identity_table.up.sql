@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS user_identity
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER      NOT NULL,
+    provider   VARCHAR(64)  NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    email      VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject)
+);
+CREATE INDEX IF NOT EXISTS idx_user_identity_user_id ON user_identity (user_id);
+
+COMMENT ON TABLE user_identity IS '外部身份提供方账户关联表';
+COMMENT ON COLUMN user_identity.provider IS '身份提供方名称';
+COMMENT ON COLUMN user_identity.subject IS '身份提供方的用户标识 (ID Token 的 sub)';
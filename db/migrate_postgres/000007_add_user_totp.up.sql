@@ -0,0 +1,20 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(255);
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+COMMENT ON COLUMN "user".totp_secret IS '加密后的 TOTP 密钥，确认前为待启用状态';
+COMMENT ON COLUMN "user".totp_enabled_at IS '启用两步验证的时间，为空时未启用';
+COMMENT ON COLUMN "user".totp_last_step IS '最后一次通过验证的 TOTP 时间步，防止验证码重放';
+
+CREATE TABLE IF NOT EXISTS user_recovery_code
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER     NOT NULL,
+    code_hash  VARCHAR(64) NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_recovery_code_user_id ON user_recovery_code (user_id);
+
+COMMENT ON TABLE user_recovery_code IS '两步验证恢复码表';
+COMMENT ON COLUMN user_recovery_code.code_hash IS '恢复码的 SHA-256 摘要';
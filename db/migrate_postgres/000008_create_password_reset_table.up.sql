@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS password_reset_token
+(
+    id         SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    user_id    INTEGER     NOT NULL,
+    expires_at TIMESTAMP   NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_token_user_id ON password_reset_token (user_id);
+
+COMMENT ON TABLE password_reset_token IS '密码重置令牌表';
+COMMENT ON COLUMN password_reset_token.token_hash IS '重置令牌的 SHA-256 摘要';
+COMMENT ON COLUMN password_reset_token.used_at IS '使用时间，使用后失效';
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS refresh_token
+(
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    token_hash  TEXT    NOT NULL UNIQUE,
+    family      TEXT    NOT NULL,
+    user_id     INTEGER NOT NULL,
+    expires_at  TIMESTAMP NOT NULL,
+    revoked_at  TIMESTAMP,
+    created_at  TIMESTAMP DEFAULT (datetime(current_timestamp, 'localtime'))
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_token_family ON refresh_token (family);
+
+CREATE TABLE IF NOT EXISTS token_blacklist
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    jti        TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT (datetime(current_timestamp, 'localtime'))
+);
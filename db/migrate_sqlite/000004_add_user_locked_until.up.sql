@@ -0,0 +1,4 @@
+ALTER TABLE "user" ADD COLUMN locked_until TIMESTAMP;
+
+INSERT INTO permission (name, description)
+VALUES ('user:unlock', '解锁被锁定的用户');
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS api_key
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    name         TEXT    NOT NULL,
+    key_prefix   TEXT    NOT NULL,
+    key_hash     TEXT    NOT NULL UNIQUE,
+    user_id      INTEGER NOT NULL,
+    scopes       TEXT    NOT NULL DEFAULT '',
+    expires_at   TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at   TIMESTAMP,
+    created_at   TIMESTAMP DEFAULT (datetime(current_timestamp, 'localtime'))
+);
+CREATE INDEX IF NOT EXISTS idx_api_key_user_id ON api_key (user_id);
+
+INSERT INTO permission (name, description)
+VALUES ('apikey:manage', '查看与吊销其他用户的 API 密钥');
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_identity
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL,
+    provider   TEXT    NOT NULL,
+    subject    TEXT    NOT NULL,
+    email      TEXT,
+    created_at TIMESTAMP DEFAULT (datetime(current_timestamp, 'localtime')),
+    UNIQUE (provider, subject)
+);
+CREATE INDEX IF NOT EXISTS idx_user_identity_user_id ON user_identity (user_id);
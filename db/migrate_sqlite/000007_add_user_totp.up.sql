@@ -0,0 +1,13 @@
+ALTER TABLE "user" ADD COLUMN totp_secret TEXT;
+ALTER TABLE "user" ADD COLUMN totp_enabled_at TIMESTAMP;
+ALTER TABLE "user" ADD COLUMN totp_last_step INTEGER;
+
+CREATE TABLE IF NOT EXISTS user_recovery_code
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL,
+    code_hash  TEXT    NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP DEFAULT (datetime(current_timestamp, 'localtime'))
+);
+CREATE INDEX IF NOT EXISTS idx_user_recovery_code_user_id ON user_recovery_code (user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS password_reset_token
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    token_hash TEXT      NOT NULL UNIQUE,
+    user_id    INTEGER   NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP DEFAULT (datetime(current_timestamp, 'localtime'))
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_token_user_id ON password_reset_token (user_id);
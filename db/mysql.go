@@ -43,6 +43,7 @@ func InitializeMysql() {
 		log.Panic(err)
 	}
 	DB = db
+	initializeReplicas(driverName)
 }
 
 func MigrateMysql() {
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"app/conf"
+	"app/log"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	pgxv5 "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"strings"
+)
+
+func InitializePostgres() {
+	if !strings.Contains(conf.DB.Type, "postgres") {
+		log.Info("Postgres dont Enable")
+		return
+	}
+	driverName := "pgxWithHooks"
+	registerHooks(driverName, stdlib.GetDefaultDriver())
+
+	// 不存在则创建
+	connConfig, err := pgx.ParseConfig(conf.DB.DSN)
+	if err != nil {
+		log.Panic(err)
+	}
+	dbName := connConfig.Database
+	initConfig := connConfig.Copy()
+	initConfig.Database = "postgres"
+	initConnStr := stdlib.RegisterConnConfig(initConfig)
+	defer stdlib.UnregisterConnConfig(initConnStr)
+	initDb := getDBConnection(driverName, initConnStr)
+	defer initDb.Close()
+	if err = initDb.Ping(); err != nil {
+		log.Panic(err)
+	}
+	var exists bool
+	err = initDb.Get(&exists, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", dbName)
+	if err != nil {
+		log.Fatalf("无法连接到 PostgreSQL 服务器: %v", err)
+	}
+	if !exists {
+		createDbSql := fmt.Sprintf(`CREATE DATABASE "%s"`, strings.ReplaceAll(dbName, `"`, `""`))
+		if _, err = initDb.Exec(createDbSql); err != nil {
+			log.Fatalf("无法创建 PostgreSQL 数据库: %v", err)
+		}
+	}
+
+	db := getDBConnection(driverName, conf.DB.DSN)
+	if err = db.Ping(); err != nil {
+		log.Panic(err)
+	}
+	DB = db
+	initializeReplicas(driverName)
+}
+
+func MigratePostgres() {
+	if !strings.Contains(conf.DB.Type, "postgres") {
+		return
+	}
+
+	if !conf.DB.EnableMigrate {
+		log.Info("Postgres dont Enable Migrate")
+	}
+
+	sourceDriver, err := iofs.New(MigrationFS, "migrate_postgres")
+	if err != nil {
+		log.Fatalf("无法创建迁移源驱动: %v", err)
+	}
+	dbDriver, err := pgxv5.WithInstance(DB.DB, &pgxv5.Config{})
+	if err != nil {
+		log.Fatalf("无法创建数据库迁移驱动: %v", err)
+	}
+	m, err := migrate.NewWithInstance("iofs", sourceDriver, "pgx5", dbDriver)
+	if err != nil {
+		log.Fatalf("无法创建 migrate 实例: %v", err)
+	}
+	log.Info("开始执行数据库迁移...")
+	err = m.Up()
+	if err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			log.Info("数据库已经是最新版本，无需迁移。")
+		} else {
+			log.Fatalf("数据库迁移失败: %v", err)
+		}
+	} else {
+		log.Info("数据库迁移成功！")
+	}
+}
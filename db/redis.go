@@ -1,14 +1,10 @@
 package db
 
 import (
-	"app/code"
 	"app/conf"
 	"app/log"
 	"context"
-	"encoding/json"
-	"errors"
 	"github.com/go-redis/redis/v8"
-	"time"
 )
 
 func InitializeRedis() {
@@ -27,77 +23,7 @@ func InitializeRedis() {
 	}
 }
 
+// RedisDB Redis 客户端，未启用 Redis 时 RDB 为 nil；缓存读写使用 cache 包
 type RedisDB struct {
 	*redis.Client
 }
-
-func (rdb *RedisDB) SetStruct(key string, val any) error {
-	if !conf.Redis.Enable {
-		return nil
-	}
-
-	jsonData, err := json.Marshal(val)
-	if err != nil {
-		log.Error(err)
-		return err
-	}
-	err = rdb.Client.Set(context.Background(), key, jsonData, time.Duration(conf.Redis.Expire)*time.Second).Err()
-	if err != nil {
-		log.Error(err)
-		return err
-	}
-	return nil
-}
-
-func (rdb *RedisDB) SetStructWithExpire(key string, val any, expire time.Duration) error {
-	if !conf.Redis.Enable {
-		return nil
-	}
-
-	jsonData, err := json.Marshal(val)
-	if err != nil {
-		log.Error(err)
-		return err
-	}
-
-	err = rdb.Client.Set(context.Background(), key, jsonData, expire).Err()
-	if err != nil {
-		log.Error(err)
-		return err
-	}
-	return nil
-}
-
-func (rdb *RedisDB) GetStruct(key string, obj any) error {
-	if !conf.Redis.Enable {
-		return nil
-	}
-
-	jsonData, err := rdb.Client.Get(context.Background(), key).Bytes()
-	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return code.RedisKeyNotExist
-		}
-		log.Error(err)
-		return nil
-	}
-
-	err = json.Unmarshal(jsonData, obj)
-	if err != nil {
-		log.Error(err)
-		return nil
-	}
-	return nil
-}
-
-func (rdb *RedisDB) Delete(key string) error {
-	if !conf.Redis.Enable {
-		return nil
-	}
-	err := rdb.Client.Del(context.Background(), key).Err()
-	if err != nil {
-		log.Error(err)
-		return nil
-	}
-	return nil
-}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"app/conf"
+	"app/log"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultReplicaCheck 未配置 db.replicaCheck 时副本健康检查的间隔
+var DefaultReplicaCheck = 10 * time.Second
+
+// replicas 只读副本，未配置 db.replicas 时为 nil
+var replicas atomic.Pointer[replicaSet]
+
+// replica 只读副本，健康检查或查询出现连接错误时标记为不可用，直到下次健康检查成功
+type replica struct {
+	name    string
+	db      *sqlx.DB
+	healthy atomic.Bool
+}
+
+// markDown 标记副本不可用，状态变化时记录日志
+func (o *replica) markDown(err error) {
+	if o.healthy.Swap(false) {
+		log.Warnf("db replica %s is down: %v", o.name, err)
+	}
+}
+
+func (o *replica) markUp() {
+	if !o.healthy.Swap(true) {
+		log.Infof("db replica %s is up", o.name)
+	}
+}
+
+// replicaSet 在健康的副本间轮询分配查询
+type replicaSet struct {
+	list []*replica
+	next atomic.Uint64
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// pick 轮询返回一个健康的副本，全部不可用时返回 nil
+func (o *replicaSet) pick() *replica {
+	if o == nil {
+		return nil
+	}
+	n := uint64(len(o.list))
+	start := o.next.Add(1)
+	for i := range n {
+		if r := o.list[(start+i)%n]; r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// check 检查所有副本的连接
+func (o *replicaSet) check() {
+	for _, r := range o.list {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := r.db.PingContext(ctx); err != nil {
+			r.markDown(err)
+		} else {
+			r.markUp()
+		}
+		cancel()
+	}
+}
+
+func (o *replicaSet) run(interval time.Duration) {
+	defer o.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			o.check()
+		case <-o.stop:
+			return
+		}
+	}
+}
+
+func (o *replicaSet) close() error {
+	close(o.stop)
+	o.wg.Wait()
+	var errs []error
+	for _, r := range o.list {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// initializeReplicas 按 db.replicas 连接只读副本并开始健康检查，连接失败的副本在检查成功后启用
+func initializeReplicas(driverName string) {
+	if len(conf.DB.Replicas) == 0 {
+		// 重新初始化时关闭之前配置的副本
+		if old := replicas.Swap(nil); old != nil {
+			_ = old.close()
+		}
+		return
+	}
+	set := &replicaSet{stop: make(chan struct{})}
+	for i, dsn := range conf.DB.Replicas {
+		r := &replica{name: "#" + strconv.Itoa(i), db: getDBConnection(driverName, dsn)}
+		set.list = append(set.list, r)
+	}
+	set.check()
+	interval := time.Duration(conf.DB.ReplicaCheck) * time.Second
+	if interval <= 0 {
+		interval = DefaultReplicaCheck
+	}
+	set.wg.Add(1)
+	go set.run(interval)
+	if old := replicas.Swap(set); old != nil {
+		_ = old.close()
+	}
+	log.Infof("db read replicas: %d", len(set.list))
+}
+
+// Close 关闭主库与只读副本的连接
+func Close() error {
+	var errs []error
+	if set := replicas.Swap(nil); set != nil {
+		errs = append(errs, set.close())
+	}
+	if DB != nil {
+		errs = append(errs, DB.Close())
+	}
+	return errors.Join(errs...)
+}
+
+type primaryKey struct{}
+
+// WithPrimary 返回查询也使用主库的 ctx，用于读取刚写入的数据 (read your writes)
+func WithPrimary(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadYourWrites 请求写入数据后调用，此后该请求的查询都使用主库，避免从延迟的副本读到旧数据
+// 在 WithFiberTx 中调用时只在事务内生效，事务内的查询本就使用主库，应在事务结束后调用
+func ReadYourWrites(c *fiber.Ctx) {
+	c.SetUserContext(WithPrimary(c.UserContext()))
+}
+
+func usePrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
+
+// isReadQuery 只有 SELECT 语句发往副本，如 INSERT ... RETURNING 通过 Get 执行时仍使用主库
+func isReadQuery(query string) bool {
+	query = strings.TrimLeft(query, " \t\r\n(")
+	return len(query) >= 6 && strings.EqualFold(query[:6], "SELECT")
+}
+
+// isConnError 是否为连接错误，此时副本标记为不可用并改用主库重试
+func isConnError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
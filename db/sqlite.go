@@ -30,6 +30,7 @@ func InitializeSqlite() {
 		log.Panic(err)
 	}
 	DB = db
+	initializeReplicas(driverName)
 }
 
 func MigrateSqlite() {
@@ -0,0 +1,225 @@
+package db
+
+import (
+	"app/conf"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+// Executor 仓库执行 SQL 的方法集合，由 Conn 绑定 context，每条语句应用 db.queryTimeout
+type Executor interface {
+	Get(dest any, query string, args ...any) error
+	Select(dest any, query string, args ...any) error
+	Exec(query string, args ...any) (sql.Result, error)
+	NamedExec(query string, arg any) (sql.Result, error)
+	BindNamed(query string, arg any) (string, []any, error)
+	Rebind(query string) string
+}
+
+// extContext 是 *sqlx.DB 与 *sqlx.Tx 共有的带 context 的方法集合
+type extContext interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error)
+}
+
+// executor 绑定了 context 的 *sqlx.DB 或 *Tx，context 取消或超时时中止语句，SQL 日志中带有 context 中的 trace id
+// 耗时包括读取结果，语句与参数由 Hooks 取得
+type executor struct {
+	ctx context.Context
+	ext extContext
+	// routable 不在事务中，查询可以发往只读副本
+	routable bool
+}
+
+func (o *executor) Get(dest any, query string, args ...any) error {
+	ctx, done := o.begin()
+	err := o.read(ctx, query, func(ext extContext) error {
+		return ext.GetContext(ctx, dest, query, args...)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		done(0, nil)
+	} else {
+		done(1, err)
+	}
+	return err
+}
+
+func (o *executor) Select(dest any, query string, args ...any) error {
+	ctx, done := o.begin()
+	err := o.read(ctx, query, func(ext extContext) error {
+		return ext.SelectContext(ctx, dest, query, args...)
+	})
+	var rows int64
+	if v := reflect.Indirect(reflect.ValueOf(dest)); v.Kind() == reflect.Slice {
+		rows = int64(v.Len())
+	}
+	done(rows, err)
+	return err
+}
+
+func (o *executor) Exec(query string, args ...any) (sql.Result, error) {
+	ctx, done := o.begin()
+	result, err := o.ext.ExecContext(ctx, query, args...)
+	done(rowsAffected(result), err)
+	return result, err
+}
+
+func (o *executor) NamedExec(query string, arg any) (sql.Result, error) {
+	ctx, done := o.begin()
+	result, err := o.ext.NamedExecContext(ctx, query, arg)
+	done(rowsAffected(result), err)
+	return result, err
+}
+
+func (o *executor) BindNamed(query string, arg any) (string, []any, error) {
+	return o.ext.BindNamed(query, arg)
+}
+
+func (o *executor) Rebind(query string) string {
+	return o.ext.Rebind(query)
+}
+
+// read 执行查询：不在事务中且未要求使用主库 (WithPrimary) 时发往健康的只读副本，
+// 副本出现连接错误时标记为不可用并改用主库重试；其他语句与写入都使用主库
+func (o *executor) read(ctx context.Context, query string, fn func(ext extContext) error) error {
+	if !o.routable || usePrimary(ctx) || !isReadQuery(query) {
+		return fn(o.ext)
+	}
+	r := replicas.Load().pick()
+	if r == nil {
+		return fn(o.ext)
+	}
+	err := fn(r.db)
+	if err != nil && ctx.Err() == nil && isConnError(err) {
+		r.markDown(err)
+		return fn(o.ext)
+	}
+	return err
+}
+
+// begin 为单条语句创建 context，返回的 done 在读取完结果后调用，记录耗时、行数与慢查询并释放超时
+func (o *executor) begin() (context.Context, func(rows int64, err error)) {
+	ctx, cancel := queryContext(o.ctx)
+	stat := &queryStat{}
+	ctx = context.WithValue(ctx, queryStatKey{}, stat)
+	start := time.Now()
+	return ctx, func(rows int64, err error) {
+		cancel()
+		// 语句未到达驱动 (如参数绑定失败) 时不记录
+		if stat.query != "" {
+			observeQuery(ctx, stat.query, stat.args, time.Since(start), rows, err)
+		}
+	}
+}
+
+func rowsAffected(result sql.Result) int64 {
+	if result == nil {
+		return -1
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// queryContext 为单条语句设置 db.queryTimeout 超时，ctx 的截止时间更早时以 ctx 为准
+func queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if conf.DB.QueryTimeout > 0 {
+		return context.WithTimeout(ctx, time.Duration(conf.DB.QueryTimeout)*time.Second)
+	}
+	return ctx, func() {}
+}
+
+type txKey struct{}
+
+// Tx 是携带在 context 中的事务
+type Tx struct {
+	*sqlx.Tx
+	afterCommit []func()
+}
+
+// Conn 返回绑定 ctx 的执行器：ctx 中存在事务时使用该事务，否则写入使用全局 DB，查询按 db.replicas 发往只读副本
+func Conn(ctx context.Context) Executor {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if tx := TxFrom(ctx); tx != nil {
+		return &executor{ctx: ctx, ext: tx}
+	}
+	return &executor{ctx: ctx, ext: DB, routable: true}
+}
+
+// TxFrom 从 ctx 中取出事务，不存在时返回 nil
+func TxFrom(ctx context.Context) *Tx {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(txKey{}).(*Tx)
+	return tx
+}
+
+// WithTx 在事务中执行 fn，fn 收到的 ctx 携带该事务
+// fn 返回错误或 panic 时回滚，否则提交；ctx 中已存在事务时直接加入外层事务
+func WithTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if TxFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	sqlxTx, err := DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	tx := &Tx{Tx: sqlxTx}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = fmt.Errorf("%w; rollback failed: %v", err, rbErr)
+			}
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			return
+		}
+		for _, f := range tx.afterCommit {
+			f()
+		}
+	}()
+	return fn(context.WithValue(ctx, txKey{}, tx))
+}
+
+// WithFiberTx 在事务中执行 fn，事务期间 c.UserContext() 携带该事务，
+// 因此 fn 内通过 c 调用的仓库方法都会加入同一个事务
+func WithFiberTx(c *fiber.Ctx, fn func(c *fiber.Ctx) error) error {
+	parent := c.UserContext()
+	defer c.SetUserContext(parent)
+	return WithTx(parent, func(ctx context.Context) error {
+		c.SetUserContext(ctx)
+		return fn(c)
+	})
+}
+
+// AfterCommit 注册事务提交后执行的回调(如缓存写入/失效)，ctx 中没有事务时立即执行
+func AfterCommit(ctx context.Context, f func()) {
+	if tx := TxFrom(ctx); tx != nil {
+		tx.afterCommit = append(tx.afterCommit, f)
+		return
+	}
+	f()
+}
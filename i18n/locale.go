@@ -46,17 +46,32 @@ func Localize(id string) string {
 }
 
 func LocalizeWithCtx(c *fiber.Ctx, id string) string {
+	return LocalizeWithLang(LangOf(c), id)
+}
+
+// LocalizeWithCtxData 按请求语言翻译带模板参数的消息，e.g., "{{.Field}} 不能为空"
+func LocalizeWithCtxData(c *fiber.Ctx, id string, data map[string]any) string {
+	return LocalizeWithLangData(LangOf(c), id, data)
+}
+
+// LangOf 根据 cookie 中的 lang 与 Accept-Language 请求头匹配支持的语言
+func LangOf(c *fiber.Ctx) language.Tag {
 	var matcher = language.NewMatcher(Languages)
 	lang := c.Cookies("lang")
 	accept := c.Get("Accept-Language")
 	tag, _ := language.MatchStrings(matcher, lang, accept)
-	return LocalizeWithLang(tag, id)
+	return tag
 }
 
 func LocalizeWithLang(lang language.Tag, id string) string {
+	return LocalizeWithLangData(lang, id, nil)
+}
+
+func LocalizeWithLangData(lang language.Tag, id string, data map[string]any) string {
 	localizer := i18n.NewLocalizer(Bundle, lang.String())
-	msg, err := localizer.LocalizeMessage(&i18n.Message{
-		ID: id,
+	msg, err := localizer.Localize(&i18n.LocalizeConfig{
+		MessageID:    id,
+		TemplateData: data,
 	})
 	if err != nil {
 		log.Debug(err)
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets 默认的耗时分桶（秒），5ms 至 10s
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram 带标签的直方图，按 Prometheus 文本格式输出
+// e.g., h := metrics.NewHistogram("db_query_duration_seconds", "...", metrics.DefaultBuckets, "operation"); h.Observe(0.01, "select")
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+	labels  []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	counts []uint64 // 每个分桶内的次数，最后一个为 +Inf
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram 创建直方图并注册到默认注册表，buckets 需升序
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		labels:  labels,
+		series:  map[string]*series{},
+	}
+	Register(h)
+	return h
+}
+
+// Observe 记录一次观测值，labelValues 与创建时的标签一一对应
+func (o *Histogram) Observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s, ok := o.series[key]
+	if !ok {
+		s = &series{values: slices.Clone(labelValues), counts: make([]uint64, len(o.buckets)+1)}
+		o.series[key] = s
+	}
+	i, _ := slices.BinarySearch(o.buckets, v)
+	s.counts[i]++
+	s.count++
+	s.sum += v
+}
+
+// Write 按 Prometheus 文本格式输出，分桶为累计值
+func (o *Histogram) Write(w io.Writer) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", o.name, o.help, o.name); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(o.series))
+	for key := range o.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		s := o.series[key]
+		var cumulative uint64
+		for i, count := range s.counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(o.buckets) {
+				le = formatFloat(o.buckets[i])
+			}
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", o.name, o.labelPairs(s.values, "le", le), cumulative); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n",
+			o.name, o.labelPairs(s.values), formatFloat(s.sum), o.name, o.labelPairs(s.values), s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// labelPairs 生成 {name="value",...}，extra 为追加的名称与值，没有标签时为空
+func (o *Histogram) labelPairs(values []string, extra ...string) string {
+	pairs := make([]string, 0, len(o.labels)+len(extra)/2)
+	for i, name := range o.labels {
+		pairs = append(pairs, name+"="+strconv.Quote(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_Histogram(t *testing.T) {
+	h := &Histogram{name: "test_seconds", help: "Test.", buckets: []float64{0.1, 1}, labels: []string{"op"}, series: map[string]*series{}}
+	h.Observe(0.05, "select")
+	h.Observe(0.1, "select")
+	h.Observe(5, "select")
+	h.Observe(0.5, "insert")
+
+	var buf bytes.Buffer
+	if err := h.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_seconds Test.
+# TYPE test_seconds histogram
+test_seconds_bucket{op="insert",le="0.1"} 0
+test_seconds_bucket{op="insert",le="1"} 1
+test_seconds_bucket{op="insert",le="+Inf"} 1
+test_seconds_sum{op="insert"} 0.5
+test_seconds_count{op="insert"} 1
+test_seconds_bucket{op="select",le="0.1"} 2
+test_seconds_bucket{op="select",le="1"} 2
+test_seconds_bucket{op="select",le="+Inf"} 3
+test_seconds_sum{op="select"} 5.15
+test_seconds_count{op="select"} 3
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func Test_WriteAll(t *testing.T) {
+	h := NewHistogram("registered_seconds", "Registered.", DefaultBuckets)
+	h.Observe(0.2)
+	var buf bytes.Buffer
+	if err := WriteAll(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "registered_seconds_count 1\n") {
+		t.Fatalf("WriteAll() = %s", buf.String())
+	}
+}
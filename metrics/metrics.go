@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"io"
+	"sync"
+)
+
+// Collector 可按 Prometheus 文本格式输出的指标
+type Collector interface {
+	Write(w io.Writer) error
+}
+
+var (
+	collectors []Collector
+	mu         sync.Mutex
+)
+
+// Register 注册指标，WriteAll 按注册顺序输出
+func Register(c Collector) {
+	mu.Lock()
+	defer mu.Unlock()
+	collectors = append(collectors, c)
+}
+
+// WriteAll 按 Prometheus 文本格式输出所有已注册的指标
+func WriteAll(w io.Writer) error {
+	mu.Lock()
+	list := append([]Collector(nil), collectors...)
+	mu.Unlock()
+	for _, c := range list {
+		if err := c.Write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"app/code"
+	"app/conf"
+	"app/log"
+	"app/model"
+	"app/repo"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	ApiKeyHeader = "X-API-Key" // 请求头中携带 API 密钥
+	ApiKeyPrefix = "ak_"       // API 密钥的固定前缀，便于识别泄露的密钥
+)
+
+// apiKeyTouchInterval 最后使用时间的更新间隔，避免每次请求都写数据库
+const apiKeyTouchInterval = time.Minute
+
+var (
+	apiKeys     = repo.NewApiKeyRepo()
+	apiKeyUsers = repo.NewUserRepo()
+	apiKeyRoles = repo.NewRoleRepo()
+)
+
+// Authenticate 校验 X-API-Key 请求头中的 API 密钥，没有该请求头时按 JwtAuth 校验访问令牌
+// 两种方式都会设置 CurrentUser，API 密钥的权限为所属用户的权限与密钥授权范围的交集
+func Authenticate() fiber.Handler {
+	jwtAuth := JwtAuth()
+	return func(c *fiber.Ctx) error {
+		key := c.Get(ApiKeyHeader)
+		if key == "" {
+			return jwtAuth(c)
+		}
+		principal, err := apiKeyPrincipal(c, key)
+		if err != nil {
+			return err
+		}
+		c.Locals(principalLocalKey, principal)
+		c.SetUserContext(model.WithPrincipal(c.UserContext(), principal))
+		return c.Next()
+	}
+}
+
+func apiKeyPrincipal(c *fiber.Ctx, key string) (*model.Principal, error) {
+	apiKey, err := apiKeys.SelectByHash(c, HashApiKey(key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, code.AuthFailed
+	} else if err != nil {
+		return nil, code.DatabaseError.Wrap(err)
+	}
+	now := time.Now()
+	if !apiKey.Active(now) {
+		log.F(c).Warnf("inactive api key used, id: %d", *apiKey.Id)
+		return nil, code.AuthFailed
+	}
+	user, err := apiKeyUsers.SelectById(c, *apiKey.UserId)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 密钥所属用户已被删除
+		return nil, code.AuthFailed
+	} else if err != nil {
+		return nil, code.DatabaseError.Wrap(err)
+	}
+	roles, err := apiKeyRoles.SelectByUserId(c, *user.Id)
+	if err != nil {
+		return nil, code.DatabaseError.Wrap(err)
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		if err := apiKeys.Touch(c, *apiKey.Id, now); err != nil {
+			log.F(c).Error(err)
+		}
+	}
+	return &model.Principal{
+		UserId:   *user.Id,
+		Username: *user.Username,
+		Roles:    RoleNames(user, roles),
+		ApiKeyId: *apiKey.Id,
+		Scopes:   apiKey.ScopeList(),
+	}, nil
+}
+
+// HashApiKey 数据库中只保存 API 密钥的摘要
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// RoleNames 用户的角色名称，rbac.admins 中的用户始终拥有管理员角色
+func RoleNames(user *model.User, list []model.Role) []string {
+	roles := make([]string, 0, len(list)+1)
+	for _, role := range list {
+		roles = append(roles, *role.Name)
+	}
+	if slices.Contains(conf.Rbac.Admins, *user.Username) && !slices.Contains(roles, model.RoleAdmin) {
+		roles = append(roles, model.RoleAdmin)
+	}
+	return roles
+}
@@ -3,12 +3,22 @@ package middleware
 import (
 	"app/code"
 	"app/conf"
-	jwtware "github.com/gofiber/contrib/jwt"
+	"app/log"
+	"app/model"
+	"app/repo"
+	"app/util/jwtutil"
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
-	"time"
+	"github.com/google/uuid"
 )
 
+// SecretAuth 校验 X-API-Secret 请求头是否为 server.secret
+//
+// Deprecated: 所有客户端共用同一个密钥，无法单独吊销，使用 Authenticate 与按客户端签发的 API 密钥
 func SecretAuth() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		clientSecret := c.Get("X-API-Secret") // 从请求头获取
@@ -19,23 +29,192 @@ func SecretAuth() fiber.Handler {
 	}
 }
 
+// tokenBlacklist 已注销的访问令牌
+var tokenBlacklist = repo.NewTokenBlacklistRepo()
+
+// JwtAuth 校验访问令牌，已注销 (jti 在黑名单中) 的令牌视为无效
+// 令牌经 bearerToken 解析，与 Limiter 共用同一次签名校验
 func JwtAuth() fiber.Handler {
-	return jwtware.New(jwtware.Config{
-		SigningKey: jwtware.SigningKey{Key: []byte(conf.Server.Secret)},
-	})
+	return func(c *fiber.Ctx) error {
+		token, err := bearerToken(c)
+		if err != nil {
+			return code.AuthFailed.Wrap(err)
+		}
+		c.Locals("user", token)
+		claims := TokenClaims(c)
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			return code.AuthFailed
+		}
+		// 两步验证登录令牌只能用于 /login/mfa
+		if typ, _ := claims["typ"].(string); typ != "" {
+			return code.AuthFailed
+		}
+		revoked, err := tokenBlacklist.Exists(c, jti)
+		if err != nil {
+			return code.DatabaseError.Wrap(err)
+		}
+		if revoked {
+			log.F(c).Warnf("revoked token used, jti: %s", jti)
+			return code.AuthFailed
+		}
+		principal := CurrentUser(c)
+		if principal == nil {
+			return code.AuthFailed
+		}
+		c.SetUserContext(model.WithPrincipal(c.UserContext(), principal))
+		return c.Next()
+	}
+}
+
+// bearerTokenLocalKey 已解析的 Authorization 请求头在 c.Locals 中的键
+const bearerTokenLocalKey = "bearerToken"
+
+// bearerResult 请求头中访问令牌的解析结果
+type bearerResult struct {
+	token *jwt.Token
+	err   error
 }
 
+// errMissingBearer 请求头中没有 Bearer 令牌
+var errMissingBearer = errors.New("missing or malformed bearer token")
+
+// bearerToken 使用 jwtutil.Current() 的密钥校验请求头中的访问令牌，只校验签名与有效期
+// 结果保存在 c.Locals 中，同一请求内 Limiter 与 JwtAuth 只解析一次；每个请求都取当前密钥，重新加载密钥 (如轮换) 后无需重新注册路由
+func bearerToken(c *fiber.Ctx) (*jwt.Token, error) {
+	if r, ok := c.Locals(bearerTokenLocalKey).(*bearerResult); ok {
+		return r.token, r.err
+	}
+	r := &bearerResult{err: errMissingBearer}
+	if tokenString, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok && tokenString != "" {
+		r.token, r.err = jwtutil.Current().Parse(tokenString)
+	}
+	c.Locals(bearerTokenLocalKey, r)
+	return r.token, r.err
+}
+
+// JwtExpireTime 未配置 jwt.accessExpire 时访问令牌的默认有效期
 var JwtExpireTime = time.Hour * 7
 
-func GenerateJwt(username string) (string, error) {
+// AccessExpire 访问令牌有效期
+func AccessExpire() time.Duration {
+	if conf.Jwt.AccessExpire > 0 {
+		return time.Duration(conf.Jwt.AccessExpire) * time.Second
+	}
+	return JwtExpireTime
+}
+
+// GenerateJwt 生成访问令牌，使用 jwt.signingKey 签名，jti 用于注销时加入黑名单，roles 为用户的角色名称
+func GenerateJwt(userId int, username string, roles []string) (string, error) {
 	now := time.Now()
+	if roles == nil {
+		roles = []string{}
+	}
 	claims := jwt.MapClaims{
+		"jti":      uuid.NewString(),
+		"uid":      userId,
 		"username": username,
-		"exp":      jwt.NewNumericDate(now.Add(JwtExpireTime)),
+		"roles":    roles,
+		"exp":      jwt.NewNumericDate(now.Add(AccessExpire())),
 		"iat":      jwt.NewNumericDate(now),
 		"nbf":      jwt.NewNumericDate(now),
 		"iss":      conf.AppName,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(conf.Server.Secret))
+	return jwtutil.Current().Sign(claims)
+}
+
+// TokenTypeMfa 两步验证登录令牌的 typ 声明，JwtAuth 不接受带 typ 的令牌
+const TokenTypeMfa = "mfa"
+
+// MfaExpireTime 未配置 mfa.challengeExpire 时两步验证登录令牌的默认有效期
+var MfaExpireTime = 5 * time.Minute
+
+// MfaExpire 两步验证登录令牌有效期
+func MfaExpire() time.Duration {
+	if conf.Mfa.ChallengeExpire > 0 {
+		return time.Duration(conf.Mfa.ChallengeExpire) * time.Second
+	}
+	return MfaExpireTime
+}
+
+// GenerateMfaJwt 生成两步验证登录令牌，密码校验通过后返回，使用第二因素验证后换取访问令牌
+func GenerateMfaJwt(userId int) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"jti": uuid.NewString(),
+		"typ": TokenTypeMfa,
+		"uid": userId,
+		"exp": jwt.NewNumericDate(now.Add(MfaExpire())),
+		"iat": jwt.NewNumericDate(now),
+		"nbf": jwt.NewNumericDate(now),
+		"iss": conf.AppName,
+	}
+	return jwtutil.Current().Sign(claims)
+}
+
+// ParseMfaJwt 校验两步验证登录令牌，返回用户编号
+func ParseMfaJwt(tokenString string) (int, error) {
+	token, err := jwtutil.Current().Parse(tokenString)
+	if err != nil {
+		return 0, code.AuthFailed.Wrap(err)
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	uid, _ := claims["uid"].(float64)
+	if typ, _ := claims["typ"].(string); typ != TokenTypeMfa || uid <= 0 {
+		return 0, code.AuthFailed
+	}
+	return int(uid), nil
+}
+
+// Jwks 公开访问令牌的验证公钥，其他服务可据此离线校验令牌
+func Jwks() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(jwtutil.Current().JWKS())
+	}
+}
+
+// TokenClaims 返回 JwtAuth 校验通过的访问令牌载荷，未经过 JwtAuth 时返回空
+func TokenClaims(c *fiber.Ctx) jwt.MapClaims {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return jwt.MapClaims{}
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	if claims == nil {
+		return jwt.MapClaims{}
+	}
+	return claims
+}
+
+// TokenRoles 返回访问令牌中的角色名称
+func TokenRoles(c *fiber.Ctx) []string {
+	items, _ := TokenClaims(c)["roles"].([]any)
+	roles := make([]string, 0, len(items))
+	for _, item := range items {
+		if role, ok := item.(string); ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// principalLocalKey 当前用户在 c.Locals 中的键
+const principalLocalKey = "principal"
+
+// CurrentUser 返回当前认证用户，未经过 JwtAuth 或令牌中没有用户编号时返回 nil
+// 也可通过 model.PrincipalFrom(c.UserContext()) 在 service/repo 中获取
+func CurrentUser(c *fiber.Ctx) *model.Principal {
+	if p, ok := c.Locals(principalLocalKey).(*model.Principal); ok {
+		return p
+	}
+	claims := TokenClaims(c)
+	uid, ok := claims["uid"].(float64)
+	if !ok {
+		return nil
+	}
+	username, _ := claims["username"].(string)
+	p := &model.Principal{UserId: int(uid), Username: username, Roles: TokenRoles(c)}
+	c.Locals(principalLocalKey, p)
+	return p
 }
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"app/conf"
+	"app/db"
+	"app/i18n"
+	"app/log"
+	"app/util/jwtutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_JwtAuthReloadKeys(t *testing.T) {
+	conf.Initialize()
+	log.Initialize()
+	db.Initialize()
+	i18n.Initialize()
+	secret, keys := conf.Server.Secret, conf.Jwt.Keys
+	t.Cleanup(func() {
+		conf.Server.Secret, conf.Jwt.Keys = secret, keys
+		jwtutil.Initialize()
+	})
+	conf.Jwt.Keys = nil
+	conf.Server.Secret = "before-reload"
+	jwtutil.Initialize()
+
+	app := fiber.New()
+	app.Use(TraceId(), ErrorParse())
+	app.Get("/", JwtAuth(), func(c *fiber.Ctx) error { return nil })
+
+	// 路由注册后重新加载密钥，新密钥签发的令牌应通过校验
+	conf.Server.Secret = "after-reload"
+	jwtutil.Initialize()
+	token, err := GenerateJwt(1, "reload", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("token signed with reloaded keys should be accepted, got %d", resp.StatusCode)
+	}
+}
+
+func Test_BearerTokenParsedOnce(t *testing.T) {
+	conf.Initialize()
+	log.Initialize()
+	secret, keys := conf.Server.Secret, conf.Jwt.Keys
+	t.Cleanup(func() {
+		conf.Server.Secret, conf.Jwt.Keys = secret, keys
+		jwtutil.Initialize()
+	})
+	conf.Jwt.Keys = nil
+	conf.Server.Secret = "bearer-once"
+	jwtutil.Initialize()
+	token, err := GenerateJwt(7, "bearer", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	if uid, ok := bearerUserId(c); !ok || uid != "7" {
+		t.Fatalf("limiter should read the user id from the token, got %q %v", uid, ok)
+	}
+	// 同一请求内 JwtAuth 复用 Limiter 的解析结果，不再校验签名
+	conf.Server.Secret = "bearer-other"
+	jwtutil.Initialize()
+	parsed, err := bearerToken(c)
+	if err != nil || parsed == nil {
+		t.Fatalf("bearer token should be parsed once per request, got %v", err)
+	}
+
+	missing := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(missing)
+	if _, err := bearerToken(missing); err == nil {
+		t.Fatal("missing bearer token should fail")
+	}
+}
@@ -1,16 +1,119 @@
 package middleware
 
 import (
-	"github.com/gofiber/contrib/circuitbreaker"
-	"github.com/gofiber/fiber/v2"
+	"app/code"
+	"app/conf"
+	"app/log"
+	"app/repo"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 )
 
+// rateLimits 限流计数
+var rateLimits = repo.NewRateLimitRepo()
+
+// Limiter 按 conf.Limiter 中的规则限流，响应头中返回 RateLimit-* 配额信息，超限时返回 429 与 Retry-After
+// 计数存储出错时放行请求
 func Limiter() fiber.Handler {
-	cb := circuitbreaker.New(circuitbreaker.Config{
-		FailureThreshold: 3,
-		Timeout:          5 * time.Second,
-		SuccessThreshold: 2,
-	})
-	return circuitbreaker.Middleware(cb)
+	return func(c *fiber.Ctx) error {
+		if !conf.Limiter.Enable {
+			return c.Next()
+		}
+		rule := matchRule(conf.Limiter.Rules, c.Method(), c.Path())
+		if rule == nil || rule.Limit <= 0 || rule.Window <= 0 {
+			return c.Next()
+		}
+		window := time.Duration(rule.Window) * time.Second
+		key := rule.Name + ":" + limitIdentity(c, rule.Key)
+		var result repo.RateLimitResult
+		var err error
+		if rule.Algorithm == "token_bucket" {
+			result, err = rateLimits.TokenBucket(c, key, rule.Limit, window)
+		} else {
+			result, err = rateLimits.SlidingWindow(c, key, rule.Limit, window)
+		}
+		if err != nil {
+			log.F(c).Error(err)
+			return c.Next()
+		}
+		c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+		c.Set("RateLimit-Policy", strconv.Itoa(rule.Limit)+";w="+strconv.Itoa(rule.Window))
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, ceilSeconds(result.RetryAfter))
+			return code.TooManyRequests
+		}
+		return c.Next()
+	}
+}
+
+// matchRule 按请求方法过滤规则后取路由前缀最长的一条
+func matchRule(rules []conf.LimiterRuleConf, method, path string) *conf.LimiterRuleConf {
+	var matched *conf.LimiterRuleConf
+	for i := range rules {
+		rule := &rules[i]
+		if len(rule.Methods) > 0 && !slices.ContainsFunc(rule.Methods, func(m string) bool { return strings.EqualFold(m, method) }) {
+			continue
+		}
+		if !hasPathPrefix(path, rule.Prefix) {
+			continue
+		}
+		if matched == nil || len(rule.Prefix) > len(matched.Prefix) {
+			matched = rule
+		}
+	}
+	return matched
+}
+
+// hasPathPrefix 按路径段匹配前缀，/api/v1/login 不匹配 /api/v1/logins
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// limitIdentity 按规则的限流维度识别请求方，无法识别时退回客户端 IP
+func limitIdentity(c *fiber.Ctx, kind string) string {
+	switch kind {
+	case "user":
+		if uid, ok := bearerUserId(c); ok {
+			return "user:" + uid
+		}
+	case "secret":
+		secret := c.Get(ApiKeyHeader)
+		if secret == "" {
+			secret = c.Get("X-API-Secret")
+		}
+		if secret != "" {
+			sum := sha256.Sum256([]byte(secret))
+			return "secret:" + hex.EncodeToString(sum[:8])
+		}
+	}
+	return "ip:" + c.IP()
+}
+
+// bearerUserId 请求头中访问令牌的用户编号，限流在 JwtAuth 之前执行，解析结果经 bearerToken 与 JwtAuth 共用
+func bearerUserId(c *fiber.Ctx) (string, bool) {
+	token, err := bearerToken(c)
+	if err != nil {
+		return "", false
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	uid, ok := claims["uid"].(float64)
+	if !ok {
+		return "", false
+	}
+	return strconv.Itoa(int(uid)), true
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
 }
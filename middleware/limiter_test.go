@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"app/conf"
+	"app/i18n"
+	"app/log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func Test_MatchRule(t *testing.T) {
+	rules := []conf.LimiterRuleConf{
+		{Name: "default", Prefix: "/api"},
+		{Name: "login", Prefix: "/api/v1/login", Methods: []string{"POST"}},
+	}
+	cases := []struct {
+		method, path, want string
+	}{
+		{"POST", "/api/v1/login", "login"},
+		{"GET", "/api/v1/login", "default"},
+		{"POST", "/api/v1/logins", "default"},
+		{"GET", "/swagger", ""},
+	}
+	for _, tc := range cases {
+		rule := matchRule(rules, tc.method, tc.path)
+		got := ""
+		if rule != nil {
+			got = rule.Name
+		}
+		if got != tc.want {
+			t.Errorf("%s %s: got rule %q, want %q", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
+
+func Test_Limiter(t *testing.T) {
+	conf.Initialize()
+	log.Initialize()
+	i18n.Initialize()
+	limiter := conf.Limiter
+	conf.Limiter = conf.LimiterConf{Enable: true, Rules: []conf.LimiterRuleConf{
+		{Name: "test_" + t.Name(), Prefix: "/api", Key: "secret", Limit: 2, Window: 60},
+	}}
+	t.Cleanup(func() { conf.Limiter = limiter })
+
+	app := fiber.New()
+	app.Use(TraceId(), ErrorParse(), Limiter())
+	app.Get("/api/ping", func(c *fiber.Ctx) error { return c.SendString("pong") })
+	request := func(secret string) *http.Response {
+		req := httptest.NewRequest("GET", "/api/ping", nil)
+		req.Header.Set("X-API-Secret", secret)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp := request("a"); resp.StatusCode != fiber.StatusOK || resp.Header.Get("RateLimit-Limit") != "2" {
+			t.Fatalf("request %d should pass: %d %v", i, resp.StatusCode, resp.Header)
+		}
+	}
+	resp := request("a")
+	if resp.StatusCode != fiber.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" ||
+		resp.Header.Get("RateLimit-Remaining") != "0" || resp.Header.Get("RateLimit-Policy") != "2;w=60" {
+		t.Fatalf("request over limit should be rejected: %d %v", resp.StatusCode, resp.Header)
+	}
+	// 不同的密钥分别计数
+	if resp := request("b"); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("other secret should pass: %d", resp.StatusCode)
+	}
+}
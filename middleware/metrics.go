@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"app/metrics"
+	"bytes"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/monitor"
+)
+
+// Metrics 监控端点：Prometheus 抓取 (Accept 为 text/plain 或 openmetrics) 或 ?format=prometheus 时按文本格式输出 metrics 中注册的指标，
+// 否则为 fiber monitor 面板
+func Metrics() fiber.Handler {
+	dashboard := monitor.New()
+	return func(c *fiber.Ctx) error {
+		accept := c.Get(fiber.HeaderAccept)
+		if c.Query("format") != "prometheus" && !strings.Contains(accept, "text/plain") && !strings.Contains(accept, "openmetrics") {
+			return dashboard(c)
+		}
+		var buf bytes.Buffer
+		if err := metrics.WriteAll(&buf); err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return c.Send(buf.Bytes())
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"app/code"
+	"app/log"
+	"app/model"
+	"app/repo"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// permissions 角色权限查询
+var permissions = repo.NewPermissionRepo()
+
+// RequirePermission 校验访问令牌中的角色是否拥有全部 perms 权限，需放在 JwtAuth 或 Authenticate 之后
+// 角色来自令牌，角色的权限每次请求从数据库查询，修改角色权限后立即生效，修改用户角色后需重新登录或刷新令牌
+// e.g., api.Delete("/user/:id", middleware.JwtAuth(), middleware.RequirePermission("user:delete"), o.Delete)
+func RequirePermission(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := CurrentUser(c)
+		if principal == nil {
+			return code.AuthFailed
+		}
+		ok, err := HasPermission(c, perms...)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.F(c).Warnf("permission denied, user: %d, roles: %v, require: %v", principal.UserId, principal.Roles, perms)
+			return code.PermissionDenied
+		}
+		return c.Next()
+	}
+}
+
+// HasPermission 当前用户是否拥有全部 perms 权限，未认证时返回 false；API 密钥还需授权范围包含 perms
+func HasPermission(c *fiber.Ctx, perms ...string) (bool, error) {
+	principal := CurrentUser(c)
+	if principal == nil {
+		return false, nil
+	}
+	granted, err := permissions.SelectNamesByRoles(c, principal.Roles)
+	if err != nil {
+		return false, code.DatabaseError.Wrap(err)
+	}
+	all := slices.Contains(granted, model.PermissionAll)
+	for _, perm := range perms {
+		if !principal.Allows(perm) || !all && !slices.Contains(granted, perm) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
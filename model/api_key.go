@@ -0,0 +1,37 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// ApiKey API 密钥表，供机器客户端调用接口，只保存密钥的摘要
+type ApiKey struct {
+	Id         *int       `json:"id" db:"id,pk"`
+	Name       *string    `json:"name" db:"name"`               // 名称
+	KeyPrefix  *string    `json:"keyPrefix" db:"key_prefix"`    // 密钥前缀，用于识别密钥
+	KeyHash    *string    `json:"-" db:"key_hash"`              // 密钥的 SHA-256 摘要
+	UserId     *int       `json:"userId" db:"user_id"`          // 所属用户编号，密钥以该用户的身份访问
+	Scopes     *string    `json:"scopes" db:"scopes"`           // 授权范围，空格分隔的权限名称
+	ExpiresAt  *time.Time `json:"expiresAt" db:"expires_at"`    // 过期时间，为空时不过期
+	LastUsedAt *time.Time `json:"lastUsedAt" db:"last_used_at"` // 最后使用时间
+	RevokedAt  *time.Time `json:"revokedAt" db:"revoked_at"`    // 吊销时间
+	CreatedAt  *time.Time `json:"createdAt" db:"created_at"`
+}
+
+func (*ApiKey) TableName() string {
+	return "api_key"
+}
+
+// ScopeList 授权范围列表
+func (o *ApiKey) ScopeList() []string {
+	if o.Scopes == nil {
+		return []string{}
+	}
+	return strings.Fields(*o.Scopes)
+}
+
+// Active 密钥未被吊销且未过期
+func (o *ApiKey) Active(now time.Time) bool {
+	return o.RevokedAt == nil && (o.ExpiresAt == nil || o.ExpiresAt.After(now))
+}
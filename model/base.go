@@ -24,3 +24,26 @@ func (o *Pagination) Format() {
 	}
 	o.Offset = (o.Page - 1) * o.Size
 }
+
+const (
+	DefaultCursorLimit = 20  // 游标分页默认大小
+	MaxCursorLimit     = 100 // 游标分页最大大小
+)
+
+// CursorPagination 游标(键集)分页，按排序键定位下一页，不统计总数，数据变动时也不会跳过或重复记录
+type CursorPagination struct {
+	Cursor string `json:"-" query:"cursor"`    // 请求的游标，为空时从第一页开始
+	Limit  int    `json:"limit" query:"limit"` // 分页大小
+	Data   any    `json:"data"`                // 数据
+	Next   string `json:"next"`                // 下一页游标，为空表示没有更多数据
+	Prev   string `json:"prev"`                // 上一页游标，为空表示已是第一页
+}
+
+func (o *CursorPagination) Format() {
+	if o.Limit <= 0 {
+		o.Limit = DefaultCursorLimit
+	}
+	if o.Limit > MaxCursorLimit {
+		o.Limit = MaxCursorLimit
+	}
+}
@@ -0,0 +1,9 @@
+package input
+
+import "time"
+
+type ApiKeyInput struct {
+	Name      *string    `json:"name" validate:"required,min=1,max=64"`              // 名称
+	Scopes    []string   `json:"scopes" validate:"required,min=1,max=32,dive,min=1"` // 授权范围，权限名称列表，* 表示所属用户的全部权限
+	ExpiresAt *time.Time `json:"expiresAt"`                                          // 过期时间，为空时不过期
+}
@@ -0,0 +1,10 @@
+package input
+
+type MfaCode struct {
+	Code string `json:"code" validate:"required,min=6,max=32"` // 认证器中的 6 位验证码，关闭两步验证时也可使用恢复码
+}
+
+type MfaLogin struct {
+	MfaToken string `json:"mfaToken" validate:"required"`          // 登录接口返回的两步验证登录令牌
+	Code     string `json:"code" validate:"required,min=6,max=32"` // 认证器中的 6 位验证码或一个未使用的恢复码
+}
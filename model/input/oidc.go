@@ -0,0 +1,12 @@
+package input
+
+type OidcProvider struct {
+	Provider string `params:"provider" validate:"required,max=64"` // 身份提供方名称
+}
+
+type OidcCallback struct {
+	Code             string `query:"code" validate:"required_without=Error"` // 授权码
+	State            string `query:"state" validate:"required"`              // 发起授权时生成的 state
+	Error            string `query:"error"`                                  // 身份提供方返回的错误
+	ErrorDescription string `query:"error_description"`                      // 错误描述
+}
@@ -0,0 +1,15 @@
+package input
+
+type PasswordChange struct {
+	OldPassword string `json:"oldPassword" validate:"required"` // 原密码
+	NewPassword string `json:"newPassword" validate:"required"` // 新密码，强度要求见 password 配置
+}
+
+type PasswordForgot struct {
+	Username string `json:"username" validate:"required,max=32"` // 用户账户
+}
+
+type PasswordReset struct {
+	Token       string `json:"token" validate:"required"`       // 通知中收到的重置令牌
+	NewPassword string `json:"newPassword" validate:"required"` // 新密码，强度要求见 password 配置
+}
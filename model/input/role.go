@@ -0,0 +1,15 @@
+package input
+
+type IdParam struct {
+	Id int `params:"id" validate:"gt=0"` // 编号
+}
+
+type RoleInput struct {
+	Name          *string `json:"name" validate:"required,min=1,max=64"`        // 角色名称
+	Description   *string `json:"description" validate:"omitempty,max=255"`     // 描述
+	PermissionIds []int   `json:"permissionIds" validate:"omitempty,dive,gt=0"` // 权限编号列表，为空时角色没有任何权限
+}
+
+type UserRoles struct {
+	RoleIds []int `json:"roleIds" validate:"omitempty,dive,gt=0"` // 角色编号列表，替换用户现有的角色
+}
@@ -1,15 +1,39 @@
 package input
 
+import "time"
+
 type UserLogin struct {
-	Username *string `json:"username" db:"username"`
-	Password *string `json:"password" db:"password"`
+	Username *string `json:"username" db:"username" validate:"required,min=1"`
+	Password *string `json:"password" db:"password" validate:"required,min=1"`
 }
 
 type UserRegister struct {
-	Username *string `json:"username" db:"username"`
-	Password *string `json:"password" db:"password"`
+	Username *string `json:"username" db:"username" validate:"required,min=3,max=32"` // 用户账户，3-32 个字符
+	Password *string `json:"password" db:"password" validate:"required"`              // 用户密码，强度要求见 password 配置
+}
+
+type UserCreate struct {
+	Username *string `json:"username" validate:"required,min=3,max=32"` // 用户账户，3-32 个字符
+	Password *string `json:"password" validate:"required"`              // 用户密码，强度要求见 password 配置
+}
+
+type UserUpdate struct {
+	Id       *int    `json:"id" validate:"required,gt=0"`                // 编号
+	Username *string `json:"username" validate:"omitempty,min=3,max=32"` // 用户账户，3-32 个字符
+	Password *string `json:"password"`                                   // 密码只能通过修改密码或重置密码接口修改，传入时返回错误
 }
 
 type UserFilter struct {
-	Username *string `json:"username" db:"username"`
+	Username    *string    `json:"username" query:"username" db:"username" filter:"like" validate:"omitempty,max=32"` // 用户账户，模糊匹配
+	Ids         []int      `json:"ids" query:"ids" db:"id" filter:"in" validate:"omitempty,max=100,dive,gt=0"`        // 编号列表
+	CreatedFrom *time.Time `json:"createdFrom" query:"createdFrom" db:"created_at" filter:"gte"`                      // 创建时间起
+	CreatedTo   *time.Time `json:"createdTo" query:"createdTo" db:"created_at" filter:"lte"`                          // 创建时间止
+}
+
+type TokenRefresh struct {
+	RefreshToken *string `json:"refreshToken" validate:"required,min=1"` // 刷新令牌
+}
+
+type Logout struct {
+	RefreshToken *string `json:"refreshToken"` // 刷新令牌，传入时同时吊销其所在的令牌族
 }
@@ -0,0 +1,21 @@
+package output
+
+import "time"
+
+type ApiKeyOutput struct {
+	Id         *int       `json:"id" db:"id"`
+	Name       *string    `json:"name" db:"name"`
+	KeyPrefix  *string    `json:"keyPrefix" db:"key_prefix"` // 密钥前缀，用于识别密钥
+	UserId     *int       `json:"userId" db:"user_id"`
+	Scopes     []string   `json:"scopes"` // 授权范围
+	ExpiresAt  *time.Time `json:"expiresAt" db:"expires_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revokedAt" db:"revoked_at"`
+	CreatedAt  *time.Time `json:"createdAt" db:"created_at"`
+}
+
+// ApiKeyCreated 新建 API 密钥的返回值，密钥明文只在创建时返回一次
+type ApiKeyCreated struct {
+	ApiKeyOutput
+	Key string `json:"key"` // 密钥明文，通过 X-API-Key 请求头使用
+}
@@ -0,0 +1,21 @@
+package output
+
+import "time"
+
+// MfaStatus 两步验证状态
+type MfaStatus struct {
+	Enabled                bool       `json:"enabled"`                // 是否已启用
+	EnabledAt              *time.Time `json:"enabledAt"`              // 启用时间
+	RecoveryCodesRemaining int        `json:"recoveryCodesRemaining"` // 剩余可用的恢复码数量
+}
+
+// TotpEnrollment 开始绑定认证器的返回值，使用验证码确认后两步验证才会启用
+type TotpEnrollment struct {
+	Secret     string `json:"secret"`     // Base32 编码的 TOTP 密钥，无法扫码时手动输入
+	OtpauthUri string `json:"otpauthUri"` // otpauth:// 地址，生成二维码供认证器扫描
+}
+
+// RecoveryCodes 恢复码明文，只在生成时返回一次，每个恢复码只能使用一次
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
@@ -0,0 +1,6 @@
+package output
+
+// OidcAuthUrl 身份提供方的授权地址
+type OidcAuthUrl struct {
+	AuthUrl string `json:"authUrl"` // 浏览器跳转至该地址完成授权，授权后回调 oidc.providers 中的 redirectUrl
+}
@@ -0,0 +1,18 @@
+package output
+
+import "time"
+
+type RoleOutput struct {
+	Id          *int       `json:"id" db:"id"`
+	Name        *string    `json:"name" db:"name"`
+	Description *string    `json:"description" db:"description"`
+	Permissions []string   `json:"permissions"` // 权限名称列表
+	CreatedAt   *time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt   *time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type PermissionOutput struct {
+	Id          *int    `json:"id" db:"id"`
+	Name        *string `json:"name" db:"name"`
+	Description *string `json:"description" db:"description"`
+}
@@ -0,0 +1,10 @@
+package output
+
+// TokenOutput 登录与刷新令牌的返回值
+type TokenOutput struct {
+	AccessToken  string `json:"accessToken"`        // 访问令牌
+	RefreshToken string `json:"refreshToken"`       // 刷新令牌，只能使用一次
+	TokenType    string `json:"tokenType"`          // 令牌类型，固定为 Bearer
+	ExpiresIn    int    `json:"expiresIn"`          // 访问令牌有效期（秒）
+	MfaToken     string `json:"mfaToken,omitempty"` // 已启用两步验证时只返回该令牌，通过 /login/mfa 完成登录
+}
@@ -3,9 +3,17 @@ package output
 import "time"
 
 type UserOutput struct {
-	Id        *int       `json:"id" db:"id" uri:"id"`
-	Username  *string    `json:"username" db:"username"`
-	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
-	DeletedAt *time.Time `json:"deletedAt" db:"deleted_at"`
+	Id            *int       `json:"id" db:"id" uri:"id"`
+	Username      *string    `json:"username" db:"username"`
+	CreatedAt     *time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt     *time.Time `json:"updatedAt" db:"updated_at"`
+	DeletedAt     *time.Time `json:"deletedAt" db:"deleted_at"`
+	LockedUntil   *time.Time `json:"lockedUntil" db:"locked_until"`
+	TotpEnabledAt *time.Time `json:"totpEnabledAt" db:"totp_enabled_at"` // 启用两步验证的时间，为空时未启用
+}
+
+// MeOutput 当前登录用户
+type MeOutput struct {
+	UserOutput
+	Roles []string `json:"roles"` // 访问令牌中的角色
 }
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// PasswordResetToken 密码重置令牌表，只保存令牌的摘要，每个令牌只能使用一次
+type PasswordResetToken struct {
+	Id        *int       `json:"id" db:"id,pk"`
+	TokenHash *string    `json:"-" db:"token_hash"`         // 重置令牌的 SHA-256 摘要
+	UserId    *int       `json:"userId" db:"user_id"`       // 用户编号
+	ExpiresAt *time.Time `json:"expiresAt" db:"expires_at"` // 过期时间
+	UsedAt    *time.Time `json:"usedAt" db:"used_at"`       // 使用时间，使用后失效
+	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
+}
+
+func (*PasswordResetToken) TableName() string {
+	return "password_reset_token"
+}
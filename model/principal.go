@@ -0,0 +1,50 @@
+package model
+
+import (
+	"context"
+	"slices"
+)
+
+// Principal 已认证的当前用户，由 JwtAuth 从访问令牌解析，或由 Authenticate 从 API 密钥解析
+type Principal struct {
+	UserId   int      `json:"userId"`             // 用户编号
+	Username string   `json:"username"`           // 用户账户
+	Roles    []string `json:"roles"`              // 角色名称
+	ApiKeyId int      `json:"apiKeyId,omitempty"` // 通过 API 密钥认证时为密钥编号
+	Scopes   []string `json:"scopes,omitempty"`   // API 密钥的授权范围
+}
+
+// HasRole 是否拥有角色
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+// IsApiKey 是否通过 API 密钥认证
+func (p *Principal) IsApiKey() bool {
+	return p != nil && p.ApiKeyId > 0
+}
+
+// Allows 授权范围是否包含 perm，访问令牌不受授权范围限制
+// API 密钥的最终权限为所属用户权限与授权范围的交集
+func (p *Principal) Allows(perm string) bool {
+	if !p.IsApiKey() {
+		return true
+	}
+	return slices.Contains(p.Scopes, PermissionAll) || slices.Contains(p.Scopes, perm)
+}
+
+type principalKey struct{}
+
+// WithPrincipal 将当前用户写入 ctx
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom 读取 ctx 中的当前用户，未认证时返回 nil
+func PrincipalFrom(ctx context.Context) *Principal {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// RecoveryCode 两步验证恢复码，只保存摘要，每个恢复码只能使用一次
+type RecoveryCode struct {
+	Id        *int       `json:"id" db:"id,pk"`
+	UserId    *int       `json:"userId" db:"user_id"` // 用户编号
+	CodeHash  *string    `json:"-" db:"code_hash"`    // 恢复码的 SHA-256 摘要
+	UsedAt    *time.Time `json:"usedAt" db:"used_at"` // 使用时间，使用后失效
+	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
+}
+
+func (*RecoveryCode) TableName() string {
+	return "user_recovery_code"
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+const (
+	// RoleAdmin 管理员角色，迁移时创建并授予 * 权限
+	RoleAdmin = "admin"
+	// PermissionAll 拥有该权限的角色通过所有权限校验
+	PermissionAll = "*"
+)
+
+// Role 角色表
+type Role struct {
+	Id          *int       `json:"id" db:"id,pk" uri:"id"`       // 编号
+	Name        *string    `json:"name" db:"name"`               // 角色名称
+	Description *string    `json:"description" db:"description"` // 描述
+	CreatedAt   *time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt   *time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+func (*Role) TableName() string {
+	return "role"
+}
+
+// Permission 权限表，权限名称形如 资源:操作，如 user:delete
+type Permission struct {
+	Id          *int       `json:"id" db:"id,pk"`                // 编号
+	Name        *string    `json:"name" db:"name"`               // 权限名称
+	Description *string    `json:"description" db:"description"` // 描述
+	CreatedAt   *time.Time `json:"createdAt" db:"created_at"`
+}
+
+func (*Permission) TableName() string {
+	return "permission"
+}
+
+// RolePermission 角色权限关联表
+type RolePermission struct {
+	RoleId       *int `json:"roleId" db:"role_id"`
+	PermissionId *int `json:"permissionId" db:"permission_id"`
+}
+
+func (*RolePermission) TableName() string {
+	return "role_permission"
+}
+
+// UserRole 用户角色关联表
+type UserRole struct {
+	UserId *int `json:"userId" db:"user_id"`
+	RoleId *int `json:"roleId" db:"role_id"`
+}
+
+func (*UserRole) TableName() string {
+	return "user_role"
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// RefreshToken 刷新令牌表，只保存令牌的摘要
+type RefreshToken struct {
+	Id        *int       `json:"id" db:"id,pk"`
+	TokenHash *string    `json:"-" db:"token_hash"`         // 刷新令牌的 SHA-256 摘要
+	Family    *string    `json:"family" db:"family"`        // 令牌族，同一次登录轮换出的令牌属于同一族
+	UserId    *int       `json:"userId" db:"user_id"`       // 用户编号
+	ExpiresAt *time.Time `json:"expiresAt" db:"expires_at"` // 过期时间
+	RevokedAt *time.Time `json:"revokedAt" db:"revoked_at"` // 吊销时间，已轮换或已注销
+	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
+}
+
+func (*RefreshToken) TableName() string {
+	return "refresh_token"
+}
+
+// TokenBlacklist 访问令牌黑名单
+type TokenBlacklist struct {
+	Id        *int       `json:"id" db:"id,pk"`
+	Jti       *string    `json:"jti" db:"jti"`              // 访问令牌编号
+	ExpiresAt *time.Time `json:"expiresAt" db:"expires_at"` // 访问令牌过期时间，过期后可清理
+	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
+}
+
+func (*TokenBlacklist) TableName() string {
+	return "token_blacklist"
+}
+
+// TokenBlacklistCacheKey 黑名单在 Redis 中的键
+func TokenBlacklistCacheKey(jti string) string {
+	return "token_blacklist:" + jti
+}
@@ -8,12 +8,21 @@ import (
 
 // User  用户表
 type User struct {
-	Id        *int       `json:"id" db:"id,pk" uri:"id"` // 编号
-	Username  *string    `json:"username" db:"username"` // 用户账户
-	Password  *string    `json:"password" db:"password"` // 用户密码
-	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
-	DeletedAt *time.Time `json:"deletedAt" db:"deleted_at"`
+	Id            *int       `json:"id" db:"id,pk" uri:"id"` // 编号
+	Username      *string    `json:"username" db:"username"` // 用户账户
+	Password      *string    `json:"-" db:"password"`        // 用户密码摘要，不写入缓存，校验时通过 SelectCredentials 查询
+	CreatedAt     *time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt     *time.Time `json:"updatedAt" db:"updated_at"`
+	DeletedAt     *time.Time `json:"deletedAt" db:"deleted_at"`
+	LockedUntil   *time.Time `json:"lockedUntil" db:"locked_until"`      // 登录失败次数过多时锁定至该时间
+	TotpSecret    *string    `json:"-" db:"totp_secret"`                 // 加密后的 TOTP 密钥，确认前为待启用状态
+	TotpEnabledAt *time.Time `json:"totpEnabledAt" db:"totp_enabled_at"` // 启用两步验证的时间，为空时未启用
+	TotpLastStep  *int64     `json:"-" db:"totp_last_step"`              // 最后一次通过验证的 TOTP 时间步，防止验证码重放
+}
+
+// MfaEnabled 是否已启用两步验证
+func (o *User) MfaEnabled() bool {
+	return o.TotpEnabledAt != nil
 }
 
 func (*User) TableName() string {
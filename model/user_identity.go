@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// UserIdentity 外部身份提供方 (OpenID Connect) 账户与用户的关联
+type UserIdentity struct {
+	Id        *int       `json:"id" db:"id,pk"`
+	UserId    *int       `json:"userId" db:"user_id"`    // 用户编号
+	Provider  *string    `json:"provider" db:"provider"` // 身份提供方名称，对应 oidc.providers 中的 name
+	Subject   *string    `json:"subject" db:"subject"`   // 身份提供方的用户标识 (ID Token 的 sub)
+	Email     *string    `json:"email" db:"email"`       // 身份提供方返回的邮箱
+	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
+}
+
+func (*UserIdentity) TableName() string {
+	return "user_identity"
+}
+
+// OidcState 发起授权请求时保存的状态，回调时按 state 取出并校验
+type OidcState struct {
+	Provider   string `json:"provider"`   // 身份提供方名称
+	Nonce      string `json:"nonce"`      // 写入 ID Token 的随机数，防止重放
+	Verifier   string `json:"verifier"`   // PKCE code_verifier
+	LinkUserId int    `json:"linkUserId"` // 关联到已登录用户时为该用户编号，登录时为 0
+	Binding    string `json:"binding"`    // 发起授权的浏览器 Cookie 的摘要，回调时校验，防止登录 CSRF
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_FileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify", "messages.log")
+	notifier := NewFileNotifier(path)
+	for _, subject := range []string{"first", "second"} {
+		if err := notifier.Notify(context.Background(), &Message{UserId: 1, Username: "alice", Subject: subject, Data: map[string]string{"token": "t"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var subjects []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		subjects = append(subjects, msg.Subject)
+	}
+	if len(subjects) != 2 || subjects[0] != "first" || subjects[1] != "second" {
+		t.Fatalf("messages should be appended as json lines, got %v", subjects)
+	}
+}
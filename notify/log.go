@@ -0,0 +1,18 @@
+package notify
+
+import (
+	"app/log"
+	"context"
+)
+
+// LogNotifier 将通知写入日志，仅用于本地开发
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (o *LogNotifier) Notify(ctx context.Context, msg *Message) error {
+	log.T(ctx).Infow("notify "+msg.Subject, "userId", msg.UserId, "username", msg.Username, "body", msg.Body, "data", msg.Data)
+	return nil
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"app/conf"
+	"context"
+	"path/filepath"
+)
+
+// Message 发送给用户的通知
+type Message struct {
+	UserId   int               `json:"userId"`         // 接收通知的用户编号
+	Username string            `json:"username"`       // 接收通知的用户账户
+	Subject  string            `json:"subject"`        // 标题
+	Body     string            `json:"body"`           // 正文
+	Data     map[string]string `json:"data,omitempty"` // 附加数据，如密码重置令牌
+}
+
+// Notifier 通知发送方式，接入邮件、短信等渠道时实现该接口并在 New 中注册
+type Notifier interface {
+	Notify(context.Context, *Message) error
+}
+
+// New 按 conf.Notifier 创建通知发送方式，未配置时写入日志
+func New() Notifier {
+	switch conf.Notifier.Type {
+	case "file":
+		path := conf.Notifier.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(conf.RootPath, path)
+		}
+		return NewFileNotifier(path)
+	default:
+		return NewLogNotifier()
+	}
+}
@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"app/model"
+	"app/util/dbutil"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type apiKeyRepo struct {
+	*CrudRepo[model.ApiKey]
+}
+
+func NewApiKeyRepo() ApiKeyRepo {
+	return &apiKeyRepo{
+		CrudRepo: NewCrudRepo[model.ApiKey](),
+	}
+}
+
+func (o *apiKeyRepo) SelectByHash(c *fiber.Ctx, keyHash string) (*model.ApiKey, error) {
+	return o.SelectOneBy(c, "key_hash", keyHash)
+}
+
+// SelectByUserId 查询用户的所有密钥，包括已吊销和已过期的
+func (o *apiKeyRepo) SelectByUserId(c *fiber.Ctx, userId int) ([]model.ApiKey, error) {
+	return o.SelectWhere(c, dbutil.Eq("user_id", userId))
+}
+
+// Revoke 吊销未被吊销的密钥，返回 false 表示密钥已被吊销
+func (o *apiKeyRepo) Revoke(c *fiber.Ctx, id int) (bool, error) {
+	n, err := o.UpdateWhere(c, map[string]any{"revoked_at": time.Now()},
+		dbutil.Eq("id", id), dbutil.IsNull("revoked_at"))
+	return n > 0, err
+}
+
+// Touch 更新密钥的最后使用时间
+func (o *apiKeyRepo) Touch(c *fiber.Ctx, id int, usedAt time.Time) error {
+	_, err := o.UpdateWhere(c, map[string]any{"last_used_at": usedAt}, dbutil.Eq("id", id))
+	return err
+}
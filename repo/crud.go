@@ -0,0 +1,591 @@
+package repo
+
+import (
+	"app/cache"
+	"app/code"
+	"app/db"
+	"app/log"
+	"app/model"
+	"app/util/dbutil"
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Model 可由 CrudRepo 管理的模型，需带有 db 标签并提供表名
+type Model interface {
+	TableName() string
+}
+
+// CrudRepo 基于 dbutil.Builder 的通用增删改查仓库
+// 用法: NewCrudRepo[model.User]()
+// 模型包含 deleted_at 列时 Delete 为软删除，查询默认排除已删除记录
+// SelectById 经过 cache.Loader 缓存，所有写入方法在事务提交后失效受影响记录的缓存
+type CrudRepo[T any] struct {
+	table   string
+	trashed dbutil.TrashedScope
+	opts    *dbutil.ListOptions
+	primary bool
+	byId    *cache.Loader[int, T]
+}
+
+func NewCrudRepo[T any, PT interface {
+	*T
+	Model
+}]() *CrudRepo[T] {
+	r := &CrudRepo[T]{
+		table: PT(new(T)).TableName(),
+	}
+	r.byId = cache.NewLoader(r.table+":id", r.selectById, cache.LoaderOptions{})
+	return r
+}
+
+// TableName 返回仓库对应的表名
+func (o *CrudRepo[T]) TableName() string {
+	return o.table
+}
+
+// WithTrashed 返回一个查询时包含已软删除记录的仓库副本
+// e.g., userRepo.WithTrashed().SelectById(c, id)
+func (o *CrudRepo[T]) WithTrashed() *CrudRepo[T] {
+	r := *o
+	r.trashed = dbutil.TrashedInclude
+	return &r
+}
+
+// OnlyTrashed 返回一个查询时只返回已软删除记录的仓库副本
+func (o *CrudRepo[T]) OnlyTrashed() *CrudRepo[T] {
+	r := *o
+	r.trashed = dbutil.TrashedOnly
+	return &r
+}
+
+// OnPrimary 返回一个查询使用主库的仓库副本，用于不能读到延迟副本中旧数据的查询 (如令牌与凭据校验)
+// e.g., refreshTokenRepo.OnPrimary().SelectOneBy(c, "token_hash", hash)
+func (o *CrudRepo[T]) OnPrimary() *CrudRepo[T] {
+	r := *o
+	r.primary = true
+	return &r
+}
+
+// WithListOptions 返回一个列表查询时应用排序与字段选择的仓库副本
+// e.g., userRepo.WithListOptions(opts).SelectWhere(c, conds...)
+func (o *CrudRepo[T]) WithListOptions(opts *dbutil.ListOptions) *CrudRepo[T] {
+	r := *o
+	r.opts = opts
+	return &r
+}
+
+// builder 创建应用了软删除查询范围的构建器
+func (o *CrudRepo[T]) builder(t any) *dbutil.Builder {
+	return dbutil.NewBuilder(t).WithTrashedScope(o.trashed)
+}
+
+// listBuilder 创建列表查询的构建器：默认排序，并应用 WithListOptions 指定的排序与字段选择
+func (o *CrudRepo[T]) listBuilder(t any) *dbutil.Builder {
+	b := o.builder(t)
+	return b.WithOrderBy(defaultOrderBy(b)).WithListOptions(o.opts)
+}
+
+// CacheKey 生成缓存键，与 model.User.CacheKey 保持一致: <table>:id:<id>
+func (o *CrudRepo[T]) CacheKey(id int) string {
+	return fmt.Sprintf("%s:id:%d", o.table, id)
+}
+
+func (o *CrudRepo[T]) Insert(c *fiber.Ctx, t *T) error {
+	b := dbutil.NewBuilder(t)
+	setTimeColumn(b, "created_at", time.Now())
+	pkName, pk, hasPK := b.PrimaryKey()
+	var id int64
+	if dbutil.CurrentDialect().InsertReturning() != dbutil.ReturningNone && hasPK {
+		// PostgreSQL 与 SQL Server 不支持 LastInsertId，通过 RETURNING 或 OUTPUT INSERTED 取回主键
+		sql := b.OnlyNonZero().WithReturning(pkName).BuildInsertQuery(o.table)
+		query, args, err := o.conn(c).BindNamed(sql, t)
+		if err == nil {
+			err = o.conn(c).Get(&id, query, args...)
+		}
+		if err != nil {
+			log.F(c).Error(err)
+			return writeError(err)
+		}
+	} else {
+		sql := b.OnlyNonZero().BuildInsertQuery(o.table)
+		result, err := o.conn(c).NamedExec(sql, t)
+		if err != nil {
+			log.F(c).Error(err)
+			return writeError(err)
+		}
+		if !hasPK {
+			// 无主键的表 (如关联表) 不回填主键也不写缓存
+			return nil
+		}
+		id, err = result.LastInsertId()
+		if err != nil {
+			log.F(c).Error(err)
+			return err
+		}
+	}
+	setInt(pk, int(id))
+	// 清除该主键可能存在的空值缓存，记录在首次查询时写入缓存
+	o.invalidate(c, int(id))
+	return nil
+}
+
+// Upsert 插入记录，conflict 唯一约束列冲突时更新其余非零值列，具体语法由方言决定；不回填主键
+// e.g., tokenBlacklistRepo.Upsert(c, &model.TokenBlacklist{Jti: &jti, ExpiresAt: &expiresAt}, "jti")
+func (o *CrudRepo[T]) Upsert(c *fiber.Ctx, t *T, conflict ...string) error {
+	b := dbutil.NewBuilder(t)
+	setTimeColumn(b, "created_at", time.Now())
+	sql := b.OnlyNonZero().BuildUpsertQuery(o.table, conflict...)
+	if _, err := o.conn(c).NamedExec(sql, t); err != nil {
+		log.F(c).Error(err)
+		return writeError(err)
+	}
+	var conds []dbutil.Cond
+	for _, column := range conflict {
+		if v, ok := b.Column(column); ok {
+			conds = append(conds, dbutil.Eq(column, v.Interface()))
+		}
+	}
+	ids, err := o.idsWhere(c, conds...)
+	if err != nil {
+		return err
+	}
+	o.invalidate(c, ids...)
+	return nil
+}
+
+// Delete 删除记录，模型包含 deleted_at 列时为软删除
+func (o *CrudRepo[T]) Delete(c *fiber.Ctx, id int) error {
+	b := dbutil.NewBuilder(new(T))
+	if !b.IsSoftDelete() {
+		return o.ForceDelete(c, id)
+	}
+	sql := b.WithCustomWhere(pkColumn(b) + " = ?").BuildSoftDeleteQuery(o.table)
+	_, err := o.conn(c).Exec(sql, time.Now(), id)
+	if err != nil {
+		log.F(c).Error(err)
+		return err
+	}
+	o.invalidate(c, id)
+	return nil
+}
+
+// ForceDelete 物理删除记录，忽略软删除
+func (o *CrudRepo[T]) ForceDelete(c *fiber.Ctx, id int) error {
+	b := dbutil.NewBuilder(new(T))
+	sql := b.WithCustomWhere(pkColumn(b) + " = ?").BuildDeleteQuery(o.table)
+	_, err := o.conn(c).Exec(sql, id)
+	if err != nil {
+		log.F(c).Error(err)
+		return err
+	}
+	o.invalidate(c, id)
+	return nil
+}
+
+// Restore 恢复已软删除的记录
+func (o *CrudRepo[T]) Restore(c *fiber.Ctx, id int) error {
+	b := dbutil.NewBuilder(new(T))
+	if !b.IsSoftDelete() {
+		return nil
+	}
+	sql := b.WithCustomWhere(pkColumn(b) + " = ?").BuildRestoreQuery(o.table)
+	_, err := o.conn(c).Exec(sql, id)
+	if err != nil {
+		log.F(c).Error(err)
+		return err
+	}
+	o.invalidate(c, id)
+	return nil
+}
+
+// invalidate 在事务提交后删除记录缓存，请求取消时仍然删除
+func (o *CrudRepo[T]) invalidate(c *fiber.Ctx, ids ...int) {
+	if len(ids) == 0 {
+		return
+	}
+	ctx := context.WithoutCancel(ctxOf(c))
+	db.AfterCommit(ctx, func() {
+		o.byId.Invalidate(ctx, ids...)
+	})
+}
+
+// idsWhere 查询满足条件的记录主键 (包括已软删除的)，用于按条件写入后失效缓存；未启用缓存或没有主键时返回空
+func (o *CrudRepo[T]) idsWhere(c *fiber.Ctx, conds ...dbutil.Cond) ([]int, error) {
+	if cache.Default() == nil {
+		return nil, nil
+	}
+	b := dbutil.NewBuilder(new(T))
+	pkName, _, ok := b.PrimaryKey()
+	if !ok {
+		return nil, nil
+	}
+	sql := b.WithTrashed().
+		OnlyNonZero().
+		WithFields(pkName).
+		Where(conds...).
+		BuildSelectQuery(o.table)
+	var ids []int
+	// 从主库读取，延迟的副本可能缺少刚写入的记录，导致其缓存未被失效
+	err := db.Conn(db.WithPrimary(ctxOf(c))).Select(&ids, sql, b.Args()...)
+	if err != nil {
+		log.F(c).Error(err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (o *CrudRepo[T]) Update(c *fiber.Ctx, t *T) error {
+	b := dbutil.NewBuilder(t)
+	setTimeColumn(b, "updated_at", time.Now())
+	pkName := pkColumn(b)
+	sql := b.ExcludePK().
+		OnlyNonZero().
+		WithCustomWhere(fmt.Sprintf("%s = :%s", pkName, pkName)).
+		BuildUpdateQuery(o.table)
+	_, err := o.conn(c).NamedExec(sql, t)
+	if err != nil {
+		log.F(c).Error(err)
+		return writeError(err)
+	}
+	if _, pk, ok := b.PrimaryKey(); ok {
+		if id, ok := getInt(pk); ok {
+			o.invalidate(c, id)
+		}
+	}
+	return nil
+}
+
+// UpdateWhere 按条件更新指定列，返回受影响的行数；更新前查询受影响的主键，提交后失效其缓存
+// e.g., UpdateWhere(c, map[string]any{"revoked_at": now}, dbutil.Eq("family", family))
+func (o *CrudRepo[T]) UpdateWhere(c *fiber.Ctx, values map[string]any, conds ...dbutil.Cond) (int64, error) {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+	args := make([]any, 0, len(values))
+	for _, column := range columns {
+		args = append(args, values[column])
+	}
+	ids, err := o.idsWhere(c, conds...)
+	if err != nil {
+		return 0, err
+	}
+	b := dbutil.NewBuilder(nil).Where(conds...)
+	sql := b.BuildUpdateColumnsQuery(o.table, columns...)
+	result, err := o.conn(c).Exec(sql, append(args, b.Args()...)...)
+	if err != nil {
+		log.F(c).Error(err)
+		return 0, writeError(err)
+	}
+	o.invalidate(c, ids...)
+	return result.RowsAffected()
+}
+
+// DeleteWhere 按条件物理删除记录，返回受影响的行数；conds 为空时不执行删除
+func (o *CrudRepo[T]) DeleteWhere(c *fiber.Ctx, conds ...dbutil.Cond) (int64, error) {
+	if len(conds) == 0 {
+		return 0, nil
+	}
+	ids, err := o.idsWhere(c, conds...)
+	if err != nil {
+		return 0, err
+	}
+	b := dbutil.NewBuilder(nil).Where(conds...)
+	result, err := o.conn(c).Exec(b.BuildDeleteQuery(o.table), b.Args()...)
+	if err != nil {
+		log.F(c).Error(err)
+		return 0, err
+	}
+	o.invalidate(c, ids...)
+	return result.RowsAffected()
+}
+
+func (o *CrudRepo[T]) Select(c *fiber.Ctx, filter *T) ([]T, error) {
+	sql := o.listBuilder(filter).
+		OnlyNonZero().
+		BuildSelectQuery(o.table)
+	var list []T
+	query, args, err := o.conn(c).BindNamed(sql, filter)
+	if err == nil {
+		err = o.conn(c).Select(&list, query, args...)
+	}
+	if err != nil {
+		log.F(c).Error(err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// SelectWhere 按类型化条件查询，条件之间使用 AND 连接
+// e.g., SelectWhere(c, dbutil.Contains("username", "ad"), dbutil.In("id", ids))
+func (o *CrudRepo[T]) SelectWhere(c *fiber.Ctx, conds ...dbutil.Cond) ([]T, error) {
+	b := o.listBuilder(new(T))
+	sql := b.OnlyNonZero().
+		Where(conds...).
+		BuildSelectQuery(o.table)
+	var list []T
+	err := o.conn(c).Select(&list, sql, b.Args()...)
+	if err != nil {
+		log.F(c).Error(err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// SelectById 按主键查询，记录不存在时返回 sql.ErrNoRows
+func (o *CrudRepo[T]) SelectById(c *fiber.Ctx, id int) (*T, error) {
+	var t *T
+	var err error
+	// 缓存中只有未删除的记录，指定了软删除查询范围时直接查库
+	if o.trashed == dbutil.TrashedExclude {
+		t, err = o.byId.Get(o.ctx(c), id)
+	} else {
+		t, err = o.selectById(o.ctx(c), id)
+	}
+	if err != nil {
+		log.F(c).Error(err)
+		return nil, err
+	}
+	return t, nil
+}
+
+// selectById 按主键查库，作为 SelectById 的缓存加载函数
+func (o *CrudRepo[T]) selectById(ctx context.Context, id int) (*T, error) {
+	t := new(T)
+	b := o.builder(t)
+	sql := b.OnlyNonZero().
+		WithCustomWhere(pkColumn(b) + " = ?").
+		BuildSelectQuery(o.table)
+
+	if err := db.Conn(ctx).Get(t, sql, id); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SelectOneBy 按单列等值条件查询一条记录
+func (o *CrudRepo[T]) SelectOneBy(c *fiber.Ctx, column string, value any) (*T, error) {
+	t := new(T)
+	sql := o.builder(t).
+		OnlyNonZero().
+		WithCustomWhere(column + " = ?").
+		BuildSelectQuery(o.table)
+
+	err := o.conn(c).Get(t, sql, value)
+	if err != nil {
+		log.F(c).Error(err)
+		return nil, err
+	}
+	return t, nil
+}
+
+func (o *CrudRepo[T]) SelectWithPagination(c *fiber.Ctx, p *model.Pagination) error {
+	if p.Total == 0 {
+		total, err := o.SelectTotalCount(c)
+		if err != nil {
+			return err
+		} else if total == 0 {
+			p.Data = nil
+			return nil
+		}
+		p.Total = total
+	}
+	p.Format()
+	sql := o.listBuilder(new(T)).
+		OnlyNonZero().
+		WithLimitOffset(p.Size, p.Offset).
+		BuildSelectQuery(o.table)
+	var list []T
+	err := o.conn(c).Select(&list, sql)
+	if err != nil {
+		log.F(c).Error(err)
+		return err
+	}
+	p.Data = list
+	return nil
+}
+
+// SelectWithCursor 游标分页查询，按 (created_at, 主键) 倒序，conds 为额外的过滤条件
+// p.Cursor 为空时返回第一页，p.Next/p.Prev 为下一页/上一页的游标
+// 排序固定为游标的排序列，WithListOptions 只有字段选择生效
+func (o *CrudRepo[T]) SelectWithCursor(c *fiber.Ctx, p *model.CursorPagination, conds ...dbutil.Cond) error {
+	p.Format()
+	b := o.builder(new(T))
+	keys := seekColumns(b)
+	if o.opts != nil && len(o.opts.Fields) > 0 {
+		// 生成游标需要读取排序列
+		b.WithFields(o.opts.Fields...).WithFields(keys...)
+	}
+	var values []any
+	backward := false
+	if p.Cursor != "" {
+		cursor, err := dbutil.DecodeCursor(p.Cursor)
+		if err == nil {
+			values, err = cursor.Values(new(T), keys...)
+		}
+		if err != nil {
+			log.F(c).Error(err)
+			return err
+		}
+		backward = cursor.Backward
+	}
+	// 多取一条用于判断是否还有更多数据；向前翻页时反向查询后再倒序
+	sql := b.OnlyNonZero().
+		Where(conds...).
+		Seek(keys, values, !backward).
+		WithLimit(p.Limit + 1).
+		BuildSelectQuery(o.table)
+	var list []T
+	err := o.conn(c).Select(&list, sql, b.Args()...)
+	if err != nil {
+		log.F(c).Error(err)
+		return err
+	}
+	hasMore := len(list) > p.Limit
+	if hasMore {
+		list = list[:p.Limit]
+	}
+	if backward {
+		slices.Reverse(list)
+	}
+
+	p.Data, p.Next, p.Prev = list, "", ""
+	if len(list) == 0 {
+		return nil
+	}
+	if hasMore || backward {
+		if p.Next, err = dbutil.EncodeCursor(&list[len(list)-1], false, keys...); err != nil {
+			log.F(c).Error(err)
+			return err
+		}
+	}
+	if (backward && hasMore) || (!backward && values != nil) {
+		if p.Prev, err = dbutil.EncodeCursor(&list[0], true, keys...); err != nil {
+			log.F(c).Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *CrudRepo[T]) SelectTotalCount(c *fiber.Ctx) (int, error) {
+	return o.CountWhere(c)
+}
+
+// CountWhere 按类型化条件统计记录数
+func (o *CrudRepo[T]) CountWhere(c *fiber.Ctx, conds ...dbutil.Cond) (int, error) {
+	b := o.builder(new(T))
+	sql := b.OnlyNonZero().
+		Where(conds...).
+		BuildCountQuery(o.table)
+	var total int
+	err := o.conn(c).Get(&total, sql, b.Args()...)
+	if err != nil {
+		log.F(c).Error(err)
+		return 0, err
+	}
+	return total, nil
+}
+
+// ctxOf 返回请求的 UserContext，c 为空时(如单元测试)返回 context.Background()
+func ctxOf(c *fiber.Ctx) context.Context {
+	if c == nil {
+		return context.Background()
+	}
+	return c.UserContext()
+}
+
+// ctx 返回请求的 UserContext，OnPrimary 时查询使用主库
+func (o *CrudRepo[T]) ctx(c *fiber.Ctx) context.Context {
+	if o.primary {
+		return db.WithPrimary(ctxOf(c))
+	}
+	return ctxOf(c)
+}
+
+// conn 返回绑定请求上下文的执行器：请求上下文中存在事务时使用该事务，否则使用 db.DB
+func (o *CrudRepo[T]) conn(c *fiber.Ctx) db.Executor {
+	return db.Conn(o.ctx(c))
+}
+
+// pkColumn 返回主键列名，未标记 pk 时默认为 id
+// writeError 转换写入错误，违反唯一约束 (如重复的用户账户) 视为 Conflict
+func writeError(err error) error {
+	if db.IsUniqueViolation(err) {
+		return code.Conflict.Wrap(err)
+	}
+	return err
+}
+
+func pkColumn(b *dbutil.Builder) string {
+	if name, _, ok := b.PrimaryKey(); ok {
+		return name
+	}
+	return "id"
+}
+
+// defaultOrderBy 默认排序：存在 created_at 列时按创建时间倒序，否则按主键倒序
+func defaultOrderBy(b *dbutil.Builder) string {
+	if _, ok := b.Column("created_at"); ok {
+		return "created_at desc"
+	}
+	return pkColumn(b) + " desc"
+}
+
+// seekColumns 游标分页的排序列：存在 created_at 列时为 (created_at, 主键)，否则只按主键
+func seekColumns(b *dbutil.Builder) []string {
+	if _, ok := b.Column("created_at"); ok {
+		return []string{"created_at", pkColumn(b)}
+	}
+	return []string{pkColumn(b)}
+}
+
+// setTimeColumn 为 *time.Time 或 time.Time 类型的列赋值
+func setTimeColumn(b *dbutil.Builder, column string, t time.Time) {
+	v, ok := b.Column(column)
+	if !ok || !v.CanSet() {
+		return
+	}
+	switch v.Type() {
+	case reflect.TypeOf(&t):
+		v.Set(reflect.ValueOf(&t))
+	case reflect.TypeOf(t):
+		v.Set(reflect.ValueOf(t))
+	}
+}
+
+// setInt 为 *int 或 int 类型的字段赋值
+func setInt(v reflect.Value, i int) {
+	if !v.CanSet() {
+		return
+	}
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		p.Elem().SetInt(int64(i))
+		v.Set(p)
+		return
+	}
+	v.SetInt(int64(i))
+}
+
+// getInt 读取 *int 或 int 类型字段的值
+func getInt(v reflect.Value) (int, bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int()), true
+	}
+	return 0, false
+}
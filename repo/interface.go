@@ -2,6 +2,8 @@ package repo
 
 import (
 	"app/model"
+	"app/util/dbutil"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -12,10 +14,103 @@ type BaseRepo interface {
 type UserRepo interface {
 	Insert(*fiber.Ctx, *model.User) error
 	Delete(*fiber.Ctx, int) error
+	ForceDelete(*fiber.Ctx, int) error
+	Restore(*fiber.Ctx, int) error
+	WithTrashed() *CrudRepo[model.User]
+	OnlyTrashed() *CrudRepo[model.User]
+	WithListOptions(*dbutil.ListOptions) *CrudRepo[model.User]
 	Update(*fiber.Ctx, *model.User) error
 	Select(*fiber.Ctx, *model.User) ([]model.User, error)
+	SelectWhere(*fiber.Ctx, ...dbutil.Cond) ([]model.User, error)
 	SelectById(*fiber.Ctx, int) (*model.User, error)
 	SelectByUsername(*fiber.Ctx, string) (*model.User, error)
 	SelectWithPagination(*fiber.Ctx, *model.Pagination) error
+	SelectWithCursor(*fiber.Ctx, *model.CursorPagination, ...dbutil.Cond) error
 	SelectTotalCount(*fiber.Ctx) (int, error)
+	Lock(*fiber.Ctx, int, time.Time) error
+	Unlock(*fiber.Ctx, int) error
+	SelectCredentials(*fiber.Ctx, int) (*model.User, error)
+	SelectCredentialsByUsername(*fiber.Ctx, string) (*model.User, error)
+	SetTotp(*fiber.Ctx, int, *string, *time.Time) error
+	UseTotpStep(*fiber.Ctx, int, int64) (bool, error)
+	SetPassword(*fiber.Ctx, int, string) error
+}
+
+type PasswordResetRepo interface {
+	Insert(*fiber.Ctx, *model.PasswordResetToken) error
+	SelectByHash(*fiber.Ctx, string) (*model.PasswordResetToken, error)
+	Use(*fiber.Ctx, int) (bool, error)
+	InvalidateByUserId(*fiber.Ctx, int) error
+	DeleteExpired(*fiber.Ctx) (int64, error)
+}
+
+type RecoveryCodeRepo interface {
+	Replace(*fiber.Ctx, int, []string) error
+	Use(*fiber.Ctx, int, string) (bool, error)
+	CountUnused(*fiber.Ctx, int) (int, error)
+	DeleteByUserId(*fiber.Ctx, int) error
+}
+
+type RefreshTokenRepo interface {
+	Insert(*fiber.Ctx, *model.RefreshToken) error
+	SelectByHash(*fiber.Ctx, string) (*model.RefreshToken, error)
+	Revoke(*fiber.Ctx, int) (bool, error)
+	RevokeFamily(*fiber.Ctx, string) error
+	RevokeByUserId(*fiber.Ctx, int) error
+	DeleteExpired(*fiber.Ctx) (int64, error)
+}
+
+type TokenBlacklistRepo interface {
+	Add(*fiber.Ctx, string, time.Time) error
+	Exists(*fiber.Ctx, string) (bool, error)
+	DeleteExpired(*fiber.Ctx) (int64, error)
+}
+
+type ApiKeyRepo interface {
+	Insert(*fiber.Ctx, *model.ApiKey) error
+	SelectById(*fiber.Ctx, int) (*model.ApiKey, error)
+	SelectByHash(*fiber.Ctx, string) (*model.ApiKey, error)
+	SelectByUserId(*fiber.Ctx, int) ([]model.ApiKey, error)
+	Revoke(*fiber.Ctx, int) (bool, error)
+	Touch(*fiber.Ctx, int, time.Time) error
+}
+
+type UserIdentityRepo interface {
+	Insert(*fiber.Ctx, *model.UserIdentity) error
+	SelectBySubject(*fiber.Ctx, string, string) (*model.UserIdentity, error)
+	SelectByUserId(*fiber.Ctx, int) ([]model.UserIdentity, error)
+}
+
+type OidcStateRepo interface {
+	Save(*fiber.Ctx, string, *model.OidcState, time.Duration) error
+	Take(*fiber.Ctx, string) (*model.OidcState, error)
+}
+
+type RoleRepo interface {
+	Insert(*fiber.Ctx, *model.Role) error
+	Update(*fiber.Ctx, *model.Role) error
+	Delete(*fiber.Ctx, int) error
+	SelectById(*fiber.Ctx, int) (*model.Role, error)
+	SelectByName(*fiber.Ctx, string) (*model.Role, error)
+	SelectWhere(*fiber.Ctx, ...dbutil.Cond) ([]model.Role, error)
+	SelectByUserId(*fiber.Ctx, int) ([]model.Role, error)
+	SetUserRoles(*fiber.Ctx, int, []int) error
+	SetPermissions(*fiber.Ctx, int, []int) error
+}
+
+type PermissionRepo interface {
+	SelectWhere(*fiber.Ctx, ...dbutil.Cond) ([]model.Permission, error)
+	SelectByRoleId(*fiber.Ctx, int) ([]model.Permission, error)
+	SelectNamesByRoles(*fiber.Ctx, []string) ([]string, error)
+}
+
+type LoginAttemptRepo interface {
+	Incr(*fiber.Ctx, string, time.Duration) (int, error)
+	Get(*fiber.Ctx, string) (int, error)
+	Reset(*fiber.Ctx, string) error
+}
+
+type RateLimitRepo interface {
+	SlidingWindow(*fiber.Ctx, string, int, time.Duration) (RateLimitResult, error)
+	TokenBucket(*fiber.Ctx, string, int, time.Duration) (RateLimitResult, error)
 }
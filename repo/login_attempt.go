@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"app/cache"
+	"app/conf"
+	"app/db"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewLoginAttemptRepo 登录失败计数，启用 Redis 时多个实例共享计数，否则保存在内存中
+func NewLoginAttemptRepo() LoginAttemptRepo {
+	if conf.Redis.Enable {
+		return &redisLoginAttemptRepo{}
+	}
+	return &memoryLoginAttemptRepo{entries: cache.NewTTLMap[*loginAttempt](memoryLoginAttemptLimit)}
+}
+
+// loginAttemptKey 登录失败计数的键
+func loginAttemptKey(key string) string {
+	return "login_attempt:" + key
+}
+
+type redisLoginAttemptRepo struct{}
+
+// incrScript KEYS: 计数；ARGV: window(ms)
+// 加一与设置过期时间在同一脚本中原子执行，没有过期时间的计数 (如第一次失败) 才设置，窗口从第一次失败开始计算
+var incrScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// Incr 失败次数加一并返回当前次数，计数在第一次失败 window 之后过期
+func (o *redisLoginAttemptRepo) Incr(c *fiber.Ctx, key string, window time.Duration) (int, error) {
+	n, err := incrScript.Run(ctxOf(c), db.RDB, []string{loginAttemptKey(key)}, window.Milliseconds()).Int()
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (o *redisLoginAttemptRepo) Get(c *fiber.Ctx, key string) (int, error) {
+	n, err := db.RDB.Get(ctxOf(c), loginAttemptKey(key)).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+func (o *redisLoginAttemptRepo) Reset(c *fiber.Ctx, key string) error {
+	return db.RDB.Del(ctxOf(c), loginAttemptKey(key)).Err()
+}
+
+type loginAttempt struct {
+	count int
+}
+
+// memoryLoginAttemptRepo 单实例部署使用的内存计数
+type memoryLoginAttemptRepo struct {
+	mu      sync.Mutex
+	entries *cache.TTLMap[*loginAttempt]
+}
+
+// memoryLoginAttemptLimit 内存中最多保存的计数，超过时淘汰最早开始的计数
+const memoryLoginAttemptLimit = 10000
+
+func (o *memoryLoginAttemptRepo) Incr(_ *fiber.Ctx, key string, window time.Duration) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e, ok := o.entries.Get(key)
+	if !ok {
+		// 只在第一次失败时写入，窗口从第一次失败开始计算
+		e = &loginAttempt{}
+		o.entries.Set(key, e, window)
+	}
+	e.count++
+	return e.count, nil
+}
+
+func (o *memoryLoginAttemptRepo) Get(_ *fiber.Ctx, key string) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e, ok := o.entries.Get(key)
+	if !ok {
+		return 0, nil
+	}
+	return e.count, nil
+}
+
+func (o *memoryLoginAttemptRepo) Reset(_ *fiber.Ctx, key string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries.Delete(key)
+	return nil
+}
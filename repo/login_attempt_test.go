@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_LoginAttempt(t *testing.T) {
+	InitDbEnv()
+	repo := NewLoginAttemptRepo()
+	for i := 1; i <= 3; i++ {
+		if n, err := repo.Incr(nil, "user:a", time.Minute); err != nil || n != i {
+			t.Fatalf("unexpected count: %d %v", n, err)
+		}
+	}
+	if n, _ := repo.Get(nil, "user:a"); n != 3 {
+		t.Fatalf("unexpected count: %d", n)
+	}
+	if err := repo.Reset(nil, "user:a"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := repo.Get(nil, "user:a"); n != 0 {
+		t.Fatalf("count should be reset: %d", n)
+	}
+
+	// 超过统计窗口后重新计数
+	if _, err := repo.Incr(nil, "user:b", time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if n, _ := repo.Get(nil, "user:b"); n != 0 {
+		t.Fatalf("expired count should be zero: %d", n)
+	}
+	if n, _ := repo.Incr(nil, "user:b", time.Minute); n != 1 {
+		t.Fatalf("expired count should restart: %d", n)
+	}
+}
@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"app/cache"
+	"app/conf"
+	"app/db"
+	"app/model"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewOidcStateRepo OpenID Connect 授权请求的状态，启用 Redis 时多个实例共享，否则保存在内存中
+func NewOidcStateRepo() OidcStateRepo {
+	if conf.Redis.Enable {
+		return &redisOidcStateRepo{}
+	}
+	return &memoryOidcStateRepo{entries: cache.NewTTLMap[model.OidcState](memoryOidcStateLimit)}
+}
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+type redisOidcStateRepo struct{}
+
+func (o *redisOidcStateRepo) Save(c *fiber.Ctx, state string, s *model.OidcState, ttl time.Duration) error {
+	value, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return db.RDB.Set(ctxOf(c), oidcStateKey(state), value, ttl).Err()
+}
+
+// Take 取出并删除状态，state 只能使用一次，不存在或已过期时返回 nil
+func (o *redisOidcStateRepo) Take(c *fiber.Ctx, state string) (*model.OidcState, error) {
+	var get *redis.StringCmd
+	_, err := db.RDB.TxPipelined(ctxOf(c), func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctxOf(c), oidcStateKey(state))
+		pipe.Del(ctxOf(c), oidcStateKey(state))
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	s := new(model.OidcState)
+	if err := json.Unmarshal([]byte(get.Val()), s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// memoryOidcStateRepo 单实例部署使用的内存状态
+type memoryOidcStateRepo struct {
+	mu      sync.Mutex
+	entries *cache.TTLMap[model.OidcState]
+}
+
+// memoryOidcStateLimit 内存中最多保存的状态数，超过时淘汰最早保存的状态
+const memoryOidcStateLimit = 10000
+
+func (o *memoryOidcStateRepo) Save(_ *fiber.Ctx, state string, s *model.OidcState, ttl time.Duration) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries.Set(state, *s, ttl)
+	return nil
+}
+
+func (o *memoryOidcStateRepo) Take(_ *fiber.Ctx, state string) (*model.OidcState, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s, ok := o.entries.Get(state)
+	if !ok {
+		return nil, nil
+	}
+	o.entries.Delete(state)
+	return &s, nil
+}
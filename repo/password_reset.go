@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"app/model"
+	"app/util/dbutil"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type passwordResetRepo struct {
+	*CrudRepo[model.PasswordResetToken]
+}
+
+func NewPasswordResetRepo() PasswordResetRepo {
+	return &passwordResetRepo{
+		CrudRepo: NewCrudRepo[model.PasswordResetToken](),
+	}
+}
+
+// SelectByHash 从主库查询，刚签发的令牌可能还未同步到副本
+func (o *passwordResetRepo) SelectByHash(c *fiber.Ctx, tokenHash string) (*model.PasswordResetToken, error) {
+	return o.OnPrimary().SelectOneBy(c, "token_hash", tokenHash)
+}
+
+// Use 使用未使用且未过期的令牌，返回 false 表示令牌已被使用或已过期 (如并发重置时另一个请求已使用该令牌)
+func (o *passwordResetRepo) Use(c *fiber.Ctx, id int) (bool, error) {
+	now := time.Now()
+	n, err := o.UpdateWhere(c, map[string]any{"used_at": now},
+		dbutil.Eq("id", id), dbutil.IsNull("used_at"), dbutil.Gt("expires_at", now))
+	return n > 0, err
+}
+
+// InvalidateByUserId 使用户所有未使用的令牌失效，签发新令牌或修改密码后调用
+func (o *passwordResetRepo) InvalidateByUserId(c *fiber.Ctx, userId int) error {
+	_, err := o.UpdateWhere(c, map[string]any{"used_at": time.Now()},
+		dbutil.Eq("user_id", userId), dbutil.IsNull("used_at"))
+	return err
+}
+
+// DeleteExpired 清理已过期的令牌
+func (o *passwordResetRepo) DeleteExpired(c *fiber.Ctx) (int64, error) {
+	return o.DeleteWhere(c, dbutil.Lt("expires_at", time.Now()))
+}
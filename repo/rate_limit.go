@@ -0,0 +1,193 @@
+package repo
+
+import (
+	"app/cache"
+	"app/conf"
+	"app/db"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitResult 一次限流判断的结果
+type RateLimitResult struct {
+	Allowed    bool          // 是否放行
+	Limit      int           // 窗口内允许的请求数 / 令牌桶容量
+	Remaining  int           // 剩余可用请求数
+	Reset      time.Duration // 距离配额完全恢复的时间
+	RetryAfter time.Duration // 被拒绝时建议的重试等待时间
+}
+
+// NewRateLimitRepo 限流计数，启用 Redis 时多个实例共享计数，否则保存在内存中
+func NewRateLimitRepo() RateLimitRepo {
+	if conf.Redis.Enable {
+		return &redisRateLimitRepo{}
+	}
+	return &memoryRateLimitRepo{
+		windows: cache.NewTTLMap[*windowCounter](memoryRateLimitLimit),
+		buckets: cache.NewTTLMap[*tokenBucket](memoryRateLimitLimit),
+	}
+}
+
+func rateLimitKey(key string) string {
+	return "rate_limit:" + key
+}
+
+// slidingWindow 滑动窗口计数：上一个固定窗口的计数按剩余比例加权后与当前窗口计数相加
+func slidingWindow(prev, cur int, elapsed, window time.Duration) int {
+	weight := float64(window-elapsed) / float64(window)
+	return int(float64(prev)*weight) + cur
+}
+
+func windowResult(allowed bool, count, limit int, elapsed, window time.Duration) RateLimitResult {
+	result := RateLimitResult{Allowed: allowed, Limit: limit, Remaining: max(limit-count, 0), Reset: window - elapsed}
+	if !allowed {
+		result.RetryAfter = result.Reset
+	}
+	return result
+}
+
+func bucketResult(allowed bool, tokens float64, capacity int, rate float64) RateLimitResult {
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Limit:     capacity,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(capacity) - tokens) / rate * float64(time.Millisecond)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Millisecond))
+	}
+	return result
+}
+
+// slidingWindowScript KEYS: 当前窗口, 上一个窗口；ARGV: limit, window(ms), elapsed(ms)
+var slidingWindowScript = redis.NewScript(`
+local cur = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+local limit, window, elapsed = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local count = math.floor(prev * (window - elapsed) / window) + cur
+if count >= limit then
+	return {0, count}
+end
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], window * 2)
+return {1, count + 1}
+`)
+
+// tokenBucketScript KEYS: 令牌桶；ARGV: capacity, rate(令牌/ms), now(ms)
+var tokenBucketScript = redis.NewScript(`
+local capacity, rate, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens, ts = tonumber(data[1]), tonumber(data[2])
+if tokens == nil then
+	tokens, ts = capacity, now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate))
+return {allowed, tostring(tokens)}
+`)
+
+type redisRateLimitRepo struct{}
+
+func (o *redisRateLimitRepo) SlidingWindow(c *fiber.Ctx, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	now := time.Now()
+	index := now.UnixMilli() / window.Milliseconds()
+	elapsed := time.Duration(now.UnixMilli()-index*window.Milliseconds()) * time.Millisecond
+	key = rateLimitKey(key)
+	keys := []string{key + ":" + strconv.FormatInt(index, 10), key + ":" + strconv.FormatInt(index-1, 10)}
+	values, err := slidingWindowScript.Run(ctxOf(c), db.RDB, keys, limit, window.Milliseconds(), elapsed.Milliseconds()).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return windowResult(values[0] == 1, int(values[1]), limit, elapsed, window), nil
+}
+
+func (o *redisRateLimitRepo) TokenBucket(c *fiber.Ctx, key string, capacity int, window time.Duration) (RateLimitResult, error) {
+	rate := float64(capacity) / float64(window.Milliseconds())
+	values, err := tokenBucketScript.Run(ctxOf(c), db.RDB, []string{rateLimitKey(key)},
+		capacity, strconv.FormatFloat(rate, 'f', -1, 64), time.Now().UnixMilli()).Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	allowed, _ := values[0].(int64)
+	tokens, err := strconv.ParseFloat(values[1].(string), 64)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return bucketResult(allowed == 1, tokens, capacity, rate), nil
+}
+
+type windowCounter struct {
+	index     int64
+	cur, prev int
+}
+
+type tokenBucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+// memoryRateLimitRepo 单实例部署使用的内存计数
+type memoryRateLimitRepo struct {
+	mu      sync.Mutex
+	windows *cache.TTLMap[*windowCounter]
+	buckets *cache.TTLMap[*tokenBucket]
+}
+
+// memoryRateLimitLimit 内存中最多保存的计数，超过时淘汰最早写入的计数
+const memoryRateLimitLimit = 100000
+
+func (o *memoryRateLimitRepo) SlidingWindow(_ *fiber.Ctx, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	index := now.UnixMilli() / window.Milliseconds()
+	elapsed := time.Duration(now.UnixMilli()-index*window.Milliseconds()) * time.Millisecond
+	w, ok := o.windows.Get(key)
+	switch {
+	case !ok:
+		w = &windowCounter{index: index}
+	case w.index == index-1:
+		w.index, w.prev, w.cur = index, w.cur, 0
+	case w.index < index-1:
+		w.index, w.prev, w.cur = index, 0, 0
+	}
+	// 两个窗口之后计数不再影响结果
+	o.windows.Set(key, w, 2*window)
+	count := slidingWindow(w.prev, w.cur, elapsed, window)
+	if count >= limit {
+		return windowResult(false, count, limit, elapsed, window), nil
+	}
+	w.cur++
+	return windowResult(true, count+1, limit, elapsed, window), nil
+}
+
+func (o *memoryRateLimitRepo) TokenBucket(_ *fiber.Ctx, key string, capacity int, window time.Duration) (RateLimitResult, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	rate := float64(capacity) / float64(window.Milliseconds())
+	b, ok := o.buckets.Get(key)
+	if !ok {
+		b = &tokenBucket{tokens: float64(capacity), ts: now}
+	}
+	// 一个窗口之后令牌桶已填满，与新建的令牌桶相同
+	o.buckets.Set(key, b, window)
+	b.tokens = math.Min(float64(capacity), b.tokens+float64(now.Sub(b.ts))/float64(time.Millisecond)*rate)
+	b.ts = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return bucketResult(allowed, b.tokens, capacity, rate), nil
+}
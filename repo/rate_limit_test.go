@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_RateLimitSlidingWindow(t *testing.T) {
+	InitDbEnv()
+	repo := NewRateLimitRepo()
+	for i := 0; i < 3; i++ {
+		result, err := repo.SlidingWindow(nil, "window:a", 3, time.Minute)
+		if err != nil || !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("request %d should be allowed: %+v %v", i, result, err)
+		}
+	}
+	result, err := repo.SlidingWindow(nil, "window:a", 3, time.Minute)
+	if err != nil || result.Allowed || result.Remaining != 0 || result.RetryAfter <= 0 {
+		t.Fatalf("request over limit should be denied: %+v %v", result, err)
+	}
+	// 不同的键分别计数
+	if result, _ := repo.SlidingWindow(nil, "window:b", 3, time.Minute); !result.Allowed {
+		t.Fatalf("other key should be allowed: %+v", result)
+	}
+}
+
+func Test_RateLimitTokenBucket(t *testing.T) {
+	InitDbEnv()
+	repo := NewRateLimitRepo()
+	window := 100 * time.Millisecond
+	for i := 0; i < 2; i++ {
+		if result, err := repo.TokenBucket(nil, "bucket:a", 2, window); err != nil || !result.Allowed {
+			t.Fatalf("request %d should be allowed: %+v %v", i, result, err)
+		}
+	}
+	result, err := repo.TokenBucket(nil, "bucket:a", 2, window)
+	if err != nil || result.Allowed || result.RetryAfter <= 0 || result.RetryAfter > window {
+		t.Fatalf("empty bucket should deny: %+v %v", result, err)
+	}
+	// 令牌按速率补充
+	time.Sleep(result.RetryAfter + 10*time.Millisecond)
+	if result, err := repo.TokenBucket(nil, "bucket:a", 2, window); err != nil || !result.Allowed {
+		t.Fatalf("refilled bucket should allow: %+v %v", result, err)
+	}
+}
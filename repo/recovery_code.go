@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"app/model"
+	"app/util/dbutil"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type recoveryCodeRepo struct {
+	*CrudRepo[model.RecoveryCode]
+}
+
+func NewRecoveryCodeRepo() RecoveryCodeRepo {
+	return &recoveryCodeRepo{
+		CrudRepo: NewCrudRepo[model.RecoveryCode](),
+	}
+}
+
+// Replace 将用户的恢复码替换为 codeHashes，应在事务中调用
+func (o *recoveryCodeRepo) Replace(c *fiber.Ctx, userId int, codeHashes []string) error {
+	if err := o.DeleteByUserId(c, userId); err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		if err := o.Insert(c, &model.RecoveryCode{UserId: &userId, CodeHash: &codeHash}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Use 使用一个未使用的恢复码，返回 false 表示恢复码不存在或已被使用
+func (o *recoveryCodeRepo) Use(c *fiber.Ctx, userId int, codeHash string) (bool, error) {
+	n, err := o.UpdateWhere(c, map[string]any{"used_at": time.Now()},
+		dbutil.Eq("user_id", userId), dbutil.Eq("code_hash", codeHash), dbutil.IsNull("used_at"))
+	return n > 0, err
+}
+
+// CountUnused 用户剩余可用的恢复码数量
+func (o *recoveryCodeRepo) CountUnused(c *fiber.Ctx, userId int) (int, error) {
+	return o.CountWhere(c, dbutil.Eq("user_id", userId), dbutil.IsNull("used_at"))
+}
+
+func (o *recoveryCodeRepo) DeleteByUserId(c *fiber.Ctx, userId int) error {
+	_, err := o.DeleteWhere(c, dbutil.Eq("user_id", userId))
+	return err
+}
@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"app/model"
+	"app/util/dbutil"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type roleRepo struct {
+	*CrudRepo[model.Role]
+	userRoles       *CrudRepo[model.UserRole]
+	rolePermissions *CrudRepo[model.RolePermission]
+}
+
+func NewRoleRepo() RoleRepo {
+	return &roleRepo{
+		CrudRepo:        NewCrudRepo[model.Role](),
+		userRoles:       NewCrudRepo[model.UserRole](),
+		rolePermissions: NewCrudRepo[model.RolePermission](),
+	}
+}
+
+func (o *roleRepo) SelectByName(c *fiber.Ctx, name string) (*model.Role, error) {
+	return o.SelectOneBy(c, "name", name)
+}
+
+// Delete 删除角色及其权限、用户关联，应在事务中调用
+func (o *roleRepo) Delete(c *fiber.Ctx, id int) error {
+	if _, err := o.rolePermissions.DeleteWhere(c, dbutil.Eq("role_id", id)); err != nil {
+		return err
+	}
+	if _, err := o.userRoles.DeleteWhere(c, dbutil.Eq("role_id", id)); err != nil {
+		return err
+	}
+	return o.CrudRepo.Delete(c, id)
+}
+
+// SelectByUserId 查询用户拥有的角色
+func (o *roleRepo) SelectByUserId(c *fiber.Ctx, userId int) ([]model.Role, error) {
+	return o.SelectWhere(c, dbutil.InSelect("id", o.userRoles.TableName(), "role_id", dbutil.Eq("user_id", userId)))
+}
+
+// SetUserRoles 将用户的角色替换为 roleIds，应在事务中调用
+func (o *roleRepo) SetUserRoles(c *fiber.Ctx, userId int, roleIds []int) error {
+	if _, err := o.userRoles.DeleteWhere(c, dbutil.Eq("user_id", userId)); err != nil {
+		return err
+	}
+	for _, roleId := range roleIds {
+		if err := o.userRoles.Insert(c, &model.UserRole{UserId: &userId, RoleId: &roleId}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetPermissions 将角色的权限替换为 permissionIds，应在事务中调用
+func (o *roleRepo) SetPermissions(c *fiber.Ctx, roleId int, permissionIds []int) error {
+	if _, err := o.rolePermissions.DeleteWhere(c, dbutil.Eq("role_id", roleId)); err != nil {
+		return err
+	}
+	for _, permissionId := range permissionIds {
+		if err := o.rolePermissions.Insert(c, &model.RolePermission{RoleId: &roleId, PermissionId: &permissionId}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type permissionRepo struct {
+	*CrudRepo[model.Permission]
+}
+
+func NewPermissionRepo() PermissionRepo {
+	return &permissionRepo{
+		CrudRepo: NewCrudRepo[model.Permission](),
+	}
+}
+
+// SelectByRoleId 查询角色拥有的权限
+func (o *permissionRepo) SelectByRoleId(c *fiber.Ctx, roleId int) ([]model.Permission, error) {
+	return o.SelectWhere(c, dbutil.InSelect("id", (*model.RolePermission)(nil).TableName(), "permission_id",
+		dbutil.Eq("role_id", roleId)))
+}
+
+// SelectNamesByRoles 查询角色名称列表拥有的权限名称
+func (o *permissionRepo) SelectNamesByRoles(c *fiber.Ctx, roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return nil, nil
+	}
+	permissions, err := o.SelectWhere(c, dbutil.InSelect("id", (*model.RolePermission)(nil).TableName(), "permission_id",
+		dbutil.InSelect("role_id", (*model.Role)(nil).TableName(), "id", dbutil.In("name", roles))))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		names = append(names, *p.Name)
+	}
+	return names, nil
+}
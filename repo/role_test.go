@@ -0,0 +1,66 @@
+package repo
+
+import (
+	"app/model"
+	"app/util"
+	"slices"
+	"testing"
+)
+
+func Test_Role(t *testing.T) {
+	InitDbEnv()
+	roleRepo := NewRoleRepo()
+	permissionRepo := NewPermissionRepo()
+
+	role := &model.Role{Name: util.EnPointer("role_" + util.RandString(8))}
+	if err := roleRepo.Insert(nil, role); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = roleRepo.Delete(nil, *role.Id) })
+
+	read, err := permissionRepo.SelectWhere(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, p := range read {
+		if *p.Name == "user:read" || *p.Name == "user:update" {
+			ids = append(ids, *p.Id)
+		}
+	}
+	if len(ids) != 2 {
+		t.Fatalf("seed permissions not found: %+v", read)
+	}
+	if err := roleRepo.SetPermissions(nil, *role.Id, ids); err != nil {
+		t.Fatal(err)
+	}
+	names, err := permissionRepo.SelectNamesByRoles(nil, []string{*role.Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"user:read", "user:update"}) {
+		t.Fatalf("unexpected permissions: %v", names)
+	}
+
+	userRepo := NewUserRepo()
+	user := &model.User{Username: util.EnPointer("role_" + util.RandString(8)), Password: util.EnPointer("password")}
+	if err := userRepo.Insert(nil, user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *user.Id) })
+	userId := *user.Id
+	if err := roleRepo.SetUserRoles(nil, userId, []int{*role.Id}); err != nil {
+		t.Fatal(err)
+	}
+	roles, err := roleRepo.SelectByUserId(nil, userId)
+	if err != nil || len(roles) != 1 || *roles[0].Name != *role.Name {
+		t.Fatalf("unexpected user roles: %+v %v", roles, err)
+	}
+	if err := roleRepo.SetUserRoles(nil, userId, nil); err != nil {
+		t.Fatal(err)
+	}
+	if roles, _ := roleRepo.SelectByUserId(nil, userId); len(roles) != 0 {
+		t.Fatalf("user roles should be cleared: %+v", roles)
+	}
+}
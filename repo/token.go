@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"app/code"
+	"app/conf"
+	"app/db"
+	"app/log"
+	"app/model"
+	"app/util/dbutil"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type refreshTokenRepo struct {
+	*CrudRepo[model.RefreshToken]
+}
+
+func NewRefreshTokenRepo() RefreshTokenRepo {
+	return &refreshTokenRepo{
+		CrudRepo: NewCrudRepo[model.RefreshToken](),
+	}
+}
+
+// SelectByHash 从主库查询，刚签发或轮换的令牌可能还未同步到副本，已吊销的令牌在副本中可能仍未吊销
+func (o *refreshTokenRepo) SelectByHash(c *fiber.Ctx, tokenHash string) (*model.RefreshToken, error) {
+	return o.OnPrimary().SelectOneBy(c, "token_hash", tokenHash)
+}
+
+// Revoke 吊销未被吊销的令牌，返回 false 表示令牌已被吊销 (如并发刷新时另一个请求已使用该令牌)
+func (o *refreshTokenRepo) Revoke(c *fiber.Ctx, id int) (bool, error) {
+	n, err := o.UpdateWhere(c, map[string]any{"revoked_at": time.Now()},
+		dbutil.Eq("id", id), dbutil.IsNull("revoked_at"))
+	return n > 0, err
+}
+
+// RevokeFamily 吊销令牌族中所有未被吊销的令牌
+func (o *refreshTokenRepo) RevokeFamily(c *fiber.Ctx, family string) error {
+	_, err := o.UpdateWhere(c, map[string]any{"revoked_at": time.Now()},
+		dbutil.Eq("family", family), dbutil.IsNull("revoked_at"))
+	return err
+}
+
+// RevokeByUserId 吊销用户所有未被吊销的令牌，修改或重置密码后调用
+func (o *refreshTokenRepo) RevokeByUserId(c *fiber.Ctx, userId int) error {
+	_, err := o.UpdateWhere(c, map[string]any{"revoked_at": time.Now()},
+		dbutil.Eq("user_id", userId), dbutil.IsNull("revoked_at"))
+	return err
+}
+
+// DeleteExpired 清理已过期的令牌
+func (o *refreshTokenRepo) DeleteExpired(c *fiber.Ctx) (int64, error) {
+	return o.DeleteWhere(c, dbutil.Lt("expires_at", time.Now()))
+}
+
+type tokenBlacklistRepo struct {
+	*CrudRepo[model.TokenBlacklist]
+}
+
+func NewTokenBlacklistRepo() TokenBlacklistRepo {
+	return &tokenBlacklistRepo{
+		CrudRepo: NewCrudRepo[model.TokenBlacklist](),
+	}
+}
+
+// Add 将访问令牌加入黑名单，启用 Redis 时同时写入 Redis，过期时间与令牌一致；重复加入 (如并发注销) 时不报错
+// Redis 在请求内同步写入，写入失败时返回错误，避免 Exists 在 Redis 中查不到已注销的令牌；
+// 在事务中调用且事务回滚时 Redis 中的记录不会撤销，令牌仍视为已注销
+func (o *tokenBlacklistRepo) Add(c *fiber.Ctx, jti string, expiresAt time.Time) error {
+	err := o.Upsert(c, &model.TokenBlacklist{Jti: &jti, ExpiresAt: &expiresAt}, "jti")
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(expiresAt)
+	if !conf.Redis.Enable || ttl <= 0 {
+		return nil
+	}
+	if err := db.RDB.Set(ctxOf(c), model.TokenBlacklistCacheKey(jti), 1, ttl).Err(); err != nil {
+		log.F(c).Error(err)
+		return code.RedisSetDataFailed.Wrap(err)
+	}
+	return nil
+}
+
+// Exists 访问令牌是否在黑名单中，启用 Redis 时查询 Redis (Add 已同步写入)，Redis 出错时查询主库 (刚注销的令牌可能还未同步到副本)
+func (o *tokenBlacklistRepo) Exists(c *fiber.Ctx, jti string) (bool, error) {
+	if conf.Redis.Enable {
+		n, err := db.RDB.Exists(ctxOf(c), model.TokenBlacklistCacheKey(jti)).Result()
+		if err == nil {
+			return n > 0, nil
+		}
+		log.F(c).Error(err)
+	}
+	total, err := o.OnPrimary().CountWhere(c, dbutil.Eq("jti", jti))
+	return total > 0, err
+}
+
+// DeleteExpired 清理令牌已过期的黑名单记录
+func (o *tokenBlacklistRepo) DeleteExpired(c *fiber.Ctx) (int64, error) {
+	return o.DeleteWhere(c, dbutil.Lt("expires_at", time.Now()))
+}
@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"app/model"
+	"app/util"
+	"app/util/dbutil"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_RefreshToken(t *testing.T) {
+	InitDbEnv()
+	repo := NewRefreshTokenRepo()
+	family := "family_" + util.RandString(8)
+	var tokens []*model.RefreshToken
+	for i := 0; i < 2; i++ {
+		token := &model.RefreshToken{
+			TokenHash: util.EnPointer(util.RandString(32)),
+			Family:    &family,
+			UserId:    util.EnPointer(1),
+			ExpiresAt: util.EnPointer(time.Now().Add(time.Hour)),
+		}
+		if err := repo.Insert(nil, token); err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if ok, err := repo.Revoke(nil, *tokens[0].Id); err != nil || !ok {
+		t.Fatalf("revoke should succeed: %v %v", ok, err)
+	}
+	if ok, _ := repo.Revoke(nil, *tokens[0].Id); ok {
+		t.Fatal("revoked token should not be revoked again")
+	}
+	if err := repo.RevokeFamily(nil, family); err != nil {
+		t.Fatal(err)
+	}
+	token, err := repo.SelectByHash(nil, *tokens[1].TokenHash)
+	if err != nil || token.RevokedAt == nil {
+		t.Fatalf("family should be revoked: %+v %v", token, err)
+	}
+
+	expired := &model.RefreshToken{
+		TokenHash: util.EnPointer(util.RandString(32)),
+		Family:    &family,
+		UserId:    util.EnPointer(1),
+		ExpiresAt: util.EnPointer(time.Now().Add(-time.Minute)),
+	}
+	if err := repo.Insert(nil, expired); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.DeleteExpired(nil); err != nil || n < 1 {
+		t.Fatalf("expired token should be deleted: %d %v", n, err)
+	}
+	if _, err := repo.SelectByHash(nil, *expired.TokenHash); err == nil {
+		t.Fatal("expired token should not exist")
+	}
+}
+
+func Test_TokenBlacklist(t *testing.T) {
+	InitDbEnv()
+	repo := NewTokenBlacklistRepo()
+	jti := util.RandString(16)
+	if exists, err := repo.Exists(nil, jti); err != nil || exists {
+		t.Fatalf("jti should not be blacklisted: %v %v", exists, err)
+	}
+	if err := repo.Add(nil, jti, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := repo.Exists(nil, jti); err != nil || !exists {
+		t.Fatalf("jti should be blacklisted: %v %v", exists, err)
+	}
+	// 重复加入 (如并发注销) 时更新过期时间，不违反唯一约束
+	if err := repo.Add(nil, jti, time.Now().Add(2*time.Hour)); err != nil {
+		t.Fatalf("adding a blacklisted jti again should succeed: %v", err)
+	}
+	if n, err := repo.(*tokenBlacklistRepo).CountWhere(nil, dbutil.Eq("jti", jti)); err != nil || n != 1 {
+		t.Fatalf("jti should be blacklisted once: %d %v", n, err)
+	}
+
+	expired := util.RandString(16)
+	if err := repo.Add(nil, expired, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.DeleteExpired(nil); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := repo.Exists(nil, expired); exists {
+		t.Fatal("expired record should be deleted")
+	}
+	if exists, _ := repo.Exists(nil, jti); !exists {
+		t.Fatal("unexpired record should be kept")
+	}
+}
+
+func Test_TokenLaggingReplica(t *testing.T) {
+	laggingReplica(t)
+	refreshRepo, blacklistRepo, resetRepo := NewRefreshTokenRepo(), NewTokenBlacklistRepo(), NewPasswordResetRepo()
+	userRepo := NewUserRepo()
+	user := &model.User{Username: util.EnPointer("lag_" + util.RandString(8)), Password: util.EnPointer("password")}
+	if err := userRepo.Insert(nil, user); err != nil {
+		t.Fatal(err)
+	}
+	defer userRepo.ForceDelete(nil, *user.Id)
+	token := &model.RefreshToken{
+		TokenHash: util.EnPointer(util.RandString(32)),
+		Family:    util.EnPointer("family_" + util.RandString(8)),
+		UserId:    user.Id,
+		ExpiresAt: util.EnPointer(time.Now().Add(time.Hour)),
+	}
+	if err := refreshRepo.Insert(nil, token); err != nil {
+		t.Fatal(err)
+	}
+	reset := &model.PasswordResetToken{
+		TokenHash: util.EnPointer(util.RandString(32)),
+		UserId:    user.Id,
+		ExpiresAt: util.EnPointer(time.Now().Add(time.Hour)),
+	}
+	if err := resetRepo.Insert(nil, reset); err != nil {
+		t.Fatal(err)
+	}
+	jti := util.RandString(16)
+	if err := blacklistRepo.Add(nil, jti, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 普通查询发往副本，读不到刚写入的记录
+	if _, err := refreshRepo.(*refreshTokenRepo).SelectOneBy(nil, "token_hash", *token.TokenHash); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("replica should lag behind the primary, got %v", err)
+	}
+	// 令牌与凭据校验使用主库
+	if found, err := refreshRepo.SelectByHash(nil, *token.TokenHash); err != nil || *found.Id != *token.Id {
+		t.Fatalf("SelectByHash() = %+v, %v", found, err)
+	}
+	if found, err := resetRepo.SelectByHash(nil, *reset.TokenHash); err != nil || *found.Id != *reset.Id {
+		t.Fatalf("SelectByHash() = %+v, %v", found, err)
+	}
+	if exists, err := blacklistRepo.Exists(nil, jti); err != nil || !exists {
+		t.Fatalf("Exists() = %v, %v, want blacklisted", exists, err)
+	}
+	if found, err := userRepo.SelectCredentials(nil, *user.Id); err != nil || *found.Id != *user.Id {
+		t.Fatalf("SelectCredentials() = %+v, %v", found, err)
+	}
+}
@@ -1,155 +1,131 @@
 package repo
 
 import (
+	"app/cache"
 	"app/db"
+	"app/log"
 	"app/model"
-	"app/util"
 	"app/util/dbutil"
-	"fmt"
+	"context"
+	"database/sql"
+	"errors"
 	"time"
 
-	"app/log"
-
 	"github.com/gofiber/fiber/v2"
 )
 
 type userRepo struct {
+	*CrudRepo[model.User]
+	// byUsername 缓存用户名到主键的映射，用户本身经 SelectById 缓存
+	byUsername *cache.Loader[string, int]
 }
 
 func NewUserRepo() UserRepo {
-	return &userRepo{}
+	r := &userRepo{
+		CrudRepo: NewCrudRepo[model.User](),
+	}
+	r.byUsername = cache.NewLoader(r.table+":username", r.selectIdByUsername, cache.LoaderOptions{})
+	return r
 }
 
 func (o *userRepo) Insert(c *fiber.Ctx, user *model.User) error {
-	user.CreatedAt = util.EnPointer(time.Now())
-	sql := fmt.Sprintf("INSERT INTO user(%s) VALUES (%s)",
-		dbutil.NewBuilder(user).OnlyNonZero().BuildColumns(", "),
-		dbutil.NewBuilder(user).OnlyNonZero().WithPrefix(":").BuildNamedPlaceholders(", "))
-	result, err := db.DB.NamedExec(sql, user)
-	if err != nil {
-		log.F(c).Info(err)
-		return err
-	}
-	id, err := result.LastInsertId()
-	if err != nil {
-		log.F(c).Error(err)
+	if err := o.CrudRepo.Insert(c, user); err != nil {
 		return err
 	}
-	user.Id = util.EnPointer(int(id))
-	db.RDB.SetStruct(user.CacheKey(), user)
+	o.invalidateUsername(c, user.Username)
 	return nil
 }
 
-func (o *userRepo) Delete(c *fiber.Ctx, id int) error {
-	sql := "DELETE FROM user WHERE id = ?"
-	_, err := db.DB.Exec(sql, id)
-	if err != nil {
-		log.F(c).Error(err)
+func (o *userRepo) Update(c *fiber.Ctx, user *model.User) error {
+	if err := o.CrudRepo.Update(c, user); err != nil {
 		return err
 	}
-	db.RDB.Delete(model.UserCacheKey(id))
+	o.invalidateUsername(c, user.Username)
 	return nil
 }
 
-func (o *userRepo) Update(c *fiber.Ctx, user *model.User) error {
-	user.UpdatedAt = util.EnPointer(time.Now())
-	sql := fmt.Sprintf("UPDATE user SET %s WHERE id = :id",
-		dbutil.NewBuilder(user).ExcludePK().OnlyNonZero().BuildSetClauses(","))
-	_, err := db.DB.NamedExec(sql, user)
+// SelectByUsername 按用户名查询未删除的用户，先经缓存取得主键，映射过期 (如用户已改名或删除) 时直接查库
+func (o *userRepo) SelectByUsername(c *fiber.Ctx, username string) (*model.User, error) {
+	id, err := o.byUsername.Get(ctxOf(c), username)
 	if err != nil {
 		log.F(c).Error(err)
-		return err
+		return nil, err
 	}
-	db.RDB.Delete(user.CacheKey())
-	return nil
+	user, err := o.SelectById(c, *id)
+	if err == nil && user.Username != nil && *user.Username == username {
+		return user, nil
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	o.invalidateUsername(c, &username)
+	return o.SelectOneBy(c, "username", username)
 }
 
-func (o *userRepo) Select(c *fiber.Ctx, userFilter *model.User) ([]model.User, error) {
-	sql := dbutil.NewBuilder(userFilter).
+func (o *userRepo) selectIdByUsername(ctx context.Context, username string) (*int, error) {
+	var id int
+	sql := o.builder(new(model.User)).
 		OnlyNonZero().
-		WithOrderBy("created_at desc").
-		BuildSelectQuery("user")
-	var users []model.User
-	stmt, err := db.DB.PrepareNamed(sql)
-	if err != nil {
-		log.F(c).Error(err)
-		return nil, err
-	}
-	err = stmt.Select(&users, userFilter)
-	if err != nil {
-		log.F(c).Error(err)
+		WithFields("id").
+		WithCustomWhere("username = ?").
+		BuildSelectQuery(o.table)
+	if err := db.Conn(ctx).Get(&id, sql, username); err != nil {
 		return nil, err
 	}
-	return users, nil
+	return &id, nil
 }
 
-func (o *userRepo) SelectById(c *fiber.Ctx, id int) (*model.User, error) {
-	user := model.User{}
-	if db.RDB.GetStruct(model.UserCacheKey(id), &user) != nil {
-		return &user, nil
+// invalidateUsername 在事务提交后删除用户名映射的缓存 (包括不存在的用户名的空值缓存)
+func (o *userRepo) invalidateUsername(c *fiber.Ctx, username *string) {
+	if username == nil {
+		return
 	}
+	ctx := context.WithoutCancel(ctxOf(c))
+	db.AfterCommit(ctx, func() {
+		o.byUsername.Invalidate(ctx, *username)
+	})
+}
 
-	sql := dbutil.NewBuilder(user).
-		OnlyNonZero().
-		WithCustomWhere("id = ?").
-		BuildSelectQuery("user")
+// Lock 锁定用户至 until，锁定期间不允许登录
+func (o *userRepo) Lock(c *fiber.Ctx, id int, until time.Time) error {
+	return o.setLockedUntil(c, id, &until)
+}
 
-	err := db.DB.Get(&user, sql, id)
-	if err != nil {
-		log.F(c).Error(err)
-		return nil, err
-	}
-	return &user, nil
+// Unlock 解除用户锁定
+func (o *userRepo) Unlock(c *fiber.Ctx, id int) error {
+	return o.setLockedUntil(c, id, nil)
 }
 
-func (o *userRepo) SelectByUsername(c *fiber.Ctx, username string) (*model.User, error) {
-	sql := dbutil.NewBuilder(&model.User{}).
-		OnlyNonZero().
-		WithCustomWhere("username = ?").
-		BuildSelectQuery("user")
+func (o *userRepo) setLockedUntil(c *fiber.Ctx, id int, until *time.Time) error {
+	_, err := o.UpdateWhere(c, map[string]any{"locked_until": until}, dbutil.Eq("id", id))
+	return err
+}
 
-	result := model.User{}
-	err := db.DB.Get(&result, sql, username)
-	if err != nil {
-		log.F(c).Error(err)
-		return nil, err
-	}
-	return &result, nil
+// SelectCredentials 直接查主库获取用户，缓存中不包含 json:"-" 的列 (如密码摘要与 TOTP 密钥)，副本中可能还是修改前的密码，校验凭据时使用
+func (o *userRepo) SelectCredentials(c *fiber.Ctx, id int) (*model.User, error) {
+	return o.OnPrimary().SelectOneBy(c, "id", id)
 }
 
-func (o *userRepo) SelectWithPagination(c *fiber.Ctx, p *model.Pagination) error {
-	if p.Total == 0 {
-		total, err := o.SelectTotalCount(c)
-		if err != nil {
-			return err
-		} else if total == 0 {
-			p.Data = nil
-			return nil
-		}
-		p.Total = total
-	}
-	p.Format()
-	sql := dbutil.NewBuilder(&model.User{}).
-		OnlyNonZero().
-		WithLimitOffset(p.Size, p.Offset).
-		BuildSelectQuery("user")
-	var users []model.User
-	err := db.DB.Select(&users, sql)
-	if err != nil {
-		log.F(c).Error(err)
-		return err
-	}
-	p.Data = users
-	return nil
+// SelectCredentialsByUsername 按用户名直接查主库获取未删除的用户，不经过缓存，密码登录时使用
+func (o *userRepo) SelectCredentialsByUsername(c *fiber.Ctx, username string) (*model.User, error) {
+	return o.OnPrimary().SelectOneBy(c, "username", username)
 }
 
-func (o *userRepo) SelectTotalCount(c *fiber.Ctx) (int, error) {
-	sql := "SELECT COUNT(id) AS total FROM user"
-	var total int
-	err := db.DB.Get(&total, sql)
-	if err != nil {
-		log.F(c).Error(err)
-		return 0, err
-	}
-	return total, nil
+// SetTotp 设置 TOTP 密钥与启用时间，同时清空最后使用的时间步；均为 nil 时关闭两步验证
+func (o *userRepo) SetTotp(c *fiber.Ctx, id int, secret *string, enabledAt *time.Time) error {
+	_, err := o.UpdateWhere(c, map[string]any{"totp_secret": secret, "totp_enabled_at": enabledAt, "totp_last_step": nil},
+		dbutil.Eq("id", id))
+	return err
+}
+
+// UseTotpStep 记录通过验证的 TOTP 时间步，返回 false 表示该时间步或更晚的验证码已被使用 (重放)
+func (o *userRepo) UseTotpStep(c *fiber.Ctx, id int, step int64) (bool, error) {
+	n, err := o.UpdateWhere(c, map[string]any{"totp_last_step": step}, dbutil.Eq("id", id),
+		dbutil.Or(dbutil.IsNull("totp_last_step"), dbutil.Lt("totp_last_step", step)))
+	return n > 0, err
+}
+
+// SetPassword 更新密码摘要
+func (o *userRepo) SetPassword(c *fiber.Ctx, id int, passwordHash string) error {
+	_, err := o.UpdateWhere(c, map[string]any{"password": passwordHash, "updated_at": time.Now()}, dbutil.Eq("id", id))
+	return err
 }
@@ -0,0 +1,36 @@
+package repo
+
+import (
+	"app/model"
+	"app/util/dbutil"
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type userIdentityRepo struct {
+	*CrudRepo[model.UserIdentity]
+}
+
+func NewUserIdentityRepo() UserIdentityRepo {
+	return &userIdentityRepo{
+		CrudRepo: NewCrudRepo[model.UserIdentity](),
+	}
+}
+
+// SelectBySubject 按身份提供方与外部用户标识查询关联
+func (o *userIdentityRepo) SelectBySubject(c *fiber.Ctx, provider, subject string) (*model.UserIdentity, error) {
+	list, err := o.SelectWhere(c, dbutil.Eq("provider", provider), dbutil.Eq("subject", subject))
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &list[0], nil
+}
+
+// SelectByUserId 查询用户关联的所有外部账户
+func (o *userIdentityRepo) SelectByUserId(c *fiber.Ctx, userId int) ([]model.UserIdentity, error) {
+	return o.SelectWhere(c, dbutil.Eq("user_id", userId))
+}
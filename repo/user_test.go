@@ -1,13 +1,21 @@
 package repo
 
 import (
+	"app/cache"
 	"app/conf"
 	"app/db"
 	"app/log"
 	"app/model"
 	"app/util"
+	"app/util/dbutil"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func InitDbEnv() {
@@ -84,3 +92,262 @@ func Test_SelectWithPagination(t *testing.T) {
 	jsonStr, _ := json.Marshal(p.Data)
 	t.Log(string(jsonStr))
 }
+
+func Test_SelectWhere(t *testing.T) {
+	InitDbEnv()
+	repo := NewUserRepo()
+	users, err := repo.SelectWhere(nil,
+		dbutil.Contains("username", "user"),
+		dbutil.Or(dbutil.In("id", 1, 2, 3), dbutil.IsNull("deleted_at")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonStr, _ := json.Marshal(users)
+	t.Log(string(jsonStr))
+}
+
+func Test_SoftDelete(t *testing.T) {
+	InitDbEnv()
+	repo := NewUserRepo()
+	user := &model.User{
+		Username: util.EnPointer("soft_delete_" + util.RandString(8)),
+		Password: util.EnPointer("password"),
+	}
+	if err := repo.Insert(nil, user); err != nil {
+		t.Fatal(err)
+	}
+	id := *user.Id
+	defer repo.ForceDelete(nil, id)
+
+	if err := repo.Delete(nil, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SelectById(nil, id); err == nil {
+		t.Fatal("soft deleted user should not be selected by default")
+	}
+	if _, err := repo.WithTrashed().SelectById(nil, id); err != nil {
+		t.Fatal("soft deleted user should be selected with trashed")
+	}
+	trashed, err := repo.OnlyTrashed().SelectWhere(nil, dbutil.Eq("id", id))
+	if err != nil || len(trashed) != 1 {
+		t.Fatalf("soft deleted user should be in trashed list, err: %v", err)
+	}
+
+	if err := repo.Restore(nil, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SelectById(nil, id); err != nil {
+		t.Fatal("restored user should be selected")
+	}
+
+	if err := repo.ForceDelete(nil, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.WithTrashed().SelectById(nil, id); err == nil {
+		t.Fatal("force deleted user should not exist")
+	}
+}
+
+func Test_SelectWithCursor(t *testing.T) {
+	InitDbEnv()
+	repo := NewUserRepo()
+	prefix := "cursor_" + util.RandString(8) + "_"
+	var ids []int
+	for i := 0; i < 5; i++ {
+		user := &model.User{
+			Username: util.EnPointer(prefix + strconv.Itoa(i)),
+			Password: util.EnPointer("password"),
+		}
+		if err := repo.Insert(nil, user); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, *user.Id)
+		defer repo.ForceDelete(nil, *user.Id)
+	}
+	filter := dbutil.HasPrefix("username", prefix)
+	pageIds := func(p *model.CursorPagination) []int {
+		var result []int
+		for _, u := range p.Data.([]model.User) {
+			result = append(result, *u.Id)
+		}
+		return result
+	}
+
+	// 向后翻页: 5 条记录按创建时间倒序分为 2, 2, 1
+	var pages [][]int
+	p := &model.CursorPagination{Limit: 2}
+	for {
+		if err := repo.SelectWithCursor(nil, p, filter); err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, pageIds(p))
+		if p.Next == "" {
+			break
+		}
+		p = &model.CursorPagination{Limit: 2, Cursor: p.Next}
+	}
+	expected := [][]int{{ids[4], ids[3]}, {ids[2], ids[1]}, {ids[0]}}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Fatalf("unexpected pages %v, expected %v", pages, expected)
+	}
+
+	// 从最后一页向前翻页
+	p = &model.CursorPagination{Limit: 2, Cursor: p.Prev}
+	if err := repo.SelectWithCursor(nil, p, filter); err != nil {
+		t.Fatal(err)
+	}
+	if got := pageIds(p); !reflect.DeepEqual(got, expected[1]) || p.Prev == "" || p.Next == "" {
+		t.Fatalf("unexpected prev page %v, expected %v", got, expected[1])
+	}
+	p = &model.CursorPagination{Limit: 2, Cursor: p.Prev}
+	if err := repo.SelectWithCursor(nil, p, filter); err != nil {
+		t.Fatal(err)
+	}
+	if got := pageIds(p); !reflect.DeepEqual(got, expected[0]) || p.Prev != "" {
+		t.Fatalf("unexpected first page %v, expected %v", got, expected[0])
+	}
+
+	if err := repo.SelectWithCursor(nil, &model.CursorPagination{Cursor: "invalid"}); err != dbutil.ErrInvalidCursor {
+		t.Fatalf("invalid cursor should be rejected, got %v", err)
+	}
+}
+
+func Test_WithListOptions(t *testing.T) {
+	InitDbEnv()
+	repo := NewUserRepo()
+	prefix := "list_" + util.RandString(8) + "_"
+	for _, name := range []string{"b", "a", "c"} {
+		user := &model.User{
+			Username: util.EnPointer(prefix + name),
+			Password: util.EnPointer("password"),
+		}
+		if err := repo.Insert(nil, user); err != nil {
+			t.Fatal(err)
+		}
+		defer repo.ForceDelete(nil, *user.Id)
+	}
+	opts, err := dbutil.ParseListOptions(&model.User{}, "username", "id,username")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := repo.WithListOptions(opts).SelectWhere(nil, dbutil.HasPrefix("username", prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, u := range users {
+		if u.Password != nil || u.CreatedAt != nil {
+			t.Errorf("unselected fields should be empty: %+v", u)
+		}
+		names = append(names, *u.Username)
+	}
+	if !reflect.DeepEqual(names, []string{prefix + "a", prefix + "b", prefix + "c"}) {
+		t.Errorf("unexpected order: %v", names)
+	}
+}
+
+func Test_UserCache(t *testing.T) {
+	InitDbEnv()
+	cache.Initialize()
+	defer cache.Close()
+	repo := NewUserRepo()
+	username := "cache_" + util.RandString(8)
+
+	// 不存在的用户名被缓存为空值，插入后失效
+	if _, err := repo.SelectByUsername(nil, username); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("SelectByUsername() error = %v, want sql.ErrNoRows", err)
+	}
+	user := &model.User{Username: &username, Password: util.EnPointer("password")}
+	if err := repo.Insert(nil, user); err != nil {
+		t.Fatal(err)
+	}
+	found, err := repo.SelectByUsername(nil, username)
+	if err != nil || *found.Id != *user.Id {
+		t.Fatalf("SelectByUsername() = %+v, %v", found, err)
+	}
+
+	// 按条件更新后失效记录缓存
+	if err := repo.Lock(nil, *user.Id, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := repo.SelectById(nil, *user.Id); err != nil || found.LockedUntil == nil {
+		t.Fatalf("SelectById() = %+v, %v, want locked", found, err)
+	}
+	// 密码摘要不写入缓存，只能通过 SelectCredentials 读取
+	if found, err := repo.SelectById(nil, *user.Id); err != nil || found.Password != nil {
+		t.Fatalf("cached user should not carry the password: %+v, %v", found, err)
+	}
+	if found, err := repo.SelectCredentials(nil, *user.Id); err != nil || found.Password == nil {
+		t.Fatalf("SelectCredentials() = %+v, %v, want password", found, err)
+	}
+
+	// 改名后原用户名的映射不再生效
+	renamed := username + "_renamed"
+	if err := repo.Update(nil, &model.User{Id: user.Id, Username: &renamed}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SelectByUsername(nil, username); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("SelectByUsername(old) error = %v, want sql.ErrNoRows", err)
+	}
+	if found, err := repo.SelectByUsername(nil, renamed); err != nil || *found.Id != *user.Id {
+		t.Fatalf("SelectByUsername(renamed) = %+v, %v", found, err)
+	}
+
+	if err := repo.ForceDelete(nil, *user.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SelectById(nil, *user.Id); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("SelectById() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// laggingReplica 以主库当前的快照作为只读副本，之后主库的写入不会同步到副本，模拟延迟的副本
+func laggingReplica(t *testing.T) {
+	InitDbEnv()
+	path := filepath.Join(t.TempDir(), "replica.db")
+	if _, err := db.DB.Exec("VACUUM INTO ?", path); err != nil {
+		t.Fatal(err)
+	}
+	replicaDSNs := conf.DB.Replicas
+	conf.DB.Replicas = []string{path}
+	db.InitializeSqlite()
+	t.Cleanup(func() {
+		conf.DB.Replicas = replicaDSNs
+		db.InitializeSqlite()
+	})
+}
+
+func Test_UpdateWhereLaggingReplica(t *testing.T) {
+	InitDbEnv()
+	cache.Initialize()
+	defer cache.Close()
+	laggingReplica(t)
+	repo := NewUserRepo()
+	user := &model.User{Username: util.EnPointer("lag_" + util.RandString(8)), Password: util.EnPointer("password")}
+	if err := repo.Insert(nil, user); err != nil {
+		t.Fatal(err)
+	}
+	defer repo.ForceDelete(nil, *user.Id)
+	if users, err := repo.SelectWhere(nil, dbutil.Eq("id", *user.Id)); err != nil || len(users) != 0 {
+		t.Fatal("replica should lag behind the primary")
+	}
+
+	// 按条件更新时从主库取得主键，副本中没有的记录的缓存也被失效
+	if _, err := repo.SelectById(nil, *user.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Lock(nil, *user.Id, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := repo.SelectById(nil, *user.Id); err != nil || found.LockedUntil == nil {
+		t.Fatalf("SelectById() = %+v, %v, want locked", found, err)
+	}
+	// 密码摘要不写入缓存，只能通过 SelectCredentials 读取
+	if found, err := repo.SelectById(nil, *user.Id); err != nil || found.Password != nil {
+		t.Fatalf("cached user should not carry the password: %+v, %v", found, err)
+	}
+	if found, err := repo.SelectCredentials(nil, *user.Id); err != nil || found.Password == nil {
+		t.Fatalf("SelectCredentials() = %+v, %v, want password", found, err)
+	}
+}
@@ -25,6 +25,7 @@ func Initialize() {
 
 		tasks := []Task{
 			NewExampleTask(),
+			NewTokenCleanupTask(),
 		}
 		for _, task := range tasks {
 			task.Register(c)
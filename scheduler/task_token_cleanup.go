@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"app/conf"
+	"app/log"
+	"app/repo"
+	"app/util"
+	"app/util/collect"
+
+	"github.com/robfig/cron/v3"
+)
+
+// TokenCleanupTask 定时清理过期的刷新令牌、访问令牌黑名单与密码重置令牌
+type TokenCleanupTask struct {
+	refreshTokenRepo   repo.RefreshTokenRepo
+	tokenBlacklistRepo repo.TokenBlacklistRepo
+	passwordResetRepo  repo.PasswordResetRepo
+}
+
+func NewTokenCleanupTask() *TokenCleanupTask {
+	return &TokenCleanupTask{
+		refreshTokenRepo:   repo.NewRefreshTokenRepo(),
+		tokenBlacklistRepo: repo.NewTokenBlacklistRepo(),
+		passwordResetRepo:  repo.NewPasswordResetRepo(),
+	}
+}
+
+func (o *TokenCleanupTask) Name() string {
+	return "TokenCleanupTask"
+}
+
+func (o *TokenCleanupTask) Register(c *cron.Cron) {
+	isEnable := collect.Contains(conf.Scheduler.EnableTasks, func(task string) bool {
+		return task == o.Name()
+	})
+	if isEnable {
+		_, err := c.AddFunc("0 0 * * * *", func() {
+			o.Run()
+		})
+		if err != nil {
+			log.Error(err)
+			return
+		}
+	}
+}
+
+func (o *TokenCleanupTask) Run() {
+	rootCtx := util.NewRootContext()
+	refreshTokens, err := o.refreshTokenRepo.DeleteExpired(nil)
+	if err != nil {
+		log.T(rootCtx).Errorf("%s delete expired refresh tokens failed: %v", o.Name(), err)
+		return
+	}
+	blacklist, err := o.tokenBlacklistRepo.DeleteExpired(nil)
+	if err != nil {
+		log.T(rootCtx).Errorf("%s delete expired blacklist records failed: %v", o.Name(), err)
+		return
+	}
+	passwordResets, err := o.passwordResetRepo.DeleteExpired(nil)
+	if err != nil {
+		log.T(rootCtx).Errorf("%s delete expired password reset tokens failed: %v", o.Name(), err)
+		return
+	}
+	log.T(rootCtx).Infof("%s deleted %d expired refresh tokens, %d expired blacklist records, %d expired password reset tokens",
+		o.Name(), refreshTokens, blacklist, passwordResets)
+}
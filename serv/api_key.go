@@ -0,0 +1,154 @@
+package serv
+
+import (
+	"app/code"
+	"app/log"
+	"app/middleware"
+	"app/model"
+	"app/model/input"
+	"app/model/output"
+	"app/repo"
+	"app/util"
+	"app/util/copier"
+	"app/util/dbutil"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type apiKeyServ struct {
+	apiKeyRepo     repo.ApiKeyRepo
+	permissionRepo repo.PermissionRepo
+	userRepo       repo.UserRepo
+}
+
+func NewApiKeyService(apiKeyRepo repo.ApiKeyRepo, permissionRepo repo.PermissionRepo, userRepo repo.UserRepo) ApiKeyServ {
+	return &apiKeyServ{
+		apiKeyRepo:     apiKeyRepo,
+		permissionRepo: permissionRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// Select 当前用户的 API 密钥
+func (o *apiKeyServ) Select(c *fiber.Ctx) ([]output.ApiKeyOutput, error) {
+	principal := middleware.CurrentUser(c)
+	if principal == nil {
+		return nil, code.AuthFailed
+	}
+	return o.selectByUserId(c, principal.UserId)
+}
+
+// SelectByUserId 指定用户的 API 密钥
+func (o *apiKeyServ) SelectByUserId(c *fiber.Ctx, userId int) ([]output.ApiKeyOutput, error) {
+	if _, err := o.userRepo.SelectById(c, userId); err != nil {
+		return nil, err
+	}
+	return o.selectByUserId(c, userId)
+}
+
+// Insert 为当前用户签发 API 密钥，密钥明文只在此时返回
+func (o *apiKeyServ) Insert(c *fiber.Ctx, apiKeyInput *input.ApiKeyInput) (*output.ApiKeyCreated, error) {
+	principal := middleware.CurrentUser(c)
+	if principal == nil {
+		return nil, code.AuthFailed
+	}
+	// API 密钥不能再签发新的密钥
+	if principal.IsApiKey() {
+		return nil, code.PermissionDenied
+	}
+	if apiKeyInput.ExpiresAt != nil && !apiKeyInput.ExpiresAt.After(time.Now()) {
+		return nil, code.ParamError.WithDetails(map[string]string{"expiresAt": "must be in the future"})
+	}
+	scopes := slices.Compact(slices.Sorted(slices.Values(apiKeyInput.Scopes)))
+	if err := o.checkScopes(c, scopes); err != nil {
+		return nil, err
+	}
+	key, err := newApiKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey := &model.ApiKey{
+		Name:      apiKeyInput.Name,
+		KeyPrefix: util.EnPointer(key[:len(middleware.ApiKeyPrefix)+8]),
+		KeyHash:   util.EnPointer(middleware.HashApiKey(key)),
+		UserId:    &principal.UserId,
+		Scopes:    util.EnPointer(strings.Join(scopes, " ")),
+		ExpiresAt: apiKeyInput.ExpiresAt,
+	}
+	if err := o.apiKeyRepo.Insert(c, apiKey); err != nil {
+		return nil, err
+	}
+	apiKeyOutput, err := toApiKeyOutput(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &output.ApiKeyCreated{ApiKeyOutput: *apiKeyOutput, Key: key}, nil
+}
+
+// Revoke 吊销 API 密钥，只有密钥所属用户或拥有 apikey:manage 权限的用户可以吊销
+func (o *apiKeyServ) Revoke(c *fiber.Ctx, id int) error {
+	apiKey, err := o.apiKeyRepo.SelectById(c, id)
+	if err != nil {
+		return err
+	}
+	if err := authorizeOwner(c, *apiKey.UserId, "apikey:manage"); err != nil {
+		return err
+	}
+	revoked, err := o.apiKeyRepo.Revoke(c, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		log.F(c).Warnf("api key %d already revoked", id)
+	}
+	return nil
+}
+
+// checkScopes 授权范围必须是已存在的权限名称
+func (o *apiKeyServ) checkScopes(c *fiber.Ctx, scopes []string) error {
+	permissions, err := o.permissionRepo.SelectWhere(c, dbutil.In("name", scopes))
+	if err != nil {
+		return err
+	}
+	if len(permissions) != len(scopes) {
+		return code.ParamError.WithDetails(map[string]string{"scopes": "unknown permission"})
+	}
+	return nil
+}
+
+func (o *apiKeyServ) selectByUserId(c *fiber.Ctx, userId int) ([]output.ApiKeyOutput, error) {
+	apiKeys, err := o.apiKeyRepo.SelectByUserId(c, userId)
+	if err != nil {
+		return nil, err
+	}
+	apiKeyOutputs := make([]output.ApiKeyOutput, 0, len(apiKeys))
+	for i := range apiKeys {
+		apiKeyOutput, err := toApiKeyOutput(&apiKeys[i])
+		if err != nil {
+			return nil, err
+		}
+		apiKeyOutputs = append(apiKeyOutputs, *apiKeyOutput)
+	}
+	return apiKeyOutputs, nil
+}
+
+func toApiKeyOutput(apiKey *model.ApiKey) (*output.ApiKeyOutput, error) {
+	var apiKeyOutput output.ApiKeyOutput
+	if err := copier.CopyProperties(apiKey, &apiKeyOutput); err != nil {
+		return nil, err
+	}
+	apiKeyOutput.Scopes = apiKey.ScopeList()
+	return &apiKeyOutput, nil
+}
+
+// newApiKey 生成带固定前缀的随机 API 密钥
+func newApiKey() (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	return middleware.ApiKeyPrefix + token, nil
+}
@@ -0,0 +1,99 @@
+package serv
+
+import (
+	"app/code"
+	"app/middleware"
+	"app/model"
+	"app/model/input"
+	"app/repo"
+	"app/util"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func Test_ApiKey(t *testing.T) {
+	app, user := initEnv(t)
+	userRepo, roleRepo := repo.NewUserRepo(), repo.NewRoleRepo()
+	apiKeyServ := NewApiKeyService(repo.NewApiKeyRepo(), repo.NewPermissionRepo(), userRepo)
+	tokenServ := NewTokenService(userRepo, repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), roleRepo)
+	role, err := roleRepo.SelectByName(nil, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := roleRepo.SetUserRoles(nil, *user.Id, []int{*role.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := apiKeyServ.Insert(authCtx(t, app, tokenServ, user), &input.ApiKeyInput{
+		Name: util.EnPointer("bad"), Scopes: []string{"no:such"},
+	}); !errors.Is(err, code.ParamError) {
+		t.Fatalf("unknown scope should be rejected, got %v", err)
+	}
+	// 授权范围包含用户没有的 user:delete，实际权限为两者的交集
+	created, err := apiKeyServ.Insert(authCtx(t, app, tokenServ, user), &input.ApiKeyInput{
+		Name: util.EnPointer("ci"), Scopes: []string{"user:read", "user:delete", "user:read"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Key, middleware.ApiKeyPrefix) || !strings.HasPrefix(created.Key, *created.KeyPrefix) ||
+		len(created.Scopes) != 2 {
+		t.Fatalf("unexpected api key: %+v", created)
+	}
+
+	app.Use(middleware.TraceId(), middleware.ErrorParse())
+	app.Get("/read", middleware.Authenticate(), middleware.RequirePermission("user:read"), func(c *fiber.Ctx) error {
+		if p := model.PrincipalFrom(c.UserContext()); p == nil || p.UserId != *user.Id || p.ApiKeyId != *created.Id {
+			t.Errorf("unexpected principal: %+v", p)
+		}
+		return nil
+	})
+	app.Get("/delete", middleware.Authenticate(), middleware.RequirePermission("user:delete"), func(c *fiber.Ctx) error { return nil })
+	app.Get("/create", middleware.Authenticate(), middleware.RequirePermission("user:create"), func(c *fiber.Ctx) error { return nil })
+	request := func(path, key string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(middleware.ApiKeyHeader, key)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	if status := request("/read", created.Key); status != fiber.StatusOK {
+		t.Fatalf("user:read should be granted, got %d", status)
+	}
+	if status := request("/delete", created.Key); status != code.PermissionDenied.Status() {
+		t.Fatalf("user:delete should be denied without role permission, got %d", status)
+	}
+	if status := request("/create", created.Key); status != code.PermissionDenied.Status() {
+		t.Fatalf("user:create should be denied outside scopes, got %d", status)
+	}
+	if status := request("/read", created.Key+"x"); status != code.AuthFailed.Status() {
+		t.Fatalf("invalid key should fail, got %d", status)
+	}
+
+	keys, err := apiKeyServ.Select(authCtx(t, app, tokenServ, user))
+	if err != nil || len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Fatalf("unexpected api keys: %+v %v", keys, err)
+	}
+
+	// 其他用户不能吊销
+	other := &model.User{Username: util.EnPointer("apikey_" + util.RandString(8)), Password: util.EnPointer("password")}
+	if err := userRepo.Insert(nil, other); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *other.Id) })
+	if err := apiKeyServ.Revoke(authCtx(t, app, tokenServ, other), *created.Id); !errors.Is(err, code.PermissionDenied) {
+		t.Fatalf("other user should not revoke, got %v", err)
+	}
+	if err := apiKeyServ.Revoke(authCtx(t, app, tokenServ, user), *created.Id); err != nil {
+		t.Fatal(err)
+	}
+	if status := request("/read", created.Key); status != code.AuthFailed.Status() {
+		t.Fatalf("revoked key should fail, got %d", status)
+	}
+}
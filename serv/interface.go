@@ -4,6 +4,7 @@ import (
 	"app/model"
 	"app/model/input"
 	"app/model/output"
+	"app/util/dbutil"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -14,9 +15,58 @@ type UserServ interface {
 	Insert(*fiber.Ctx, *model.User) error
 	Delete(*fiber.Ctx, int) error
 	Update(*fiber.Ctx, *model.User) error
-	Select(*fiber.Ctx, *input.UserFilter) ([]output.UserOutput, error)
+	Select(*fiber.Ctx, *input.UserFilter, *dbutil.ListOptions) ([]output.UserOutput, error)
 	SelectById(*fiber.Ctx, int) (*output.UserOutput, error)
-	SelectWithPagination(*fiber.Ctx, *model.Pagination) error
-	Login(*fiber.Ctx, *input.UserLogin) (string, error)
+	Me(*fiber.Ctx) (*output.MeOutput, error)
+	SelectWithPagination(*fiber.Ctx, *model.Pagination, *dbutil.ListOptions) error
+	SelectWithCursor(*fiber.Ctx, *input.UserFilter, *dbutil.ListOptions, *model.CursorPagination) error
+	SelectTrashed(*fiber.Ctx, *dbutil.ListOptions) ([]output.UserOutput, error)
+	Restore(*fiber.Ctx, int) error
+	Login(*fiber.Ctx, *input.UserLogin) (*output.TokenOutput, error)
+	LoginMfa(*fiber.Ctx, *input.MfaLogin) (*output.TokenOutput, error)
+	Unlock(*fiber.Ctx, int) error
 	Register(*fiber.Ctx, *input.UserRegister) error
 }
+
+type PasswordServ interface {
+	Change(*fiber.Ctx, *input.PasswordChange) error
+	Forgot(*fiber.Ctx, *input.PasswordForgot) error
+	Reset(*fiber.Ctx, *input.PasswordReset) error
+}
+
+type TokenServ interface {
+	Issue(*fiber.Ctx, *model.User) (*output.TokenOutput, error)
+	Refresh(*fiber.Ctx, string) (*output.TokenOutput, error)
+	Logout(*fiber.Ctx, *string) error
+}
+
+type RoleServ interface {
+	Select(*fiber.Ctx) ([]output.RoleOutput, error)
+	SelectPermissions(*fiber.Ctx) ([]output.PermissionOutput, error)
+	Insert(*fiber.Ctx, *input.RoleInput) error
+	Update(*fiber.Ctx, int, *input.RoleInput) error
+	Delete(*fiber.Ctx, int) error
+	SelectUserRoles(*fiber.Ctx, int) ([]output.RoleOutput, error)
+	SetUserRoles(*fiber.Ctx, int, []int) error
+}
+
+type ApiKeyServ interface {
+	Select(*fiber.Ctx) ([]output.ApiKeyOutput, error)
+	SelectByUserId(*fiber.Ctx, int) ([]output.ApiKeyOutput, error)
+	Insert(*fiber.Ctx, *input.ApiKeyInput) (*output.ApiKeyCreated, error)
+	Revoke(*fiber.Ctx, int) error
+}
+
+type OidcServ interface {
+	AuthUrl(*fiber.Ctx, string, bool) (string, error)
+	Callback(*fiber.Ctx, string, *input.OidcCallback) (*output.TokenOutput, error)
+}
+
+type MfaServ interface {
+	Status(*fiber.Ctx) (*output.MfaStatus, error)
+	Enroll(*fiber.Ctx) (*output.TotpEnrollment, error)
+	Confirm(*fiber.Ctx, *input.MfaCode) (*output.RecoveryCodes, error)
+	Disable(*fiber.Ctx, *input.MfaCode) error
+	RegenerateRecoveryCodes(*fiber.Ctx, *input.MfaCode) (*output.RecoveryCodes, error)
+	Verify(*fiber.Ctx, *model.User, string) (bool, error)
+}
@@ -0,0 +1,89 @@
+package serv
+
+import (
+	"app/code"
+	"app/conf"
+	"app/log"
+	"app/model"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func userAttemptKey(username string) string {
+	return "user:" + username
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkIpAllowed 同一 IP 在统计窗口内失败次数超过 login.maxIpAttempts 时拒绝登录
+func (o *userServ) checkIpAllowed(c *fiber.Ctx) error {
+	if conf.Login.MaxIpAttempts <= 0 {
+		return nil
+	}
+	n, err := o.attemptRepo.Get(c, ipAttemptKey(c.IP()))
+	if err != nil {
+		// 计数不可用时不阻止登录
+		log.F(c).Error(err)
+		return nil
+	}
+	if n >= conf.Login.MaxIpAttempts {
+		log.F(c).Warnf("too many failed login attempts from ip %s", c.IP())
+		return code.TooManyAttempts
+	}
+	return nil
+}
+
+// loginFailed 记录一次失败：通过 Retry-After 响应头告知客户端按失败次数递增的等待时间，同一账户失败次数达到 login.maxAttempts 时锁定账户
+// 账户不存在、已锁定或本次失败导致锁定时均返回 UsernameOrPasswordFailed，避免通过响应区分账户是否存在或已锁定
+func (o *userServ) loginFailed(c *fiber.Ctx, username string, user *model.User) error {
+	window := time.Duration(conf.Login.AttemptWindow) * time.Second
+	if _, err := o.attemptRepo.Incr(c, ipAttemptKey(c.IP()), window); err != nil {
+		log.F(c).Error(err)
+	}
+	n, err := o.attemptRepo.Incr(c, userAttemptKey(username), window)
+	if err != nil {
+		log.F(c).Error(err)
+		return code.UsernameOrPasswordFailed
+	}
+	if delay := loginDelay(n); delay > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	}
+	if user == nil || conf.Login.MaxAttempts <= 0 || n < conf.Login.MaxAttempts {
+		return code.UsernameOrPasswordFailed
+	}
+	until := time.Now().Add(time.Duration(conf.Login.LockDuration) * time.Second)
+	if err := o.userRepo.Lock(c, *user.Id, until); err != nil {
+		return err
+	}
+	if err := o.attemptRepo.Reset(c, userAttemptKey(username)); err != nil {
+		log.F(c).Error(err)
+	}
+	log.F(c).Warnf("user %s locked until %s after %d failed login attempts", username, until.Format(time.DateTime), n)
+	return code.UsernameOrPasswordFailed
+}
+
+// loginDelay 第 n 次失败后建议的等待时间：delayBase * 2^(n-1)，不超过 delayMax
+func loginDelay(n int) time.Duration {
+	if n <= 0 || conf.Login.DelayBase <= 0 {
+		return 0
+	}
+	delay := time.Duration(conf.Login.DelayBase) * time.Millisecond
+	max := time.Duration(conf.Login.DelayMax) * time.Millisecond
+	for i := 1; i < n && (max <= 0 || delay < max); i++ {
+		delay *= 2
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// lockedError 账户已锁定，只在凭据校验通过后返回
+func lockedError(until time.Time) error {
+	return code.AccountLocked.WithDetails(map[string]any{"lockedUntil": until})
+}
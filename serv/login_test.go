@@ -0,0 +1,129 @@
+package serv
+
+import (
+	"app/cache"
+	"app/code"
+	"app/conf"
+	"app/db"
+	"app/model"
+	"app/model/input"
+	"app/repo"
+	"app/util"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func Test_LoginLockout(t *testing.T) {
+	app, _ := initEnv(t)
+	login := conf.Login
+	conf.Login = conf.LoginConf{MaxAttempts: 3, LockDuration: 60, AttemptWindow: 60, MaxIpAttempts: 100, DelayBase: 1000, DelayMax: 1500}
+	t.Cleanup(func() { conf.Login = login })
+
+	userRepo, roleRepo := repo.NewUserRepo(), repo.NewRoleRepo()
+	tokenServ := NewTokenService(userRepo, repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), roleRepo)
+	userServ := NewUserService(userRepo, roleRepo, repo.NewLoginAttemptRepo(), NewMfaService(userRepo, repo.NewRecoveryCodeRepo()), tokenServ)
+	user := &model.User{
+		Username: util.EnPointer("lock_" + util.RandString(8)),
+		Password: util.EnPointer("password"),
+	}
+	if err := userServ.Insert(newCtx(app), user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *user.Id) })
+
+	wrong := &input.UserLogin{Username: user.Username, Password: util.EnPointer("wrong")}
+	right := &input.UserLogin{Username: user.Username, Password: util.EnPointer("password")}
+	// 失败后不阻塞，通过 Retry-After 告知客户端递增的等待时间 (秒，向上取整)
+	var retryAfter []string
+	for i := 0; i < 3; i++ {
+		c := newCtx(app)
+		if _, err := userServ.Login(c, wrong); !errors.Is(err, code.UsernameOrPasswordFailed) {
+			t.Fatalf("wrong password should fail, got %v", err)
+		}
+		retryAfter = append(retryAfter, string(c.Response().Header.Peek(fiber.HeaderRetryAfter)))
+	}
+	if !slices.Equal(retryAfter, []string{"1", "2", "2"}) {
+		t.Fatalf("unexpected progressive retry after: %v", retryAfter)
+	}
+	locked, err := userRepo.SelectCredentials(nil, *user.Id)
+	if err != nil || locked.LockedUntil == nil || !locked.LockedUntil.After(time.Now()) {
+		t.Fatalf("account should be locked after 3 failures: %+v %v", locked, err)
+	}
+	// 锁定状态只在密码正确后返回，密码错误时与账户不存在的响应相同
+	if _, err := userServ.Login(newCtx(app), wrong); !errors.Is(err, code.UsernameOrPasswordFailed) {
+		t.Fatalf("locked account with wrong password should not reveal the lock, got %v", err)
+	}
+	if _, err := userServ.Login(newCtx(app), right); !errors.Is(err, code.AccountLocked) {
+		t.Fatalf("locked account should reject correct password, got %v", err)
+	}
+
+	if err := userServ.Unlock(newCtx(app), *user.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userServ.Login(newCtx(app), right); err != nil {
+		t.Fatalf("unlocked account should login, got %v", err)
+	}
+}
+
+func Test_LoginIpLimit(t *testing.T) {
+	app, _ := initEnv(t)
+	login := conf.Login
+	conf.Login = conf.LoginConf{MaxAttempts: 100, AttemptWindow: 60, MaxIpAttempts: 2}
+	t.Cleanup(func() { conf.Login = login })
+
+	userRepo, roleRepo := repo.NewUserRepo(), repo.NewRoleRepo()
+	userServ := NewUserService(userRepo, roleRepo, repo.NewLoginAttemptRepo(), NewMfaService(userRepo, repo.NewRecoveryCodeRepo()), nil)
+	// 不存在的账户同样计入 IP 失败次数
+	for _, name := range []string{"nobody_1", "nobody_2"} {
+		login := &input.UserLogin{Username: util.EnPointer(name + util.RandString(8)), Password: util.EnPointer("x")}
+		if _, err := userServ.Login(newCtx(app), login); !errors.Is(err, code.UsernameOrPasswordFailed) {
+			t.Fatalf("unknown user should fail, got %v", err)
+		}
+	}
+	login3 := &input.UserLogin{Username: util.EnPointer("nobody_3"), Password: util.EnPointer("x")}
+	if _, err := userServ.Login(newCtx(app), login3); !errors.Is(err, code.TooManyAttempts) {
+		t.Fatalf("ip should be throttled, got %v", err)
+	}
+}
+
+func Test_LoginSkipsUserCache(t *testing.T) {
+	app, _ := initEnv(t)
+	cache.Initialize()
+	t.Cleanup(func() { _ = cache.Close() })
+	login := conf.Login
+	conf.Login = conf.LoginConf{MaxAttempts: 100, AttemptWindow: 60, MaxIpAttempts: 100}
+	t.Cleanup(func() { conf.Login = login })
+
+	userRepo, roleRepo := repo.NewUserRepo(), repo.NewRoleRepo()
+	tokenServ := NewTokenService(userRepo, repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), roleRepo)
+	userServ := NewUserService(userRepo, roleRepo, repo.NewLoginAttemptRepo(), NewMfaService(userRepo, repo.NewRecoveryCodeRepo()), tokenServ)
+	user := &model.User{
+		Username: util.EnPointer("stale_" + util.RandString(8)),
+		Password: util.EnPointer("password"),
+	}
+	if err := userServ.Insert(newCtx(app), user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *user.Id) })
+
+	// 模拟其他实例修改密码后本实例的缓存未失效
+	if _, err := userRepo.SelectByUsername(nil, *user.Username); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hashPassword(newCtx(app), "changed-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.DB.MustExec(`UPDATE "user" SET password = ? WHERE id = ?`, hash, *user.Id)
+
+	if _, err := userServ.Login(newCtx(app), &input.UserLogin{Username: user.Username, Password: util.EnPointer("changed-password")}); err != nil {
+		t.Fatalf("login should check the password on the primary, got %v", err)
+	}
+	if _, err := userServ.Login(newCtx(app), &input.UserLogin{Username: user.Username, Password: util.EnPointer("password")}); !errors.Is(err, code.UsernameOrPasswordFailed) {
+		t.Fatalf("old password should be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package serv
+
+import (
+	"app/code"
+	"app/conf"
+	"app/db"
+	"app/log"
+	"app/middleware"
+	"app/model"
+	"app/model/input"
+	"app/model/output"
+	"app/repo"
+	"app/util"
+	"app/util/cryptoutil"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	// totpPeriod TOTP 时间步长（秒）
+	totpPeriod = 30
+	// totpSkew 允许前后各偏差的时间步数，容忍客户端时钟误差
+	totpSkew = 1
+)
+
+// MfaRecoveryCodes 未配置 mfa.recoveryCodes 时生成的恢复码数量
+var MfaRecoveryCodes = 10
+
+type mfaServ struct {
+	userRepo         repo.UserRepo
+	recoveryCodeRepo repo.RecoveryCodeRepo
+}
+
+func NewMfaService(userRepo repo.UserRepo, recoveryCodeRepo repo.RecoveryCodeRepo) MfaServ {
+	return &mfaServ{
+		userRepo:         userRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+	}
+}
+
+// Status 当前用户的两步验证状态
+func (o *mfaServ) Status(c *fiber.Ctx) (*output.MfaStatus, error) {
+	user, err := o.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	status := &output.MfaStatus{Enabled: user.MfaEnabled(), EnabledAt: user.TotpEnabledAt}
+	if status.Enabled {
+		if status.RecoveryCodesRemaining, err = o.recoveryCodeRepo.CountUnused(c, *user.Id); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Enroll 生成新的 TOTP 密钥，确认前处于待启用状态，重复调用会替换待启用的密钥
+func (o *mfaServ) Enroll(c *fiber.Ctx) (*output.TotpEnrollment, error) {
+	user, err := o.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	if user.MfaEnabled() {
+		return nil, code.Conflict
+	}
+	issuer := conf.Mfa.Issuer
+	if issuer == "" {
+		issuer = conf.AppName
+	}
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: issuer, AccountName: *user.Username, Period: totpPeriod})
+	if err != nil {
+		return nil, err
+	}
+	secret, err := cryptoutil.Encrypt(totpEncryptionKey(), key.Secret())
+	if err != nil {
+		return nil, err
+	}
+	if err := o.userRepo.SetTotp(c, *user.Id, &secret, nil); err != nil {
+		return nil, err
+	}
+	return &output.TotpEnrollment{Secret: key.Secret(), OtpauthUri: key.URL()}, nil
+}
+
+// Confirm 使用认证器中的验证码确认待启用的密钥，启用两步验证并生成恢复码
+func (o *mfaServ) Confirm(c *fiber.Ctx, in *input.MfaCode) (*output.RecoveryCodes, error) {
+	user, err := o.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	if user.MfaEnabled() || user.TotpSecret == nil {
+		return nil, code.Conflict
+	}
+	step, ok := verifyTotp(c, user, in.Code)
+	if !ok {
+		return nil, code.MfaCodeInvalid
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		if err := o.userRepo.SetTotp(c, *user.Id, user.TotpSecret, util.EnPointer(time.Now())); err != nil {
+			return err
+		}
+		// 确认用的验证码不能再用于登录
+		if _, err := o.userRepo.UseTotpStep(c, *user.Id, step); err != nil {
+			return err
+		}
+		return o.recoveryCodeRepo.Replace(c, *user.Id, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.F(c).Infof("user %d enabled two-factor authentication", *user.Id)
+	return &output.RecoveryCodes{Codes: codes}, nil
+}
+
+// Disable 关闭两步验证并删除恢复码，已启用时需要验证码或恢复码；未启用时取消待启用的密钥
+func (o *mfaServ) Disable(c *fiber.Ctx, in *input.MfaCode) error {
+	user, err := o.currentUser(c)
+	if err != nil {
+		return err
+	}
+	if user.MfaEnabled() {
+		ok, err := o.Verify(c, user, in.Code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return code.MfaCodeInvalid
+		}
+	}
+	err = db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		if err := o.userRepo.SetTotp(c, *user.Id, nil, nil); err != nil {
+			return err
+		}
+		return o.recoveryCodeRepo.DeleteByUserId(c, *user.Id)
+	})
+	if err != nil {
+		return err
+	}
+	log.F(c).Infof("user %d disabled two-factor authentication", *user.Id)
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，原有的恢复码全部失效
+func (o *mfaServ) RegenerateRecoveryCodes(c *fiber.Ctx, in *input.MfaCode) (*output.RecoveryCodes, error) {
+	user, err := o.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MfaEnabled() {
+		return nil, code.Conflict
+	}
+	ok, err := o.Verify(c, user, in.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, code.MfaCodeInvalid
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		return o.recoveryCodeRepo.Replace(c, *user.Id, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &output.RecoveryCodes{Codes: codes}, nil
+}
+
+// Verify 校验第二因素：6 位数字按 TOTP 验证码校验，同一时间步只能使用一次；其他按恢复码校验，使用后失效
+// user 需通过 SelectCredentials 获取
+func (o *mfaServ) Verify(c *fiber.Ctx, user *model.User, passcode string) (bool, error) {
+	normalized := normalizeRecoveryCode(passcode)
+	if len(normalized) == 6 && strings.Trim(normalized, "0123456789") == "" {
+		step, ok := verifyTotp(c, user, normalized)
+		if !ok {
+			return false, nil
+		}
+		return o.userRepo.UseTotpStep(c, *user.Id, step)
+	}
+	return o.recoveryCodeRepo.Use(c, *user.Id, hashToken(normalized))
+}
+
+// currentUser 当前登录用户，直接查库以获取 TOTP 密钥
+func (o *mfaServ) currentUser(c *fiber.Ctx) (*model.User, error) {
+	principal := middleware.CurrentUser(c)
+	if principal == nil {
+		return nil, code.AuthFailed
+	}
+	return o.userRepo.SelectCredentials(c, principal.UserId)
+}
+
+// verifyTotp 在允许的时钟偏差内校验验证码，返回匹配的时间步，晚于已使用时间步的才有效
+func verifyTotp(c *fiber.Ctx, user *model.User, passcode string) (int64, bool) {
+	if user.TotpSecret == nil || len(passcode) != 6 {
+		return 0, false
+	}
+	secret, err := cryptoutil.Decrypt(totpEncryptionKey(), *user.TotpSecret)
+	if err != nil {
+		// mfa.encryptionKey 变更后原有密钥无法解密，只能使用恢复码
+		log.F(c).Errorf("decrypt totp secret of user %d: %v", *user.Id, err)
+		return 0, false
+	}
+	current := time.Now().Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if user.TotpLastStep != nil && step <= *user.TotpLastStep {
+			continue
+		}
+		expected, err := hotp.GenerateCodeCustom(secret, uint64(step), hotp.ValidateOpts{Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1})
+		if err != nil {
+			log.F(c).Error(err)
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(passcode)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// generateRecoveryCodes 生成恢复码明文与摘要，明文格式为 xxxxx-xxxxx
+func generateRecoveryCodes() (codes []string, hashes []string, err error) {
+	n := conf.Mfa.RecoveryCodes
+	if n <= 0 {
+		n = MfaRecoveryCodes
+	}
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for range n {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		s := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes = append(codes, s[:5]+"-"+s[5:])
+		hashes = append(hashes, hashToken(s))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 忽略大小写、连字符与空白
+func normalizeRecoveryCode(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(s))
+}
+
+// totpEncryptionKey 加密 TOTP 密钥的密钥，未配置 mfa.encryptionKey 时使用 server.secret
+func totpEncryptionKey() string {
+	if conf.Mfa.EncryptionKey != "" {
+		return conf.Mfa.EncryptionKey
+	}
+	return conf.Server.Secret
+}
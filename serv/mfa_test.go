@@ -0,0 +1,106 @@
+package serv
+
+import (
+	"app/code"
+	"app/conf"
+	"app/model"
+	"app/model/input"
+	"app/repo"
+	"app/util"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func Test_MfaLogin(t *testing.T) {
+	app, _ := initEnv(t)
+	login := conf.Login
+	conf.Login = conf.LoginConf{MaxAttempts: 100, AttemptWindow: 60, MaxIpAttempts: 100}
+	t.Cleanup(func() { conf.Login = login })
+
+	userRepo, roleRepo, recoveryCodeRepo := repo.NewUserRepo(), repo.NewRoleRepo(), repo.NewRecoveryCodeRepo()
+	tokenServ := NewTokenService(userRepo, repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), roleRepo)
+	mfaServ := NewMfaService(userRepo, recoveryCodeRepo)
+	userServ := NewUserService(userRepo, roleRepo, repo.NewLoginAttemptRepo(), mfaServ, tokenServ)
+	user := &model.User{
+		Username: util.EnPointer("mfa_" + util.RandString(8)),
+		Password: util.EnPointer("password"),
+	}
+	if err := userServ.Insert(newCtx(app), user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = recoveryCodeRepo.DeleteByUserId(nil, *user.Id)
+		_ = userRepo.ForceDelete(nil, *user.Id)
+	})
+	credentials := &input.UserLogin{Username: user.Username, Password: util.EnPointer("password")}
+
+	// 确认前两步验证不生效
+	enrollment, err := mfaServ.Enroll(authCtx(t, app, tokenServ, user))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(enrollment.OtpauthUri, "otpauth://totp/") {
+		t.Fatalf("unexpected otpauth uri: %s", enrollment.OtpauthUri)
+	}
+	if tokens, err := userServ.Login(newCtx(app), credentials); err != nil || tokens.AccessToken == "" {
+		t.Fatalf("pending enrollment should not require mfa: %v", err)
+	}
+	if _, err := mfaServ.Confirm(authCtx(t, app, tokenServ, user), &input.MfaCode{Code: "000000x"}); !errors.Is(err, code.MfaCodeInvalid) {
+		t.Fatalf("wrong code should not confirm, got %v", err)
+	}
+	now := time.Now()
+	passcode, _ := totp.GenerateCode(enrollment.Secret, now)
+	recovery, err := mfaServ.Confirm(authCtx(t, app, tokenServ, user), &input.MfaCode{Code: passcode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovery.Codes) != MfaRecoveryCodes {
+		t.Fatalf("expected %d recovery codes, got %d", MfaRecoveryCodes, len(recovery.Codes))
+	}
+
+	// 密码正确时只返回两步验证登录令牌
+	challenge, err := userServ.Login(newCtx(app), credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge.MfaToken == "" || challenge.AccessToken != "" {
+		t.Fatalf("login should return only an mfa token: %+v", challenge)
+	}
+	if _, err := userServ.LoginMfa(newCtx(app), &input.MfaLogin{MfaToken: challenge.MfaToken, Code: passcode}); !errors.Is(err, code.MfaCodeInvalid) {
+		t.Fatalf("code used for confirmation should not be replayed, got %v", err)
+	}
+	next, _ := totp.GenerateCode(enrollment.Secret, now.Add(30*time.Second))
+	tokens, err := userServ.LoginMfa(newCtx(app), &input.MfaLogin{MfaToken: challenge.MfaToken, Code: next})
+	if err != nil || tokenUserId(t, tokens) != *user.Id {
+		t.Fatalf("valid code should complete login: %v", err)
+	}
+	if _, err := userServ.LoginMfa(newCtx(app), &input.MfaLogin{MfaToken: challenge.MfaToken, Code: next}); !errors.Is(err, code.MfaCodeInvalid) {
+		t.Fatalf("code should be used only once, got %v", err)
+	}
+	if _, err := userServ.LoginMfa(newCtx(app), &input.MfaLogin{MfaToken: tokens.AccessToken, Code: next}); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("access token should not be accepted as mfa token, got %v", err)
+	}
+
+	// 恢复码忽略大小写，只能使用一次
+	if _, err := userServ.LoginMfa(newCtx(app), &input.MfaLogin{MfaToken: challenge.MfaToken, Code: strings.ToUpper(recovery.Codes[0])}); err != nil {
+		t.Fatalf("recovery code should complete login: %v", err)
+	}
+	if _, err := userServ.LoginMfa(newCtx(app), &input.MfaLogin{MfaToken: challenge.MfaToken, Code: recovery.Codes[0]}); !errors.Is(err, code.MfaCodeInvalid) {
+		t.Fatalf("recovery code should be used only once, got %v", err)
+	}
+	status, err := mfaServ.Status(authCtx(t, app, tokenServ, user))
+	if err != nil || !status.Enabled || status.RecoveryCodesRemaining != MfaRecoveryCodes-1 {
+		t.Fatalf("unexpected mfa status: %+v %v", status, err)
+	}
+
+	if err := mfaServ.Disable(authCtx(t, app, tokenServ, user), &input.MfaCode{Code: recovery.Codes[1]}); err != nil {
+		t.Fatal(err)
+	}
+	if tokens, err := userServ.Login(newCtx(app), credentials); err != nil || tokens.AccessToken == "" {
+		t.Fatalf("disabled mfa should not be required: %v", err)
+	}
+}
@@ -0,0 +1,341 @@
+package serv
+
+import (
+	"app/code"
+	"app/conf"
+	"app/db"
+	"app/log"
+	"app/middleware"
+	"app/model"
+	"app/model/input"
+	"app/model/output"
+	"app/repo"
+	"app/util"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// OidcStateExpireTime 未配置 oidc.stateExpire 时授权请求的默认有效期
+	OidcStateExpireTime = 10 * time.Minute
+	// OidcDiscoveryTimeout 获取身份提供方发现文档的超时时间
+	OidcDiscoveryTimeout = 10 * time.Second
+)
+
+// oidcBindingCookie 将 state 绑定到发起授权的浏览器的 Cookie
+const oidcBindingCookie = "oidc_binding"
+
+// oidcClient 身份提供方的客户端，首次使用时通过发现文档初始化
+type oidcClient struct {
+	conf     conf.OidcProviderConf
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// oidcClaims ID Token 中用于创建用户的声明
+type oidcClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type oidcServ struct {
+	userRepo     repo.UserRepo
+	identityRepo repo.UserIdentityRepo
+	stateRepo    repo.OidcStateRepo
+	userServ     UserServ
+	tokenServ    TokenServ
+
+	mu      sync.Mutex
+	clients map[string]*oidcClient
+	group   singleflight.Group
+}
+
+func NewOidcService(userRepo repo.UserRepo, identityRepo repo.UserIdentityRepo, stateRepo repo.OidcStateRepo, userServ UserServ, tokenServ TokenServ) OidcServ {
+	return &oidcServ{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		stateRepo:    stateRepo,
+		userServ:     userServ,
+		tokenServ:    tokenServ,
+		clients:      map[string]*oidcClient{},
+	}
+}
+
+// AuthUrl 生成身份提供方的授权地址，link 为 true 时回调后将外部账户关联到当前用户
+// state 与 nonce 保存在服务端，PKCE code_verifier 不离开服务端；state 通过 Cookie 绑定到当前浏览器
+func (o *oidcServ) AuthUrl(c *fiber.Ctx, provider string, link bool) (string, error) {
+	client, err := o.client(c, provider)
+	if err != nil {
+		return "", err
+	}
+	s := &model.OidcState{Provider: provider, Verifier: oauth2.GenerateVerifier()}
+	if link {
+		principal := middleware.CurrentUser(c)
+		if principal == nil {
+			return "", code.AuthFailed
+		}
+		s.LinkUserId = principal.UserId
+	}
+	if s.Nonce, err = randomToken(); err != nil {
+		return "", err
+	}
+	binding, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	s.Binding = hashToken(binding)
+	state, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	if err := o.stateRepo.Save(c, state, s, oidcStateExpire()); err != nil {
+		return "", err
+	}
+	// 身份提供方跳转回来是跨站的顶级导航，SameSite=Lax 时仍会携带
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcBindingCookie,
+		Value:    binding,
+		Path:     "/",
+		Expires:  time.Now().Add(oidcStateExpire()),
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return client.oauth2.AuthCodeURL(state, oidc.Nonce(s.Nonce), oauth2.S256ChallengeOption(s.Verifier)), nil
+}
+
+// Callback 校验 state、用授权码换取并校验 ID Token，登录已关联的用户、关联当前用户或自动创建用户，然后签发令牌或返回两步验证登录令牌
+// state 只能由发起授权的浏览器使用，否则他人的授权结果可能登录或关联到攻击者的账户
+func (o *oidcServ) Callback(c *fiber.Ctx, provider string, callback *input.OidcCallback) (*output.TokenOutput, error) {
+	binding := c.Cookies(oidcBindingCookie)
+	if binding == "" {
+		log.F(c).Warnf("oidc callback for provider %s without binding cookie", provider)
+		return nil, code.AuthFailed
+	}
+	c.Cookie(&fiber.Cookie{Name: oidcBindingCookie, Path: "/", Expires: time.Unix(0, 0), HTTPOnly: true, SameSite: fiber.CookieSameSiteLaxMode})
+	s, err := o.stateRepo.Take(c, callback.State)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil || s.Provider != provider || subtle.ConstantTimeCompare([]byte(s.Binding), []byte(hashToken(binding))) != 1 {
+		log.F(c).Warnf("invalid oidc state for provider %s", provider)
+		return nil, code.AuthFailed
+	}
+	if callback.Error != "" {
+		log.F(c).Warnf("oidc provider %s returned error: %s %s", provider, callback.Error, callback.ErrorDescription)
+		return nil, code.AuthFailed.WithDetails(map[string]string{"error": callback.Error})
+	}
+	client, err := o.client(c, provider)
+	if err != nil {
+		return nil, err
+	}
+	token, err := client.oauth2.Exchange(c.UserContext(), callback.Code, oauth2.VerifierOption(s.Verifier))
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			return nil, code.AuthFailed.Wrap(err)
+		}
+		return nil, code.ExternalError.Wrap(err)
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, code.AuthFailed.Wrap(errors.New("oidc: id_token missing from token response"))
+	}
+	idToken, err := client.verifier.Verify(c.UserContext(), rawIdToken)
+	if err != nil {
+		return nil, code.AuthFailed.Wrap(err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(s.Nonce)) != 1 {
+		return nil, code.AuthFailed.Wrap(errors.New("oidc: nonce mismatch"))
+	}
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, code.AuthFailed.Wrap(err)
+	}
+	user, err := o.resolveUser(c, client.conf, idToken.Subject, &claims, s.LinkUserId)
+	if err != nil {
+		return nil, err
+	}
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return nil, lockedError(*user.LockedUntil)
+	}
+	// 与密码登录相同，已启用两步验证的用户需要再通过 /login/mfa 验证
+	return issueOrChallenge(c, o.tokenServ, user)
+}
+
+// resolveUser 按外部账户查找已关联的用户，未关联时关联到 linkUserId 或按 autoProvision 创建用户
+func (o *oidcServ) resolveUser(c *fiber.Ctx, provider conf.OidcProviderConf, subject string, claims *oidcClaims, linkUserId int) (*model.User, error) {
+	identity, err := o.identityRepo.SelectBySubject(c, provider.Name, subject)
+	if err == nil {
+		if linkUserId != 0 && linkUserId != *identity.UserId {
+			// 外部账户已关联其他用户
+			return nil, code.Conflict
+		}
+		// 锁定状态与两步验证状态不经过缓存读取
+		user, err := o.userRepo.SelectCredentials(c, *identity.UserId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, code.AuthFailed
+		}
+		return user, err
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	identity = &model.UserIdentity{Provider: &provider.Name, Subject: &subject}
+	if claims.Email != "" {
+		identity.Email = &claims.Email
+	}
+	if linkUserId != 0 {
+		user, err := o.userRepo.SelectCredentials(c, linkUserId)
+		if err != nil {
+			return nil, err
+		}
+		identity.UserId = user.Id
+		return user, o.identityRepo.Insert(c, identity)
+	}
+	if !provider.AutoProvision {
+		return nil, code.IdentityNotLinked
+	}
+	username, err := o.provisionUsername(c, provider.Name, subject, claims)
+	if err != nil {
+		return nil, err
+	}
+	// 自动创建的用户没有可用的密码，只能通过身份提供方登录或重置密码
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+	user := &model.User{Username: &username, Password: &password}
+	err = db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		if err := o.userServ.Insert(c, user); err != nil {
+			return err
+		}
+		identity.UserId = user.Id
+		return o.identityRepo.Insert(c, identity)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.F(c).Infof("provisioned user %d from oidc provider %s", *user.Id, provider.Name)
+	return user, nil
+}
+
+// usernameInvalidChars 用户账户中不允许的字符
+var usernameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// provisionUsername 依次使用 preferred_username、已验证邮箱的前缀、<provider>_<sub> 作为用户账户，已被占用时追加随机后缀
+func (o *oidcServ) provisionUsername(c *fiber.Ctx, provider, subject string, claims *oidcClaims) (string, error) {
+	candidate := claims.PreferredUsername
+	if candidate == "" && claims.EmailVerified {
+		candidate, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if candidate == "" {
+		candidate = provider + "_" + subject
+	}
+	candidate = usernameInvalidChars.ReplaceAllString(candidate, "_")
+	candidate = candidate[:min(len(candidate), 24)]
+	for len(candidate) < 3 {
+		candidate += "_"
+	}
+	username := candidate
+	for range 5 {
+		_, err := o.userRepo.SelectByUsername(c, username)
+		if errors.Is(err, sql.ErrNoRows) {
+			return username, nil
+		} else if err != nil {
+			return "", err
+		}
+		username = candidate + "_" + util.RandString(6)
+	}
+	return "", code.Conflict
+}
+
+// client 返回身份提供方的客户端，配置变化后重新初始化，发现文档获取失败时不缓存
+// 发现文档在锁外获取，同一身份提供方并发初始化时只请求一次，不可用的身份提供方不影响其他身份提供方
+func (o *oidcServ) client(c *fiber.Ctx, name string) (*oidcClient, error) {
+	var providerConf *conf.OidcProviderConf
+	for i := range conf.Oidc.Providers {
+		if conf.Oidc.Providers[i].Name == name {
+			providerConf = &conf.Oidc.Providers[i]
+			break
+		}
+	}
+	if providerConf == nil {
+		return nil, code.NotFound
+	}
+	if client := o.cachedClient(name, providerConf); client != nil {
+		return client, nil
+	}
+	result, err, _ := o.group.Do(name, func() (any, error) {
+		if client := o.cachedClient(name, providerConf); client != nil {
+			return client, nil
+		}
+		client, err := newOidcClient(c, *providerConf)
+		if err != nil {
+			return nil, err
+		}
+		o.mu.Lock()
+		o.clients[name] = client
+		o.mu.Unlock()
+		return client, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*oidcClient), nil
+}
+
+// cachedClient 返回配置未变化的已初始化客户端，没有时返回 nil
+func (o *oidcServ) cachedClient(name string, providerConf *conf.OidcProviderConf) *oidcClient {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if client, ok := o.clients[name]; ok && reflect.DeepEqual(client.conf, *providerConf) {
+		return client
+	}
+	return nil
+}
+
+// newOidcClient 通过发现文档初始化客户端，不随单个请求取消
+func newOidcClient(c *fiber.Ctx, providerConf conf.OidcProviderConf) (*oidcClient, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.UserContext()), OidcDiscoveryTimeout)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, providerConf.Issuer)
+	if err != nil {
+		return nil, code.ExternalError.Wrap(err)
+	}
+	scopes := providerConf.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	return &oidcClient{
+		conf: providerConf,
+		oauth2: &oauth2.Config{
+			ClientID:     providerConf.ClientId,
+			ClientSecret: providerConf.ClientSecret,
+			RedirectURL:  providerConf.RedirectUrl,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: providerConf.ClientId}),
+	}, nil
+}
+
+func oidcStateExpire() time.Duration {
+	if conf.Oidc.StateExpire > 0 {
+		return time.Duration(conf.Oidc.StateExpire) * time.Second
+	}
+	return OidcStateExpireTime
+}
@@ -0,0 +1,283 @@
+package serv
+
+import (
+	"app/code"
+	"app/conf"
+	"app/middleware"
+	"app/model/input"
+	"app/model/output"
+	"app/repo"
+	"app/util"
+	"app/util/jwtutil"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/coreos/go-oidc/v3/oidc/oidctest"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/valyala/fasthttp"
+)
+
+// stubGrant 存根身份提供方签发的授权码
+type stubGrant struct {
+	challenge string
+	claims    map[string]any
+}
+
+// stubProvider 本地 OpenID Connect 存根，发现文档与公钥由 oidctest 提供，这里实现令牌端点与 PKCE 校验
+type stubProvider struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	grants map[string]stubGrant
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &stubProvider{key: key, grants: map[string]stubGrant{}}
+	discovery := &oidctest.Server{PublicKeys: []oidctest.PublicKey{{PublicKey: key.Public(), KeyID: "stub", Algorithm: oidc.RS256}}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", p.token)
+	mux.Handle("/", discovery)
+	p.Server = httptest.NewServer(mux)
+	discovery.SetIssuer(p.URL)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize 模拟用户在身份提供方完成授权，返回授权码
+func (p *stubProvider) authorize(t *testing.T, authUrl string, claims map[string]any) (code, state string) {
+	u, err := url.Parse(authUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("auth url should use PKCE: %s", authUrl)
+	}
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = query.Get("nonce")
+	}
+	claims["aud"] = query.Get("client_id")
+	code = util.RandString(16)
+	p.mu.Lock()
+	p.grants[code] = stubGrant{challenge: query.Get("code_challenge"), claims: claims}
+	p.mu.Unlock()
+	return code, query.Get("state")
+}
+
+func (p *stubProvider) token(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	grant, ok := p.grants[r.PostFormValue("code")]
+	delete(p.grants, r.PostFormValue("code"))
+	p.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	claims := map[string]any{"iss": p.URL, "iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix()}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+	raw, _ := json.Marshal(claims)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "stub",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     oidctest.SignIDToken(p.key, "stub", oidc.RS256, string(raw)),
+	})
+}
+
+// callbackCtx 模拟同一浏览器访问回调地址，携带发起授权时设置的 Cookie
+func callbackCtx(t *testing.T, app *fiber.App, from *fiber.Ctx) *fiber.Ctx {
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(oidcBindingCookie)
+	if !from.Response().Header.Cookie(cookie) || !cookie.HTTPOnly() {
+		t.Fatal("auth url should set an HttpOnly binding cookie")
+	}
+	c := newCtx(app)
+	c.Request().Header.SetCookie(oidcBindingCookie, string(cookie.Value()))
+	return c
+}
+
+func tokenUserId(t *testing.T, tokens *output.TokenOutput) int {
+	token, err := jwtutil.Current().Parse(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(token.Claims.(jwt.MapClaims)["uid"].(float64))
+}
+
+func Test_OidcLogin(t *testing.T) {
+	app, user := initEnv(t)
+	stub := newStubProvider(t)
+	oidcConf := conf.Oidc
+	conf.Oidc = conf.OidcConf{Providers: []conf.OidcProviderConf{{
+		Name: "stub", Issuer: stub.URL, ClientId: "fiber-template", RedirectUrl: "http://localhost/callback", AutoProvision: true,
+	}}}
+	t.Cleanup(func() { conf.Oidc = oidcConf })
+
+	userRepo, roleRepo := repo.NewUserRepo(), repo.NewRoleRepo()
+	tokenServ := NewTokenService(userRepo, repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), roleRepo)
+	userServ := NewUserService(userRepo, roleRepo, repo.NewLoginAttemptRepo(), NewMfaService(userRepo, repo.NewRecoveryCodeRepo()), tokenServ)
+	oidcServ := NewOidcService(userRepo, repo.NewUserIdentityRepo(), repo.NewOidcStateRepo(), userServ, tokenServ)
+	login := func(c *fiber.Ctx, link bool, claims map[string]any) (*output.TokenOutput, string, error) {
+		authUrl, err := oidcServ.AuthUrl(c, "stub", link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, state := stub.authorize(t, authUrl, claims)
+		tokens, err := oidcServ.Callback(callbackCtx(t, app, c), "stub", &input.OidcCallback{Code: code, State: state})
+		return tokens, state, err
+	}
+
+	// 首次登录自动创建用户
+	subject := util.RandString(12)
+	name := "oidc_" + util.RandString(8)
+	browser := newCtx(app)
+	tokens, state, err := login(browser, false, map[string]any{"sub": subject, "preferred_username": name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provisioned, err := userRepo.SelectById(nil, tokenUserId(t, tokens))
+	if err != nil || *provisioned.Username != name {
+		t.Fatalf("user should be provisioned: %+v %v", provisioned, err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *provisioned.Id) })
+	if _, err := oidcServ.Callback(callbackCtx(t, app, browser), "stub", &input.OidcCallback{Code: "reused", State: state}); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("state should be used only once, got %v", err)
+	}
+
+	// 再次登录使用已关联的用户
+	tokens, _, err = login(newCtx(app), false, map[string]any{"sub": subject, "preferred_username": "other"})
+	if err != nil || tokenUserId(t, tokens) != *provisioned.Id {
+		t.Fatalf("linked identity should login the same user: %v", err)
+	}
+
+	// 已启用两步验证的用户只得到两步验证登录令牌
+	if err := userRepo.SetTotp(nil, *provisioned.Id, util.EnPointer("secret"), util.EnPointer(time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	tokens, _, err = login(newCtx(app), false, map[string]any{"sub": subject})
+	if err != nil || tokens.AccessToken != "" || tokens.RefreshToken != "" || tokens.MfaToken == "" {
+		t.Fatalf("mfa user should get an mfa challenge: %+v %v", tokens, err)
+	}
+	if userId, err := middleware.ParseMfaJwt(tokens.MfaToken); err != nil || userId != *provisioned.Id {
+		t.Fatalf("mfa token should belong to the user: %d %v", userId, err)
+	}
+	if err := userRepo.SetTotp(nil, *provisioned.Id, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := login(newCtx(app), false, map[string]any{"sub": subject, "nonce": "forged"}); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("nonce mismatch should fail, got %v", err)
+	}
+	browser = newCtx(app)
+	authUrl, err := oidcServ.AuthUrl(browser, "stub", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authCode, state := stub.authorize(t, authUrl, map[string]any{"sub": subject})
+	stub.grants[authCode] = stubGrant{challenge: "forged", claims: stub.grants[authCode].claims}
+	if _, err := oidcServ.Callback(callbackCtx(t, app, browser), "stub", &input.OidcCallback{Code: authCode, State: state}); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("PKCE verifier mismatch should fail, got %v", err)
+	}
+
+	// 已登录用户关联外部账户
+	linked := util.RandString(12)
+	tokens, _, err = login(authCtx(t, app, tokenServ, user), true, map[string]any{"sub": linked})
+	if err != nil || tokenUserId(t, tokens) != *user.Id {
+		t.Fatalf("identity should be linked to current user: %v", err)
+	}
+	if _, _, err := login(authCtx(t, app, tokenServ, user), true, map[string]any{"sub": subject}); !errors.Is(err, code.Conflict) {
+		t.Fatalf("identity linked to another user should conflict, got %v", err)
+	}
+
+	// 攻击者发起关联后诱导他人授权：他人的浏览器没有攻击者的 Cookie，state 不能使用
+	attacker := authCtx(t, app, tokenServ, user)
+	authUrl, err = oidcServ.AuthUrl(attacker, "stub", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authCode, state = stub.authorize(t, authUrl, map[string]any{"sub": util.RandString(12)})
+	if _, err := oidcServ.Callback(newCtx(app), "stub", &input.OidcCallback{Code: authCode, State: state}); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("callback without binding cookie should fail, got %v", err)
+	}
+	victim := newCtx(app)
+	if _, err := oidcServ.AuthUrl(victim, "stub", false); err != nil {
+		t.Fatal(err)
+	}
+	authUrl, err = oidcServ.AuthUrl(attacker, "stub", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authCode, state = stub.authorize(t, authUrl, map[string]any{"sub": util.RandString(12)})
+	if _, err := oidcServ.Callback(callbackCtx(t, app, victim), "stub", &input.OidcCallback{Code: authCode, State: state}); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("state bound to another browser should fail, got %v", err)
+	}
+
+	conf.Oidc.Providers[0].AutoProvision = false
+	if _, _, err := login(newCtx(app), false, map[string]any{"sub": util.RandString(12)}); !errors.Is(err, code.IdentityNotLinked) {
+		t.Fatalf("unlinked identity should be rejected without autoProvision, got %v", err)
+	}
+}
+
+func Test_OidcSlowDiscovery(t *testing.T) {
+	app, _ := initEnv(t)
+	stub := newStubProvider(t)
+	hit, release := make(chan struct{}, 1), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case hit <- struct{}{}:
+		default:
+		}
+		<-release
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+	oidcConf := conf.Oidc
+	conf.Oidc = conf.OidcConf{Providers: []conf.OidcProviderConf{
+		{Name: "slow", Issuer: slow.URL, ClientId: "fiber-template"},
+		{Name: "stub", Issuer: stub.URL, ClientId: "fiber-template"},
+	}}
+	t.Cleanup(func() { conf.Oidc = oidcConf })
+	userRepo := repo.NewUserRepo()
+	oidcServ := NewOidcService(userRepo, repo.NewUserIdentityRepo(), repo.NewOidcStateRepo(), nil, nil)
+
+	go func() { _, _ = oidcServ.AuthUrl(newCtx(app), "slow", false) }()
+	<-hit
+	// 发现文档未返回的身份提供方不影响其他身份提供方
+	done := make(chan error, 1)
+	go func() {
+		_, err := oidcServ.AuthUrl(newCtx(app), "stub", false)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("discovery of a slow provider should not block other providers")
+	}
+}
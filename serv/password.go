@@ -0,0 +1,351 @@
+package serv
+
+import (
+	"app/code"
+	"app/conf"
+	"app/db"
+	"app/log"
+	"app/middleware"
+	"app/model"
+	"app/model/input"
+	"app/notify"
+	"app/repo"
+	"app/util"
+	"bufio"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordResetExpireTime 未配置 password.resetExpire 时重置令牌的默认有效期
+var PasswordResetExpireTime = 30 * time.Minute
+
+// bcryptMaxLength bcrypt 只使用密码的前 72 个字节，更长的密码直接拒绝
+const bcryptMaxLength = 72
+
+type passwordServ struct {
+	userRepo         repo.UserRepo
+	resetRepo        repo.PasswordResetRepo
+	refreshTokenRepo repo.RefreshTokenRepo
+	attemptRepo      repo.LoginAttemptRepo
+	notifier         notify.Notifier
+}
+
+func NewPasswordService(userRepo repo.UserRepo, resetRepo repo.PasswordResetRepo, refreshTokenRepo repo.RefreshTokenRepo, attemptRepo repo.LoginAttemptRepo, notifier notify.Notifier) PasswordServ {
+	return &passwordServ{
+		userRepo:         userRepo,
+		resetRepo:        resetRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		attemptRepo:      attemptRepo,
+		notifier:         notifier,
+	}
+}
+
+// Change 校验原密码后修改当前用户的密码，吊销所有刷新令牌与未使用的重置令牌
+func (o *passwordServ) Change(c *fiber.Ctx, in *input.PasswordChange) error {
+	principal := middleware.CurrentUser(c)
+	if principal == nil {
+		return code.AuthFailed
+	}
+	user, err := o.userRepo.SelectCredentials(c, principal.UserId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return code.AuthFailed
+	} else if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(in.OldPassword)); err != nil {
+		return code.UsernameOrPasswordFailed
+	}
+	if in.NewPassword == in.OldPassword {
+		return code.WeakPassword.WithDetails(map[string][]string{"violations": {"reused"}})
+	}
+	if err := o.setPassword(c, user, in.NewPassword); err != nil {
+		return err
+	}
+	log.F(c).Infof("user %d changed password", *user.Id)
+	return nil
+}
+
+// Forgot 签发密码重置令牌并通过 notifier 发送给用户，之前未使用的令牌随即失效
+// 账户不存在时同样返回成功，避免通过响应区分账户是否存在
+func (o *passwordServ) Forgot(c *fiber.Ctx, in *input.PasswordForgot) error {
+	user, err := o.userRepo.SelectByUsername(c, in.Username)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.F(c).Warnf("password reset requested for unknown user %s", in.Username)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(passwordResetExpire())
+	err = db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		if err := o.resetRepo.InvalidateByUserId(c, *user.Id); err != nil {
+			return err
+		}
+		return o.resetRepo.Insert(c, &model.PasswordResetToken{TokenHash: util.EnPointer(hashToken(token)), UserId: user.Id, ExpiresAt: &expiresAt})
+	})
+	if err != nil {
+		return err
+	}
+	msg := &notify.Message{
+		UserId:   *user.Id,
+		Username: *user.Username,
+		Subject:  "重置密码",
+		Body:     "使用以下令牌重置密码，有效期至 " + expiresAt.Format(time.DateTime) + "：" + token,
+		Data:     map[string]string{"token": token, "expiresAt": expiresAt.Format(time.RFC3339)},
+	}
+	if conf.Password.ResetUrl != "" {
+		link := resetLink(conf.Password.ResetUrl, token)
+		msg.Body = "打开以下链接重置密码，有效期至 " + expiresAt.Format(time.DateTime) + "：" + link
+		msg.Data["url"] = link
+	}
+	if err := o.notifier.Notify(c.UserContext(), msg); err != nil {
+		// 发送失败时不向客户端暴露账户是否存在
+		log.F(c).Errorf("send password reset notification to user %d: %v", *user.Id, err)
+	}
+	return nil
+}
+
+// Reset 使用重置令牌设置新密码并解除账户锁定，令牌只能使用一次
+func (o *passwordServ) Reset(c *fiber.Ctx, in *input.PasswordReset) error {
+	token, err := o.resetRepo.SelectByHash(c, hashToken(in.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return code.PasswordResetInvalid
+	} else if err != nil {
+		return err
+	}
+	if token.UsedAt != nil || !time.Now().Before(*token.ExpiresAt) {
+		return code.PasswordResetInvalid
+	}
+	user, err := o.userRepo.SelectCredentials(c, *token.UserId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return code.PasswordResetInvalid
+	} else if err != nil {
+		return err
+	}
+	err = db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		ok, err := o.resetRepo.Use(c, *token.Id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return code.PasswordResetInvalid
+		}
+		if err := o.setPassword(c, user, in.NewPassword); err != nil {
+			return err
+		}
+		return o.userRepo.Unlock(c, *user.Id)
+	})
+	if err != nil {
+		return err
+	}
+	if err := o.attemptRepo.Reset(c, userAttemptKey(*user.Username)); err != nil {
+		log.F(c).Error(err)
+	}
+	log.F(c).Infof("user %d reset password", *user.Id)
+	return nil
+}
+
+// setPassword 校验密码强度后保存，吊销所有刷新令牌与未使用的重置令牌，已签发的访问令牌在过期前仍然有效
+func (o *passwordServ) setPassword(c *fiber.Ctx, user *model.User, password string) error {
+	if err := checkPassword(password, *user.Username); err != nil {
+		return err
+	}
+	hash, err := hashPassword(c, password)
+	if err != nil {
+		return err
+	}
+	return db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		if err := o.userRepo.SetPassword(c, *user.Id, hash); err != nil {
+			return err
+		}
+		if err := o.refreshTokenRepo.RevokeByUserId(c, *user.Id); err != nil {
+			return err
+		}
+		return o.resetRepo.InvalidateByUserId(c, *user.Id)
+	})
+}
+
+// checkPassword 按 conf.Password 校验密码强度，不满足时返回 code.WeakPassword，详细信息中列出未满足的规则
+func checkPassword(password, username string) error {
+	policy := conf.Password
+	var violations []string
+	length := utf8.RuneCountInString(password)
+	if length < max(policy.MinLength, 1) {
+		violations = append(violations, "minLength")
+	}
+	if (policy.MaxLength > 0 && length > policy.MaxLength) || len(password) > bcryptMaxLength {
+		violations = append(violations, "maxLength")
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	if policy.RequireUpper && !upper {
+		violations = append(violations, "upper")
+	}
+	if policy.RequireLower && !lower {
+		violations = append(violations, "lower")
+	}
+	if policy.RequireDigit && !digit {
+		violations = append(violations, "digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		violations = append(violations, "symbol")
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		violations = append(violations, "username")
+	}
+	if breachedPasswords.contains(password) {
+		violations = append(violations, "breached")
+	}
+	if len(violations) > 0 {
+		return code.WeakPassword.WithDetails(map[string][]string{"violations": violations})
+	}
+	return nil
+}
+
+// randomPassword 生成符合 conf.Password 的随机密码，用于没有密码的账户 (如 OIDC 自动创建的用户)
+func randomPassword() (string, error) {
+	const (
+		uppers  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+		lowers  = "abcdefghijkmnopqrstuvwxyz"
+		digits  = "23456789"
+		symbols = "!#$%&*+-=?@_"
+	)
+	length := max(conf.Password.MinLength, 32)
+	if conf.Password.MaxLength > 0 {
+		length = min(length, conf.Password.MaxLength)
+	}
+	length = min(length, bcryptMaxLength)
+	// 每类字符至少一个，其余从全部字符中选取，最后打乱顺序
+	sets := []string{uppers, lowers, digits, symbols}
+	for len(sets) < length {
+		sets = append(sets, uppers+lowers+digits+symbols)
+	}
+	b := make([]byte, 0, len(sets))
+	for _, set := range sets {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		if err != nil {
+			return "", err
+		}
+		b = append(b, set[n.Int64()])
+	}
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		b[i], b[j.Int64()] = b[j.Int64()], b[i]
+	}
+	return string(b), nil
+}
+
+func hashPassword(c *fiber.Ctx, password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.F(c).Error(err)
+		return "", code.PasswordCryptFailed.Wrap(err)
+	}
+	return string(hash), nil
+}
+
+func resetLink(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func passwordResetExpire() time.Duration {
+	if conf.Password.ResetExpire > 0 {
+		return time.Duration(conf.Password.ResetExpire) * time.Second
+	}
+	return PasswordResetExpireTime
+}
+
+// breachedPasswords 已泄露密码列表，password.breachedFile 变更或文件修改后重新加载
+var breachedPasswords = &breachedList{}
+
+type breachedList struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	set     map[string]struct{}
+}
+
+// contains 不区分大小写地判断密码是否在列表中，列表文件读取失败时只记录日志
+func (o *breachedList) contains(password string) bool {
+	path := conf.Password.BreachedFile
+	if path == "" {
+		return false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(conf.RootPath, path)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.load(path); err != nil {
+		log.Errorf("load breached password file %s: %v", path, err)
+	}
+	_, ok := o.set[strings.ToLower(password)]
+	return ok
+}
+
+func (o *breachedList) load(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if path != o.path {
+			o.path, o.modTime, o.set = "", time.Time{}, nil
+		}
+		return err
+	}
+	if path == o.path && info.ModTime().Equal(o.modTime) {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	set := map[string]struct{}{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			set[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	o.path, o.modTime, o.set = path, info.ModTime(), set
+	return nil
+}
@@ -0,0 +1,151 @@
+package serv
+
+import (
+	"app/code"
+	"app/conf"
+	"app/model"
+	"app/model/input"
+	"app/notify"
+	"app/repo"
+	"app/util"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stubNotifier 记录发送的通知
+type stubNotifier struct {
+	messages []*notify.Message
+}
+
+func (o *stubNotifier) Notify(_ context.Context, msg *notify.Message) error {
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+func violations(err error) []string {
+	var bizErr *code.BizError
+	if !errors.As(err, &bizErr) {
+		return nil
+	}
+	details, _ := bizErr.Details.(map[string][]string)
+	return details["violations"]
+}
+
+func Test_CheckPassword(t *testing.T) {
+	password := conf.Password
+	t.Cleanup(func() { conf.Password = password })
+	breached := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(breached, []byte("# common passwords\nPassw0rd!\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	conf.Password = conf.PasswordConf{MinLength: 8, MaxLength: 64, RequireUpper: true, RequireDigit: true, RequireSymbol: true, BreachedFile: breached}
+
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"Str0ng!pass", nil},
+		{"Sh0rt!", []string{"minLength"}},
+		{"alllowercase", []string{"upper", "digit", "symbol"}},
+		{"passw0rd!", []string{"upper", "breached"}},
+		{"Alice_2024", []string{"username"}},
+	}
+	for _, tt := range tests {
+		if got := violations(checkPassword(tt.password, "alice_2024")); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("checkPassword(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+	for range 5 {
+		random, err := randomPassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkPassword(random, ""); err != nil {
+			t.Fatalf("random password %q should satisfy the policy: %v", random, err)
+		}
+	}
+}
+
+func Test_PasswordChangeAndReset(t *testing.T) {
+	app, _ := initEnv(t)
+	password := conf.Password
+	conf.Password = conf.PasswordConf{MinLength: 8, MaxLength: 64, RequireDigit: true}
+	t.Cleanup(func() { conf.Password = password })
+
+	userRepo, roleRepo, refreshTokenRepo, attemptRepo := repo.NewUserRepo(), repo.NewRoleRepo(), repo.NewRefreshTokenRepo(), repo.NewLoginAttemptRepo()
+	tokenServ := NewTokenService(userRepo, refreshTokenRepo, repo.NewTokenBlacklistRepo(), roleRepo)
+	userServ := NewUserService(userRepo, roleRepo, attemptRepo, NewMfaService(userRepo, repo.NewRecoveryCodeRepo()), tokenServ)
+	notifier := &stubNotifier{}
+	passwordServ := NewPasswordService(userRepo, repo.NewPasswordResetRepo(), refreshTokenRepo, attemptRepo, notifier)
+	user := &model.User{
+		Username: util.EnPointer("pwd_" + util.RandString(8)),
+		Password: util.EnPointer("password"),
+	}
+	if err := userServ.Insert(newCtx(app), user); !errors.Is(err, code.WeakPassword) {
+		t.Fatalf("weak password should be rejected, got %v", err)
+	}
+	user.Password = util.EnPointer("password1")
+	if err := userServ.Insert(newCtx(app), user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *user.Id) })
+	if err := userServ.Update(authCtx(t, app, tokenServ, user), &model.User{Id: user.Id, Password: util.EnPointer("password2")}); !errors.Is(err, code.ParamError) {
+		t.Fatalf("update should not change password, got %v", err)
+	}
+
+	// 修改密码需要原密码，刷新令牌随即失效
+	tokens, err := tokenServ.Issue(newCtx(app), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := passwordServ.Change(authCtx(t, app, tokenServ, user), &input.PasswordChange{OldPassword: "wrong", NewPassword: "password2"}); !errors.Is(err, code.UsernameOrPasswordFailed) {
+		t.Fatalf("wrong old password should fail, got %v", err)
+	}
+	if err := passwordServ.Change(authCtx(t, app, tokenServ, user), &input.PasswordChange{OldPassword: "password1", NewPassword: "password2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokenServ.Refresh(newCtx(app), tokens.RefreshToken); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("refresh tokens should be revoked after password change, got %v", err)
+	}
+	if _, err := userServ.Login(newCtx(app), &input.UserLogin{Username: user.Username, Password: util.EnPointer("password2")}); err != nil {
+		t.Fatalf("new password should login: %v", err)
+	}
+
+	// 账户不存在时同样返回成功且不发送通知
+	if err := passwordServ.Forgot(newCtx(app), &input.PasswordForgot{Username: "nobody_" + util.RandString(8)}); err != nil || len(notifier.messages) != 0 {
+		t.Fatalf("unknown user should not be notified: %v", err)
+	}
+	if err := passwordServ.Forgot(newCtx(app), &input.PasswordForgot{Username: *user.Username}); err != nil {
+		t.Fatal(err)
+	}
+	if err := passwordServ.Forgot(newCtx(app), &input.PasswordForgot{Username: *user.Username}); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.messages) != 2 || notifier.messages[1].UserId != *user.Id {
+		t.Fatalf("reset token should be sent to the user: %+v", notifier.messages)
+	}
+	stale, token := notifier.messages[0].Data["token"], notifier.messages[1].Data["token"]
+	if err := passwordServ.Reset(newCtx(app), &input.PasswordReset{Token: stale, NewPassword: "password3"}); !errors.Is(err, code.PasswordResetInvalid) {
+		t.Fatalf("earlier reset token should be invalidated, got %v", err)
+	}
+	if err := passwordServ.Reset(newCtx(app), &input.PasswordReset{Token: token, NewPassword: "weak"}); !errors.Is(err, code.WeakPassword) {
+		t.Fatalf("weak password should be rejected, got %v", err)
+	}
+	if err := userRepo.Lock(nil, *user.Id, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := passwordServ.Reset(newCtx(app), &input.PasswordReset{Token: token, NewPassword: "password3"}); err != nil {
+		t.Fatalf("reset token should still be usable after a rejected password: %v", err)
+	}
+	if err := passwordServ.Reset(newCtx(app), &input.PasswordReset{Token: token, NewPassword: "password4"}); !errors.Is(err, code.PasswordResetInvalid) {
+		t.Fatalf("reset token should be used only once, got %v", err)
+	}
+	if _, err := userServ.Login(newCtx(app), &input.UserLogin{Username: user.Username, Password: util.EnPointer("password3")}); err != nil {
+		t.Fatalf("reset should set the password and unlock the account: %v", err)
+	}
+}
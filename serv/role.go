@@ -0,0 +1,191 @@
+package serv
+
+import (
+	"app/code"
+	"app/db"
+	"app/model"
+	"app/model/input"
+	"app/model/output"
+	"app/repo"
+	"app/util/copier"
+	"app/util/dbutil"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type roleServ struct {
+	roleRepo       repo.RoleRepo
+	permissionRepo repo.PermissionRepo
+	userRepo       repo.UserRepo
+}
+
+func NewRoleService(roleRepo repo.RoleRepo, permissionRepo repo.PermissionRepo, userRepo repo.UserRepo) RoleServ {
+	return &roleServ{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		userRepo:       userRepo,
+	}
+}
+
+func (o *roleServ) Select(c *fiber.Ctx) ([]output.RoleOutput, error) {
+	roles, err := o.roleRepo.SelectWhere(c)
+	if err != nil {
+		return nil, err
+	}
+	return o.toOutputs(c, roles)
+}
+
+func (o *roleServ) SelectPermissions(c *fiber.Ctx) ([]output.PermissionOutput, error) {
+	permissions, err := o.permissionRepo.SelectWhere(c)
+	if err != nil {
+		return nil, err
+	}
+	var permissionOutputs []output.PermissionOutput
+	err = copier.TransferListType(permissions, &permissionOutputs)
+	if err != nil {
+		return nil, err
+	}
+	return permissionOutputs, nil
+}
+
+func (o *roleServ) Insert(c *fiber.Ctx, roleInput *input.RoleInput) error {
+	if err := o.checkName(c, *roleInput.Name, 0); err != nil {
+		return err
+	}
+	if err := o.checkPermissions(c, roleInput.PermissionIds); err != nil {
+		return err
+	}
+	return db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		role := &model.Role{Name: roleInput.Name, Description: roleInput.Description}
+		if err := o.roleRepo.Insert(c, role); err != nil {
+			return err
+		}
+		return o.roleRepo.SetPermissions(c, *role.Id, uniqueIds(roleInput.PermissionIds))
+	})
+}
+
+func (o *roleServ) Update(c *fiber.Ctx, id int, roleInput *input.RoleInput) error {
+	role, err := o.roleRepo.SelectById(c, id)
+	if err != nil {
+		return err
+	}
+	// 管理员角色固定拥有所有权限，只允许修改描述
+	if *role.Name == model.RoleAdmin && (*roleInput.Name != model.RoleAdmin || roleInput.PermissionIds != nil) {
+		return code.Conflict
+	}
+	if err := o.checkName(c, *roleInput.Name, id); err != nil {
+		return err
+	}
+	if err := o.checkPermissions(c, roleInput.PermissionIds); err != nil {
+		return err
+	}
+	return db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		err := o.roleRepo.Update(c, &model.Role{Id: &id, Name: roleInput.Name, Description: roleInput.Description})
+		if err != nil || *role.Name == model.RoleAdmin {
+			return err
+		}
+		return o.roleRepo.SetPermissions(c, id, uniqueIds(roleInput.PermissionIds))
+	})
+}
+
+func (o *roleServ) Delete(c *fiber.Ctx, id int) error {
+	role, err := o.roleRepo.SelectById(c, id)
+	if err != nil {
+		return err
+	}
+	if *role.Name == model.RoleAdmin {
+		return code.Conflict
+	}
+	return db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		return o.roleRepo.Delete(c, id)
+	})
+}
+
+func (o *roleServ) SelectUserRoles(c *fiber.Ctx, userId int) ([]output.RoleOutput, error) {
+	if _, err := o.userRepo.SelectById(c, userId); err != nil {
+		return nil, err
+	}
+	roles, err := o.roleRepo.SelectByUserId(c, userId)
+	if err != nil {
+		return nil, err
+	}
+	return o.toOutputs(c, roles)
+}
+
+// SetUserRoles 替换用户的角色，新角色在用户重新登录或刷新令牌后生效
+func (o *roleServ) SetUserRoles(c *fiber.Ctx, userId int, roleIds []int) error {
+	if _, err := o.userRepo.SelectById(c, userId); err != nil {
+		return err
+	}
+	roleIds = uniqueIds(roleIds)
+	if len(roleIds) > 0 {
+		roles, err := o.roleRepo.SelectWhere(c, dbutil.In("id", roleIds))
+		if err != nil {
+			return err
+		}
+		if len(roles) != len(roleIds) {
+			return code.NotFound
+		}
+	}
+	return db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		return o.roleRepo.SetUserRoles(c, userId, roleIds)
+	})
+}
+
+// checkName 角色名称不能与其他角色重复
+func (o *roleServ) checkName(c *fiber.Ctx, name string, id int) error {
+	role, err := o.roleRepo.SelectByName(c, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if *role.Id != id {
+		return code.Conflict
+	}
+	return nil
+}
+
+// checkPermissions 权限编号必须都存在
+func (o *roleServ) checkPermissions(c *fiber.Ctx, permissionIds []int) error {
+	permissionIds = uniqueIds(permissionIds)
+	if len(permissionIds) == 0 {
+		return nil
+	}
+	permissions, err := o.permissionRepo.SelectWhere(c, dbutil.In("id", permissionIds))
+	if err != nil {
+		return err
+	}
+	if len(permissions) != len(permissionIds) {
+		return code.NotFound
+	}
+	return nil
+}
+
+func (o *roleServ) toOutputs(c *fiber.Ctx, roles []model.Role) ([]output.RoleOutput, error) {
+	var roleOutputs []output.RoleOutput
+	err := copier.TransferListType(roles, &roleOutputs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range roleOutputs {
+		permissions, err := o.permissionRepo.SelectByRoleId(c, *roleOutputs[i].Id)
+		if err != nil {
+			return nil, err
+		}
+		roleOutputs[i].Permissions = make([]string, 0, len(permissions))
+		for _, p := range permissions {
+			roleOutputs[i].Permissions = append(roleOutputs[i].Permissions, *p.Name)
+		}
+	}
+	return roleOutputs, nil
+}
+
+func uniqueIds(ids []int) []int {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
@@ -0,0 +1,92 @@
+package serv
+
+import (
+	"app/code"
+	"app/middleware"
+	"app/model"
+	"app/model/input"
+	"app/repo"
+	"app/util"
+	"app/util/jwtutil"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func Test_RolePermission(t *testing.T) {
+	app, user := initEnv(t)
+	roleRepo, permissionRepo := repo.NewRoleRepo(), repo.NewPermissionRepo()
+	roleServ := NewRoleService(roleRepo, permissionRepo, repo.NewUserRepo())
+	tokenServ := NewTokenService(repo.NewUserRepo(), repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), roleRepo)
+
+	permissions, err := roleServ.SelectPermissions(newCtx(app))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var readId int
+	for _, p := range permissions {
+		if *p.Name == "user:read" {
+			readId = *p.Id
+		}
+	}
+	name := "reader_" + util.RandString(8)
+	if err := roleServ.Insert(newCtx(app), &input.RoleInput{Name: &name, PermissionIds: []int{readId, readId}}); err != nil {
+		t.Fatal(err)
+	}
+	role, err := roleRepo.SelectByName(nil, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = roleServ.Delete(newCtx(app), *role.Id) })
+	if err := roleServ.Insert(newCtx(app), &input.RoleInput{Name: &name}); !errors.Is(err, code.Conflict) {
+		t.Fatalf("duplicate role name should conflict, got %v", err)
+	}
+	if err := roleServ.SetUserRoles(newCtx(app), *user.Id, []int{*role.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := tokenServ.Issue(newCtx(app), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwtutil.Current().Parse(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newCtx(app)
+	c.Locals("user", token)
+	if roles := middleware.TokenRoles(c); !slices.Equal(roles, []string{name}) {
+		t.Fatalf("token should carry user roles, got %v", roles)
+	}
+
+	// 模拟 JwtAuth 校验通过后的请求
+	app.Use(middleware.TraceId(), middleware.ErrorParse())
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", token)
+		return c.Next()
+	})
+	app.Get("/read", middleware.RequirePermission("user:read"), func(c *fiber.Ctx) error { return nil })
+	app.Get("/delete", middleware.RequirePermission("user:delete"), func(c *fiber.Ctx) error { return nil })
+	resp, err := app.Test(httptest.NewRequest("GET", "/read", nil))
+	if err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("user:read should be granted: %v %v", resp.StatusCode, err)
+	}
+	resp, err = app.Test(httptest.NewRequest("GET", "/delete", nil))
+	if err != nil || resp.StatusCode != code.PermissionDenied.Status() {
+		t.Fatalf("user:delete should be denied: %v %v", resp.StatusCode, err)
+	}
+	if roles, _ := roleServ.SelectUserRoles(newCtx(app), *user.Id); len(roles) != 1 || !slices.Equal(roles[0].Permissions, []string{"user:read"}) {
+		t.Fatalf("unexpected user roles: %+v", roles)
+	}
+
+	admin, err := roleRepo.SelectByName(nil, model.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := roleServ.Delete(newCtx(app), *admin.Id); !errors.Is(err, code.Conflict) {
+		t.Fatalf("admin role should not be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package serv
+
+import (
+	"app/code"
+	"app/conf"
+	"app/db"
+	"app/log"
+	"app/middleware"
+	"app/model"
+	"app/model/output"
+	"app/repo"
+	"app/util"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RefreshExpireTime 未配置 jwt.refreshExpire 时刷新令牌的默认有效期
+var RefreshExpireTime = time.Hour * 24 * 7
+
+// errRefreshTokenReused 刷新令牌已被使用过，需要吊销整个令牌族
+var errRefreshTokenReused = errors.New("refresh token reused")
+
+type tokenServ struct {
+	userRepo         repo.UserRepo
+	refreshTokenRepo repo.RefreshTokenRepo
+	blacklistRepo    repo.TokenBlacklistRepo
+	roleRepo         repo.RoleRepo
+}
+
+func NewTokenService(userRepo repo.UserRepo, refreshTokenRepo repo.RefreshTokenRepo, blacklistRepo repo.TokenBlacklistRepo, roleRepo repo.RoleRepo) TokenServ {
+	return &tokenServ{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		blacklistRepo:    blacklistRepo,
+		roleRepo:         roleRepo,
+	}
+}
+
+// Issue 为登录用户签发访问令牌与新令牌族的刷新令牌
+func (o *tokenServ) Issue(c *fiber.Ctx, user *model.User) (*output.TokenOutput, error) {
+	return o.issue(c, user, uuid.NewString())
+}
+
+// Refresh 轮换刷新令牌：旧令牌吊销并签发同一令牌族的新令牌；
+// 已吊销的令牌被再次使用时视为泄露，吊销整个令牌族
+func (o *tokenServ) Refresh(c *fiber.Ctx, refreshToken string) (*output.TokenOutput, error) {
+	token, err := o.refreshTokenRepo.SelectByHash(c, hashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, code.AuthFailed
+	} else if err != nil {
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, o.revokeReused(c, token)
+	}
+	if token.ExpiresAt == nil || time.Now().After(*token.ExpiresAt) {
+		return nil, code.AuthFailed
+	}
+
+	var result *output.TokenOutput
+	err = db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		ok, err := o.refreshTokenRepo.Revoke(c, *token.Id)
+		if err != nil {
+			return err
+		} else if !ok {
+			return errRefreshTokenReused
+		}
+		user, err := o.userRepo.SelectById(c, *token.UserId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return code.AuthFailed
+		} else if err != nil {
+			return err
+		}
+		result, err = o.issue(c, user, *token.Family)
+		return err
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		return nil, o.revokeReused(c, token)
+	}
+	return result, err
+}
+
+// Logout 将当前访问令牌加入黑名单，传入刷新令牌时同时吊销其所在的令牌族
+func (o *tokenServ) Logout(c *fiber.Ctx, refreshToken *string) error {
+	claims := middleware.TokenClaims(c)
+	jti, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if jti == "" || err != nil || exp == nil {
+		return code.AuthFailed
+	}
+	if err := o.blacklistRepo.Add(c, jti, exp.Time); err != nil {
+		return err
+	}
+	if refreshToken == nil || *refreshToken == "" {
+		return nil
+	}
+	token, err := o.refreshTokenRepo.SelectByHash(c, hashToken(*refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// 只允许注销自己的令牌
+	if principal := middleware.CurrentUser(c); principal == nil || token.UserId == nil || principal.UserId != *token.UserId {
+		return code.PermissionDenied
+	}
+	return o.refreshTokenRepo.RevokeFamily(c, *token.Family)
+}
+
+func (o *tokenServ) issue(c *fiber.Ctx, user *model.User, family string) (*output.TokenOutput, error) {
+	roles, err := o.userRoles(c, user)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := middleware.GenerateJwt(*user.Id, *user.Username, roles)
+	if err != nil {
+		return nil, code.TokenGenerateFailed.Wrap(err)
+	}
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, code.TokenGenerateFailed.Wrap(err)
+	}
+	expiresAt := time.Now().Add(refreshExpire())
+	err = o.refreshTokenRepo.Insert(c, &model.RefreshToken{
+		TokenHash: util.EnPointer(hashToken(refreshToken)),
+		Family:    &family,
+		UserId:    user.Id,
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &output.TokenOutput{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(middleware.AccessExpire().Seconds()),
+	}, nil
+}
+
+// userRoles 用户的角色名称，rbac.admins 中的用户始终拥有管理员角色
+func (o *tokenServ) userRoles(c *fiber.Ctx, user *model.User) ([]string, error) {
+	list, err := o.roleRepo.SelectByUserId(c, *user.Id)
+	if err != nil {
+		return nil, err
+	}
+	return middleware.RoleNames(user, list), nil
+}
+
+func (o *tokenServ) revokeReused(c *fiber.Ctx, token *model.RefreshToken) error {
+	log.F(c).Warnf("refresh token reused, revoke family: %s, user: %d", *token.Family, *token.UserId)
+	if err := o.refreshTokenRepo.RevokeFamily(c, *token.Family); err != nil {
+		return err
+	}
+	return code.AuthFailed
+}
+
+func refreshExpire() time.Duration {
+	if conf.Jwt.RefreshExpire > 0 {
+		return time.Duration(conf.Jwt.RefreshExpire) * time.Second
+	}
+	return RefreshExpireTime
+}
+
+// randomToken 生成随机的不透明令牌，用于刷新令牌、OIDC state 等
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken 数据库中只保存刷新令牌的摘要
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,161 @@
+package serv
+
+import (
+	"app/code"
+	"app/conf"
+	"app/db"
+	"app/i18n"
+	"app/log"
+	"app/model"
+	"app/model/input"
+	"app/repo"
+	"app/util"
+	"app/util/jwtutil"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/valyala/fasthttp"
+)
+
+func initEnv(t *testing.T) (*fiber.App, *model.User) {
+	conf.Initialize()
+	log.Initialize()
+	db.Initialize()
+	i18n.Initialize()
+	user := &model.User{
+		Username: util.EnPointer("token_" + util.RandString(8)),
+		Password: util.EnPointer("password"),
+	}
+	userRepo := repo.NewUserRepo()
+	if err := userRepo.Insert(nil, user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *user.Id) })
+	return fiber.New(), user
+}
+
+func newCtx(app *fiber.App) *fiber.Ctx {
+	return app.AcquireCtx(&fasthttp.RequestCtx{})
+}
+
+func Test_TokenRefresh(t *testing.T) {
+	app, user := initEnv(t)
+	tokenServ := NewTokenService(repo.NewUserRepo(), repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), repo.NewRoleRepo())
+
+	first, err := tokenServ.Issue(newCtx(app), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := tokenServ.Refresh(newCtx(app), first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Fatal("refresh should rotate the refresh token")
+	}
+
+	// 重复使用已轮换的令牌会吊销整个令牌族
+	if _, err := tokenServ.Refresh(newCtx(app), first.RefreshToken); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("reused refresh token should be rejected, got %v", err)
+	}
+	if _, err := tokenServ.Refresh(newCtx(app), second.RefreshToken); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("token family should be revoked after reuse, got %v", err)
+	}
+	if _, err := tokenServ.Refresh(newCtx(app), "unknown"); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("unknown refresh token should be rejected, got %v", err)
+	}
+}
+
+func Test_TokenLogout(t *testing.T) {
+	app, user := initEnv(t)
+	blacklistRepo := repo.NewTokenBlacklistRepo()
+	tokenServ := NewTokenService(repo.NewUserRepo(), repo.NewRefreshTokenRepo(), blacklistRepo, repo.NewRoleRepo())
+
+	tokens, err := tokenServ.Issue(newCtx(app), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessToken, err := jwtutil.Current().Parse(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newCtx(app)
+	c.Locals("user", accessToken)
+	if err := tokenServ.Logout(c, &tokens.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+
+	jti := accessToken.Claims.(jwt.MapClaims)["jti"].(string)
+	if exists, err := blacklistRepo.Exists(nil, jti); err != nil || !exists {
+		t.Fatalf("access token should be blacklisted: %v %v", exists, err)
+	}
+	if _, err := tokenServ.Refresh(newCtx(app), tokens.RefreshToken); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("refresh token should be revoked after logout, got %v", err)
+	}
+}
+
+// laggingReplica 以主库当前的快照作为只读副本，之后主库的写入不会同步到副本，模拟延迟的副本
+func laggingReplica(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replica.db")
+	if _, err := db.DB.Exec("VACUUM INTO ?", path); err != nil {
+		t.Fatal(err)
+	}
+	replicaDSNs := conf.DB.Replicas
+	conf.DB.Replicas = []string{path}
+	db.InitializeSqlite()
+	t.Cleanup(func() {
+		conf.DB.Replicas = replicaDSNs
+		db.InitializeSqlite()
+	})
+}
+
+func Test_LaggingReplica(t *testing.T) {
+	app, _ := initEnv(t)
+	laggingReplica(t)
+	userRepo, roleRepo := repo.NewUserRepo(), repo.NewRoleRepo()
+	tokenServ := NewTokenService(userRepo, repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), roleRepo)
+	userServ := NewUserService(userRepo, roleRepo, repo.NewLoginAttemptRepo(), NewMfaService(userRepo, repo.NewRecoveryCodeRepo()), tokenServ)
+
+	// 注册后立即登录
+	register := &input.UserRegister{Username: util.EnPointer("lag_" + util.RandString(8)), Password: util.EnPointer("password")}
+	if err := userServ.Register(newCtx(app), register); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if user, err := userRepo.WithTrashed().SelectOneBy(nil, "username", *register.Username); err == nil {
+			_ = userRepo.ForceDelete(nil, *user.Id)
+		}
+	})
+	if _, err := userRepo.SelectByUsername(nil, *register.Username); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("replica should lag behind the primary, got %v", err)
+	}
+	tokens, err := userServ.Login(newCtx(app), &input.UserLogin{Username: register.Username, Password: register.Password})
+	if err != nil {
+		t.Fatalf("login right after register should succeed: %v", err)
+	}
+
+	// 签发后立即刷新，注销后访问令牌立即失效
+	refreshed, err := tokenServ.Refresh(newCtx(app), tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh right after login should succeed: %v", err)
+	}
+	if _, err := tokenServ.Refresh(newCtx(app), tokens.RefreshToken); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("rotated refresh token should be rejected, got %v", err)
+	}
+	token, err := jwtutil.Current().Parse(refreshed.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newCtx(app)
+	c.Locals("user", token)
+	if err := tokenServ.Logout(c, nil); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := repo.NewTokenBlacklistRepo().Exists(nil, token.Claims.(jwt.MapClaims)["jti"].(string)); err != nil || !exists {
+		t.Fatalf("logged out token should be blacklisted: %v %v", exists, err)
+	}
+}
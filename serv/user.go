@@ -1,63 +1,83 @@
 package serv
 
 import (
+	"app/code"
+	"app/conf"
+	"app/db"
 	"app/log"
 	"app/middleware"
 	"app/model"
 	"app/model/input"
 	"app/model/output"
 	"app/repo"
-	"app/util"
 	"app/util/copier"
+	"app/util/dbutil"
+	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type userServ struct {
-	userRepo repo.UserRepo
+	userRepo    repo.UserRepo
+	roleRepo    repo.RoleRepo
+	attemptRepo repo.LoginAttemptRepo
+	mfaServ     MfaServ
+	tokenServ   TokenServ
 }
 
-func NewUserService(userRepo repo.UserRepo) UserServ {
+func NewUserService(userRepo repo.UserRepo, roleRepo repo.RoleRepo, attemptRepo repo.LoginAttemptRepo, mfaServ MfaServ, tokenServ TokenServ) UserServ {
 	return &userServ{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		attemptRepo: attemptRepo,
+		mfaServ:     mfaServ,
+		tokenServ:   tokenServ,
 	}
 }
 
 func (o *userServ) Insert(c *fiber.Ctx, user *model.User) error {
-	password, err := bcrypt.GenerateFromPassword([]byte(*user.Password), bcrypt.DefaultCost)
+	if user.Username == nil || user.Password == nil {
+		return code.ParamError
+	}
+	created, err := o.createUser(c, *user.Username, *user.Password)
 	if err != nil {
-		log.F(c).Error(err)
 		return err
 	}
-	user.Password = util.EnPointer(string(password))
-	return o.userRepo.Insert(c, user)
+	*user = *created
+	return nil
 }
 
 func (o *userServ) Delete(c *fiber.Ctx, id int) error {
 	return o.userRepo.Delete(c, id)
 }
 
+// Update 更新用户，没有 user:update 权限的用户只能更新自己
 func (o *userServ) Update(c *fiber.Ctx, user *model.User) error {
-	if user.Password != nil && len(util.DePointer(user.Password)) > 0 {
-		password, err := bcrypt.GenerateFromPassword([]byte(*user.Password), bcrypt.DefaultCost)
-		if err != nil {
-			log.F(c).Error(err)
-			return err
-		}
-		user.Password = util.EnPointer(string(password))
+	if user.Id == nil {
+		return code.ParamError
 	}
+	if err := authorizeOwner(c, *user.Id, "user:update"); err != nil {
+		return err
+	}
+	// 密码只能通过修改密码或重置密码接口修改
+	if user.Password != nil && len(*user.Password) > 0 {
+		return code.ParamError.WithDetails(map[string]string{"password": "use PUT /user/password"})
+	}
+	// 创建、删除、锁定与启用两步验证的时间不允许通过更新修改
+	user.Password, user.CreatedAt, user.DeletedAt, user.LockedUntil, user.TotpEnabledAt = nil, nil, nil, nil, nil
 
 	return o.userRepo.Update(c, user)
 }
 
-func (o *userServ) Select(c *fiber.Ctx, userFilter *input.UserFilter) ([]output.UserOutput, error) {
-	user := &model.User{}
-	err := copier.CopyProperties(userFilter, user)
+func (o *userServ) Select(c *fiber.Ctx, userFilter *input.UserFilter, opts *dbutil.ListOptions) ([]output.UserOutput, error) {
+	conds, err := dbutil.FilterConds(userFilter)
 	if err != nil {
 		return nil, err
 	}
-	users, err := o.userRepo.Select(c, user)
+	users, err := o.userRepo.WithListOptions(opts).SelectWhere(c, conds...)
 	if err != nil {
 		return nil, err
 	}
@@ -82,8 +102,24 @@ func (o *userServ) SelectById(c *fiber.Ctx, id int) (*output.UserOutput, error)
 	return &userOutputs, err
 }
 
-func (o *userServ) SelectWithPagination(c *fiber.Ctx, p *model.Pagination) error {
-	err := o.userRepo.SelectWithPagination(c, p)
+// Me 当前登录用户的信息与角色
+func (o *userServ) Me(c *fiber.Ctx) (*output.MeOutput, error) {
+	principal := middleware.CurrentUser(c)
+	if principal == nil {
+		return nil, code.AuthFailed
+	}
+	user, err := o.SelectById(c, principal.UserId)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 令牌签发后用户已被删除
+		return nil, code.AuthFailed
+	} else if err != nil {
+		return nil, err
+	}
+	return &output.MeOutput{UserOutput: *user, Roles: principal.Roles}, nil
+}
+
+func (o *userServ) SelectWithPagination(c *fiber.Ctx, p *model.Pagination, opts *dbutil.ListOptions) error {
+	err := o.userRepo.WithListOptions(opts).SelectWithPagination(c, p)
 	if err != nil || p.Data == nil {
 		return err
 	}
@@ -97,34 +133,193 @@ func (o *userServ) SelectWithPagination(c *fiber.Ctx, p *model.Pagination) error
 	return nil
 }
 
-func (o *userServ) Login(c *fiber.Ctx, userLogin *input.UserLogin) (string, error) {
-	userDB, err := o.userRepo.SelectByUsername(c, *userLogin.Username)
+func (o *userServ) SelectWithCursor(c *fiber.Ctx, userFilter *input.UserFilter, opts *dbutil.ListOptions, p *model.CursorPagination) error {
+	conds, err := dbutil.FilterConds(userFilter)
+	if err != nil {
+		return err
+	}
+	err = o.userRepo.WithListOptions(opts).SelectWithCursor(c, p, conds...)
+	if errors.Is(err, dbutil.ErrInvalidCursor) {
+		return code.ParamError
+	} else if err != nil {
+		return err
+	}
+	var userOutputs []output.UserOutput
+	err = copier.TransferListType(p.Data.([]model.User), &userOutputs)
 	if err != nil {
-		return "", err
+		return err
 	}
+	p.Data = userOutputs
+	return nil
+}
+
+func (o *userServ) SelectTrashed(c *fiber.Ctx, opts *dbutil.ListOptions) ([]output.UserOutput, error) {
+	users, err := o.userRepo.OnlyTrashed().WithListOptions(opts).SelectWhere(c)
+	if err != nil {
+		return nil, err
+	}
+	var userOutputs []output.UserOutput
+	err = copier.TransferListType(users, &userOutputs)
+	if err != nil {
+		return nil, err
+	}
+	return userOutputs, nil
+}
+
+func (o *userServ) Restore(c *fiber.Ctx, id int) error {
+	return o.userRepo.Restore(c, id)
+}
+
+func (o *userServ) Login(c *fiber.Ctx, userLogin *input.UserLogin) (*output.TokenOutput, error) {
+	if err := o.checkIpAllowed(c); err != nil {
+		return nil, err
+	}
+	// 凭据不经过缓存直接从主库读取，刚注册或修改密码的用户不会因缓存或副本延迟登录失败或使用旧密码登录
+	// 之后的查询 (如签发令牌时读取角色) 同样使用主库
+	db.ReadYourWrites(c)
+	userDB, err := o.userRepo.SelectCredentialsByUsername(c, *userLogin.Username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, o.loginFailed(c, *userLogin.Username, nil)
+	} else if err != nil {
+		return nil, err
+	}
+	// 先校验密码，密码正确后才返回锁定状态
 	if err := bcrypt.CompareHashAndPassword([]byte(*userDB.Password), []byte(*userLogin.Password)); err != nil {
 		log.F(c).Error(err)
-		return "", err
+		return nil, o.loginFailed(c, *userLogin.Username, userDB)
+	}
+	if userDB.LockedUntil != nil && time.Now().Before(*userDB.LockedUntil) {
+		return nil, lockedError(*userDB.LockedUntil)
+	}
+	if err := o.attemptRepo.Reset(c, userAttemptKey(*userLogin.Username)); err != nil {
+		log.F(c).Error(err)
 	}
-	token, err := middleware.GenerateJwt(*userLogin.Username)
+	return issueOrChallenge(c, o.tokenServ, userDB)
+}
+
+// issueOrChallenge 签发令牌；已启用两步验证时只返回两步验证登录令牌，需通过 /login/mfa 完成登录
+func issueOrChallenge(c *fiber.Ctx, tokenServ TokenServ, user *model.User) (*output.TokenOutput, error) {
+	if user.MfaEnabled() {
+		mfaToken, err := middleware.GenerateMfaJwt(*user.Id)
+		if err != nil {
+			log.F(c).Error(err)
+			return nil, code.TokenGenerateFailed.Wrap(err)
+		}
+		return &output.TokenOutput{MfaToken: mfaToken}, nil
+	}
+	return tokenServ.Issue(c, user)
+}
+
+// LoginMfa 使用两步验证登录令牌与验证码或恢复码完成登录，验证失败与密码错误一样计数并锁定账户
+func (o *userServ) LoginMfa(c *fiber.Ctx, in *input.MfaLogin) (*output.TokenOutput, error) {
+	if err := o.checkIpAllowed(c); err != nil {
+		return nil, err
+	}
+	db.ReadYourWrites(c)
+	userId, err := middleware.ParseMfaJwt(in.MfaToken)
+	if err != nil {
+		return nil, err
+	}
+	userDB, err := o.userRepo.SelectCredentials(c, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, code.AuthFailed
+	} else if err != nil {
+		return nil, err
+	}
+	if userDB.LockedUntil != nil && time.Now().Before(*userDB.LockedUntil) {
+		return nil, lockedError(*userDB.LockedUntil)
+	}
+	if !userDB.MfaEnabled() {
+		// 签发登录令牌后两步验证已被关闭，需重新登录
+		return nil, code.AuthFailed
+	}
+	ok, err := o.mfaServ.Verify(c, userDB, in.Code)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if !ok {
+		if err := o.loginFailed(c, *userDB.Username, userDB); !errors.Is(err, code.UsernameOrPasswordFailed) {
+			return nil, err
+		}
+		return nil, code.MfaCodeInvalid
+	}
+	if err := o.attemptRepo.Reset(c, userAttemptKey(*userDB.Username)); err != nil {
+		log.F(c).Error(err)
 	}
-	return token, nil
+	return o.tokenServ.Issue(c, userDB)
 }
 
-func (o *userServ) Register(c *fiber.Ctx, userRegister *input.UserRegister) error {
-	var user model.User
-	err := copier.CopyProperties(userRegister, &user)
+// Unlock 解除账户锁定并清空失败次数
+func (o *userServ) Unlock(c *fiber.Ctx, id int) error {
+	user, err := o.userRepo.SelectById(c, id)
 	if err != nil {
 		return err
 	}
-	password, err := bcrypt.GenerateFromPassword([]byte(*userRegister.Password), bcrypt.DefaultCost)
+	if err := o.userRepo.Unlock(c, id); err != nil {
+		return err
+	}
+	return o.attemptRepo.Reset(c, userAttemptKey(*user.Username))
+}
+
+func (o *userServ) Register(c *fiber.Ctx, userRegister *input.UserRegister) error {
+	_, err := o.createUser(c, *userRegister.Username, *userRegister.Password)
+	return err
+}
+
+// createUser 校验密码策略并保存密码摘要，在同一事务中创建用户并分配默认角色，新增与注册用户共用
+func (o *userServ) createUser(c *fiber.Ctx, username, password string) (*model.User, error) {
+	if err := checkPassword(password, username); err != nil {
+		return nil, err
+	}
+	hashed, err := hashPassword(c, password)
 	if err != nil {
-		log.F(c).Error(err)
+		return nil, err
+	}
+	user := &model.User{Username: &username, Password: &hashed}
+	err = db.WithFiberTx(c, func(c *fiber.Ctx) error {
+		if err := o.userRepo.Insert(c, user); err != nil {
+			return err
+		}
+		return o.assignDefaultRole(c, *user.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// assignDefaultRole 为新用户分配 rbac.defaultRole 角色，未配置或角色不存在时不分配
+func (o *userServ) assignDefaultRole(c *fiber.Ctx, userId int) error {
+	if conf.Rbac.DefaultRole == "" {
+		return nil
+	}
+	role, err := o.roleRepo.SelectByName(c, conf.Rbac.DefaultRole)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.F(c).Warnf("default role %s not found", conf.Rbac.DefaultRole)
+		return nil
+	} else if err != nil {
 		return err
 	}
-	user.Password = util.EnPointer(string(password))
+	return o.roleRepo.SetUserRoles(c, userId, []int{*role.Id})
+}
 
-	return o.userRepo.Insert(c, &user)
+// authorizeOwner 当前用户是资源所有者 ownerId 或拥有 perm 权限时通过
+func authorizeOwner(c *fiber.Ctx, ownerId int, perm string) error {
+	principal := middleware.CurrentUser(c)
+	if principal == nil {
+		return code.AuthFailed
+	}
+	// API 密钥以所属用户的身份访问时，授权范围也需包含 perm
+	if principal.UserId == ownerId && principal.Allows(perm) {
+		return nil
+	}
+	ok, err := middleware.HasPermission(c, perm)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		log.F(c).Warnf("user %d is not allowed to modify resource of user %d", principal.UserId, ownerId)
+		return code.PermissionDenied
+	}
+	return nil
 }
@@ -0,0 +1,127 @@
+package serv
+
+import (
+	"app/code"
+	"app/conf"
+	"app/middleware"
+	"app/model"
+	"app/model/input"
+	"app/repo"
+	"app/util"
+	"app/util/jwtutil"
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// authCtx 返回携带 user 访问令牌的请求上下文，与 JwtAuth 校验通过后一致
+func authCtx(t *testing.T, app *fiber.App, tokenServ TokenServ, user *model.User) *fiber.Ctx {
+	tokens, err := tokenServ.Issue(newCtx(app), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwtutil.Current().Parse(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newCtx(app)
+	c.Locals("user", token)
+	return c
+}
+
+func Test_UserOwnership(t *testing.T) {
+	app, user := initEnv(t)
+	userRepo, roleRepo := repo.NewUserRepo(), repo.NewRoleRepo()
+	tokenServ := NewTokenService(userRepo, repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), roleRepo)
+	userServ := NewUserService(userRepo, roleRepo, repo.NewLoginAttemptRepo(), NewMfaService(userRepo, repo.NewRecoveryCodeRepo()), tokenServ)
+
+	other := &model.User{
+		Username: util.EnPointer("other_" + util.RandString(8)),
+		Password: util.EnPointer("password"),
+	}
+	if err := userRepo.Insert(nil, other); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *other.Id) })
+
+	c := authCtx(t, app, tokenServ, user)
+	if p := middleware.CurrentUser(c); p == nil || p.UserId != *user.Id || p.Username != *user.Username {
+		t.Fatalf("unexpected principal: %+v", p)
+	}
+	me, err := userServ.Me(c)
+	if err != nil || *me.Id != *user.Id {
+		t.Fatalf("unexpected me: %+v %v", me, err)
+	}
+
+	if err := userServ.Update(c, &model.User{Id: user.Id, Username: util.EnPointer(*user.Username + "_1")}); err != nil {
+		t.Fatalf("user should update self, got %v", err)
+	}
+	// 用户账户重复时返回 Conflict 而不是 ServerError
+	if err := userServ.Update(c, &model.User{Id: user.Id, Username: other.Username}); code.From(err).Err != code.Conflict {
+		t.Fatalf("duplicate username should conflict, got %v", err)
+	}
+	if err := userServ.Update(c, &model.User{Id: other.Id, Username: util.EnPointer("hacked")}); !errors.Is(err, code.PermissionDenied) {
+		t.Fatalf("user should not update others, got %v", err)
+	}
+	if err := userServ.Update(newCtx(app), &model.User{Id: other.Id}); !errors.Is(err, code.AuthFailed) {
+		t.Fatalf("anonymous update should fail, got %v", err)
+	}
+
+	// 管理员可以更新其他用户
+	admin, err := roleRepo.SelectByName(nil, model.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := roleRepo.SetUserRoles(nil, *user.Id, []int{*admin.Id}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = roleRepo.SetUserRoles(nil, *user.Id, nil) })
+	c = authCtx(t, app, tokenServ, user)
+	if !middleware.CurrentUser(c).HasRole(model.RoleAdmin) {
+		t.Fatal("principal should carry admin role")
+	}
+	if err := userServ.Update(c, &model.User{Id: other.Id, Username: util.EnPointer(*other.Username + "_1")}); err != nil {
+		t.Fatalf("admin should update others, got %v", err)
+	}
+}
+
+func Test_UserCreate(t *testing.T) {
+	app, _ := initEnv(t)
+	userRepo, roleRepo := repo.NewUserRepo(), repo.NewRoleRepo()
+	tokenServ := NewTokenService(userRepo, repo.NewRefreshTokenRepo(), repo.NewTokenBlacklistRepo(), roleRepo)
+	userServ := NewUserService(userRepo, roleRepo, repo.NewLoginAttemptRepo(), NewMfaService(userRepo, repo.NewRecoveryCodeRepo()), tokenServ)
+
+	// 新增与注册用户使用相同的密码策略并分配默认角色
+	inserted := &model.User{Username: util.EnPointer("create_" + util.RandString(8)), Password: util.EnPointer("password")}
+	if err := userServ.Insert(newCtx(app), inserted); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *inserted.Id) })
+	registered := &input.UserRegister{Username: util.EnPointer("register_" + util.RandString(8)), Password: util.EnPointer("password")}
+	if err := userServ.Register(newCtx(app), registered); err != nil {
+		t.Fatal(err)
+	}
+	user, err := userRepo.SelectByUsername(nil, *registered.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = userRepo.ForceDelete(nil, *user.Id) })
+	for _, u := range []*model.User{inserted, user} {
+		if *u.Password == "password" {
+			t.Fatalf("password of %s should be hashed", *u.Username)
+		}
+		roles, err := roleRepo.SelectByUserId(nil, *u.Id)
+		if conf.Rbac.DefaultRole != "" && (err != nil || len(roles) != 1 || *roles[0].Name != conf.Rbac.DefaultRole) {
+			t.Fatalf("%s should have the default role: %+v %v", *u.Username, roles, err)
+		}
+	}
+
+	weak := util.EnPointer(util.RandString(conf.Password.MinLength - 1))
+	if err := userServ.Insert(newCtx(app), &model.User{Username: util.EnPointer("weak_" + util.RandString(8)), Password: weak}); !errors.Is(err, code.WeakPassword) {
+		t.Fatalf("weak password should be rejected on insert, got %v", err)
+	}
+	if err := userServ.Register(newCtx(app), &input.UserRegister{Username: util.EnPointer("weak_" + util.RandString(8)), Password: weak}); !errors.Is(err, code.WeakPassword) {
+		t.Fatalf("weak password should be rejected on register, got %v", err)
+	}
+}
@@ -3,12 +3,16 @@ package server
 import (
 	v1 "app/api/http/v1"
 	"app/api/http/v1/auth"
+	"app/cache"
 	"app/conf"
 	"app/db"
 	"app/i18n"
 	"app/log"
 	"app/middleware"
+	"app/notify"
 	"app/scheduler"
+	"app/util/httputil"
+	"app/util/jwtutil"
 	"context"
 	"os"
 	"os/signal"
@@ -22,7 +26,6 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/healthcheck"
-	"github.com/gofiber/fiber/v2/middleware/monitor"
 )
 
 func NewServer() (*Server, error) {
@@ -30,28 +33,65 @@ func NewServer() (*Server, error) {
 	conf.Initialize()
 	log.Initialize()
 	db.Initialize()
+	cache.Initialize()
 	i18n.Initialize()
 	scheduler.Initialize()
+	jwtutil.Initialize()
+	httputil.RegisterParserDecoder()
 
 	// 初始化数据库
 	userRepo := repo.NewUserRepo()
+	refreshTokenRepo := repo.NewRefreshTokenRepo()
+	tokenBlacklistRepo := repo.NewTokenBlacklistRepo()
+	roleRepo := repo.NewRoleRepo()
+	permissionRepo := repo.NewPermissionRepo()
+	apiKeyRepo := repo.NewApiKeyRepo()
+	userIdentityRepo := repo.NewUserIdentityRepo()
+	recoveryCodeRepo := repo.NewRecoveryCodeRepo()
+	passwordResetRepo := repo.NewPasswordResetRepo()
+	loginAttemptRepo := repo.NewLoginAttemptRepo()
 	repos := []repo.BaseRepo{
 		userRepo,
+		refreshTokenRepo,
+		tokenBlacklistRepo,
+		roleRepo,
+		permissionRepo,
+		apiKeyRepo,
+		userIdentityRepo,
+		recoveryCodeRepo,
+		passwordResetRepo,
 	}
 
 	// 初始化服务
-	userService := serv.NewUserService(userRepo)
+	tokenService := serv.NewTokenService(userRepo, refreshTokenRepo, tokenBlacklistRepo, roleRepo)
+	mfaService := serv.NewMfaService(userRepo, recoveryCodeRepo)
+	userService := serv.NewUserService(userRepo, roleRepo, loginAttemptRepo, mfaService, tokenService)
+	passwordService := serv.NewPasswordService(userRepo, passwordResetRepo, refreshTokenRepo, loginAttemptRepo, notify.New())
+	roleService := serv.NewRoleService(roleRepo, permissionRepo, userRepo)
+	apiKeyService := serv.NewApiKeyService(apiKeyRepo, permissionRepo, userRepo)
+	oidcService := serv.NewOidcService(userRepo, userIdentityRepo, repo.NewOidcStateRepo(), userService, tokenService)
 	services := []serv.BaseServ{
+		tokenService,
+		mfaService,
 		userService,
+		passwordService,
+		roleService,
+		apiKeyService,
+		oidcService,
 	}
 
 	// 初始化API
-	commonController := v1.NewCommonController(userService)
+	commonController := v1.NewCommonController(userService, tokenService)
 	controllers := []v1.BaseContro{
 		commonController,
+		v1.NewOidcController(oidcService),
+		v1.NewPasswordController(passwordService),
 	}
 	authControllers := []v1.BaseContro{
 		auth.NewUserController(userService),
+		auth.NewRoleController(roleService),
+		auth.NewApiKeyController(apiKeyService),
+		auth.NewMfaController(mfaService),
 	}
 
 	// 初始化Fiber
@@ -63,13 +103,14 @@ func NewServer() (*Server, error) {
 	app.Use(middleware.TraceId())
 	app.Use(middleware.Logger())
 	app.Use(cors.New())
-	app.Use(middleware.Limiter())
 	app.Use(middleware.CircuitBreaker())
 	app.Use(middleware.ErrorParse())
+	app.Use(middleware.Limiter())
 	app.Use(middleware.Swagger())
 	app.Use(healthcheck.New())
 	app.Hooks().OnRoute(middleware.HookRoute)
-	app.Get("/metrics", monitor.New())
+	app.Get("/metrics", middleware.Metrics())
+	app.Get("/.well-known/jwks.json", middleware.Jwks())
 
 	return &Server{
 		engine:          app,
@@ -89,10 +130,11 @@ type Server struct {
 }
 
 func (s *Server) Close() {
-	if db.DB != nil {
-		if err := db.DB.Close(); err != nil {
-			log.Error(err)
-		}
+	if err := cache.Close(); err != nil {
+		log.Error(err)
+	}
+	if err := db.Close(); err != nil {
+		log.Error(err)
 	}
 	if conf.Redis.Enable {
 		if err := db.RDB.Close(); err != nil {
@@ -0,0 +1,26 @@
+package cryptoutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_EncryptDecrypt(t *testing.T) {
+	ciphertext, err := Encrypt("key", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other, _ := Encrypt("key", "JBSWY3DPEHPK3PXP"); other == ciphertext {
+		t.Fatal("ciphertext should use a random nonce")
+	}
+	plaintext, err := Decrypt("key", ciphertext)
+	if err != nil || plaintext != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("unexpected plaintext: %q %v", plaintext, err)
+	}
+	if _, err := Decrypt("other", ciphertext); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("wrong key should fail, got %v", err)
+	}
+	if _, err := Decrypt("key", "!"); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("malformed ciphertext should fail, got %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+package dbutil
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Cond 可组合的 WHERE 条件，统一生成 ? 占位符与按顺序排列的绑定参数
+// 最终占位符形式 ($1, @p1 ...) 由 Builder 根据方言转换
+type Cond interface {
+	Build(d Dialect) (string, []any)
+}
+
+// condFunc 将函数适配为 Cond
+type condFunc func(d Dialect) (string, []any)
+
+func (f condFunc) Build(d Dialect) (string, []any) { return f(d) }
+
+// likeEscape LIKE 转义字符，使用 ! 避免不同数据库对反斜杠的处理差异
+const likeEscape = "!"
+
+// quoteIdent 引用列名，支持 "table.column" 形式
+func quoteIdent(q Quoter, name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = q.Quote(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+func compare(column, op string, value any) Cond {
+	return condFunc(func(d Dialect) (string, []any) {
+		return fmt.Sprintf("%s %s ?", quoteIdent(d, column), op), []any{value}
+	})
+}
+
+// Eq column = value
+func Eq(column string, value any) Cond { return compare(column, "=", value) }
+
+// Ne column <> value
+func Ne(column string, value any) Cond { return compare(column, "<>", value) }
+
+// Gt column > value
+func Gt(column string, value any) Cond { return compare(column, ">", value) }
+
+// Gte column >= value
+func Gte(column string, value any) Cond { return compare(column, ">=", value) }
+
+// Lt column < value
+func Lt(column string, value any) Cond { return compare(column, "<", value) }
+
+// Lte column <= value
+func Lte(column string, value any) Cond { return compare(column, "<=", value) }
+
+// In column IN (?, ?, ...)，values 可以直接传入一个切片；为空时恒为假
+func In(column string, values ...any) Cond {
+	return in(column, "IN", "1 = 0", values)
+}
+
+// NotIn column NOT IN (?, ?, ...)，values 可以直接传入一个切片；为空时恒为真
+func NotIn(column string, values ...any) Cond {
+	return in(column, "NOT IN", "1 = 1", values)
+}
+
+func in(column, op, empty string, values []any) Cond {
+	values = flatten(values)
+	return condFunc(func(d Dialect) (string, []any) {
+		if len(values) == 0 {
+			return empty, nil
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+		return fmt.Sprintf("%s %s (%s)", quoteIdent(d, column), op, placeholders), values
+	})
+}
+
+// InSelect column IN (SELECT selected FROM table WHERE conds)，conds 为空时不带 WHERE
+// e.g., InSelect("id", "user_role", "role_id", Eq("user_id", 1))
+func InSelect(column, table, selected string, conds ...Cond) Cond {
+	return condFunc(func(d Dialect) (string, []any) {
+		sql := fmt.Sprintf("%s IN (SELECT %s FROM %s", quoteIdent(d, column), quoteIdent(d, selected), quoteTable(d, table))
+		if len(conds) == 0 {
+			return sql + ")", nil
+		}
+		where, args := And(conds...).Build(d)
+		return sql + " WHERE " + where + ")", args
+	})
+}
+
+// Like column LIKE pattern，pattern 中的通配符由调用方负责
+func Like(column string, pattern string) Cond {
+	return condFunc(func(d Dialect) (string, []any) {
+		return fmt.Sprintf("%s LIKE ?", quoteIdent(d, column)), []any{pattern}
+	})
+}
+
+// Contains column 包含 s，s 中的 % 与 _ 会被转义
+func Contains(column, s string) Cond { return escapedLike(column, "%"+EscapeLike(s)+"%") }
+
+// HasPrefix column 以 s 开头，s 中的 % 与 _ 会被转义
+func HasPrefix(column, s string) Cond { return escapedLike(column, EscapeLike(s)+"%") }
+
+// HasSuffix column 以 s 结尾，s 中的 % 与 _ 会被转义
+func HasSuffix(column, s string) Cond { return escapedLike(column, "%"+EscapeLike(s)) }
+
+func escapedLike(column, pattern string) Cond {
+	return condFunc(func(d Dialect) (string, []any) {
+		return fmt.Sprintf("%s LIKE ? ESCAPE '%s'", quoteIdent(d, column), likeEscape), []any{pattern}
+	})
+}
+
+// EscapeLike 转义 LIKE 模式中的通配符
+func EscapeLike(s string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(s)
+}
+
+// Between column BETWEEN from AND to
+func Between(column string, from, to any) Cond {
+	return condFunc(func(d Dialect) (string, []any) {
+		return fmt.Sprintf("%s BETWEEN ? AND ?", quoteIdent(d, column)), []any{from, to}
+	})
+}
+
+// IsNull column IS NULL
+func IsNull(column string) Cond {
+	return condFunc(func(d Dialect) (string, []any) {
+		return quoteIdent(d, column) + " IS NULL", nil
+	})
+}
+
+// IsNotNull column IS NOT NULL
+func IsNotNull(column string) Cond {
+	return condFunc(func(d Dialect) (string, []any) {
+		return quoteIdent(d, column) + " IS NOT NULL", nil
+	})
+}
+
+// RowLt 行值比较 (c1, c2) < (?, ?)，用于键集分页
+// 不支持行值比较的数据库展开为 c1 < ? OR (c1 = ? AND c2 < ?)
+func RowLt(columns []string, values []any) Cond { return rowCompare(columns, "<", values) }
+
+// RowGt 行值比较 (c1, c2) > (?, ?)，用于键集分页
+func RowGt(columns []string, values []any) Cond { return rowCompare(columns, ">", values) }
+
+func rowCompare(columns []string, op string, values []any) Cond {
+	if len(columns) != len(values) {
+		panic(fmt.Sprintf("dbutil: row compare expects %d values, got %d", len(columns), len(values)))
+	}
+	return condFunc(func(d Dialect) (string, []any) {
+		if len(columns) == 0 {
+			return "1 = 1", nil
+		}
+		if len(columns) == 1 || d.SupportsRowValues() {
+			quoted := make([]string, len(columns))
+			for i, c := range columns {
+				quoted[i] = quoteIdent(d, c)
+			}
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+			if len(columns) == 1 {
+				return fmt.Sprintf("%s %s ?", quoted[0], op), values
+			}
+			return fmt.Sprintf("(%s) %s (%s)", strings.Join(quoted, ", "), op, placeholders), values
+		}
+		// 展开为: c1 op v1 OR (c1 = v1 AND c2 op v2) OR ...
+		var ors []Cond
+		for i := range columns {
+			var ands []Cond
+			for j := 0; j < i; j++ {
+				ands = append(ands, Eq(columns[j], values[j]))
+			}
+			ands = append(ands, compare(columns[i], op, values[i]))
+			ors = append(ors, And(ands...))
+		}
+		return Or(ors...).Build(d)
+	})
+}
+
+// Raw 原样使用的条件片段，使用 ? 作为占位符
+func Raw(sql string, args ...any) Cond {
+	return condFunc(func(d Dialect) (string, []any) {
+		return sql, args
+	})
+}
+
+// And 用 AND 连接多个条件，为空时恒为真
+func And(conds ...Cond) Cond { return group(" AND ", "1 = 1", conds) }
+
+// Or 用 OR 连接多个条件，为空时恒为假
+func Or(conds ...Cond) Cond { return group(" OR ", "1 = 0", conds) }
+
+func group(sep, empty string, conds []Cond) Cond {
+	return condFunc(func(d Dialect) (string, []any) {
+		var clauses []string
+		var args []any
+		for _, c := range conds {
+			if c == nil {
+				continue
+			}
+			sql, a := c.Build(d)
+			clauses = append(clauses, sql)
+			args = append(args, a...)
+		}
+		switch len(clauses) {
+		case 0:
+			return empty, nil
+		case 1:
+			return clauses[0], args
+		}
+		return "(" + strings.Join(clauses, sep) + ")", args
+	})
+}
+
+// Not 对条件取反
+func Not(cond Cond) Cond {
+	return condFunc(func(d Dialect) (string, []any) {
+		sql, args := cond.Build(d)
+		return "NOT (" + sql + ")", args
+	})
+}
+
+// flatten 展开作为单个参数传入的切片，使 In("id", ids) 与 In("id", 1, 2) 等价
+func flatten(values []any) []any {
+	if len(values) != 1 {
+		return values
+	}
+	v := reflect.ValueOf(values[0])
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return values
+	}
+	result := make([]any, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		result[i] = v.Index(i).Interface()
+	}
+	return result
+}
+
+// FilterConds 根据过滤结构体的 db 与 filter 标签生成条件，nil 指针、零值与空切片会被忽略
+// 支持的 filter 标签: eq(默认), ne, gt, gte, lt, lte, like(包含), prefix, suffix, in, notin, between(两个元素的切片，否则忽略), null(bool)
+// 用法:
+//
+//	type UserFilter struct {
+//		Username *string `db:"username" filter:"like"`
+//		Ids      []int   `db:"id" filter:"in"`
+//	}
+//
+// 未知的 filter 标签返回错误，与字段是否为零值无关，因此在开发时的首次调用即可发现
+func FilterConds(filter any) ([]Cond, error) {
+	if filter == nil {
+		return nil, nil
+	}
+	v := deReference(filter)
+	if v.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	t := v.Type()
+	var conds []Cond
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		column := strings.Split(field.Tag.Get("db"), ",")[0]
+		if column == "" || column == "-" || !field.IsExported() {
+			continue
+		}
+		op := field.Tag.Get("filter")
+		if !filterOps[op] {
+			return nil, fmt.Errorf("dbutil: unknown filter %q on %s.%s", op, t.Name(), field.Name)
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		} else if fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Slice && fv.Len() == 0 {
+			continue
+		}
+		if cond := filterCond(column, op, fv); cond != nil {
+			conds = append(conds, cond)
+		}
+	}
+	return conds, nil
+}
+
+// filterOps 支持的 filter 标签
+var filterOps = map[string]bool{
+	"": true, "eq": true, "ne": true, "gt": true, "gte": true, "lt": true, "lte": true, "like": true,
+	"prefix": true, "suffix": true, "in": true, "notin": true, "between": true, "null": true,
+}
+
+func filterCond(column, op string, v reflect.Value) Cond {
+	value := v.Interface()
+	switch op {
+	case "", "eq":
+		return Eq(column, value)
+	case "ne":
+		return Ne(column, value)
+	case "gt":
+		return Gt(column, value)
+	case "gte":
+		return Gte(column, value)
+	case "lt":
+		return Lt(column, value)
+	case "lte":
+		return Lte(column, value)
+	case "like":
+		return Contains(column, fmt.Sprint(value))
+	case "prefix":
+		return HasPrefix(column, fmt.Sprint(value))
+	case "suffix":
+		return HasSuffix(column, fmt.Sprint(value))
+	case "in":
+		return In(column, value)
+	case "notin":
+		return NotIn(column, value)
+	case "between":
+		if v.Kind() != reflect.Slice || v.Len() != 2 {
+			return nil
+		}
+		return Between(column, v.Index(0).Interface(), v.Index(1).Interface())
+	default: // null
+		if v.Kind() == reflect.Bool && !v.Bool() {
+			return IsNotNull(column)
+		}
+		return IsNull(column)
+	}
+}
@@ -0,0 +1,90 @@
+package dbutil
+
+import (
+	"app/model/input"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConditions(t *testing.T) {
+	cases := []struct {
+		cond Cond
+		sql  string
+		args []any
+	}{
+		{Eq("id", 1), `"id" = ?`, []any{1}},
+		{Ne("u.id", 1), `"u"."id" <> ?`, []any{1}},
+		{In("id", 1, 2, 3), `"id" IN (?, ?, ?)`, []any{1, 2, 3}},
+		{In("id", []int{1, 2}), `"id" IN (?, ?)`, []any{1, 2}},
+		{In("id"), `1 = 0`, nil},
+		{NotIn("id", []int{}), `1 = 1`, nil},
+		{Like("username", "a%"), `"username" LIKE ?`, []any{"a%"}},
+		{Contains("username", "50%_off!"), `"username" LIKE ? ESCAPE '!'`, []any{"%50!%!_off!!%"}},
+		{HasPrefix("username", "ad"), `"username" LIKE ? ESCAPE '!'`, []any{"ad%"}},
+		{Between("id", 1, 10), `"id" BETWEEN ? AND ?`, []any{1, 10}},
+		{IsNull("deleted_at"), `"deleted_at" IS NULL`, nil},
+		{IsNotNull("deleted_at"), `"deleted_at" IS NOT NULL`, nil},
+		{Or(Eq("id", 1), And(Gt("id", 5), Lte("id", 9))), `("id" = ? OR ("id" > ? AND "id" <= ?))`, []any{1, 5, 9}},
+		{Not(Or(IsNull("deleted_at"), Eq("id", 1))), `NOT (("deleted_at" IS NULL OR "id" = ?))`, []any{1}},
+		{Raw("LENGTH(username) > ?", 3), `LENGTH(username) > ?`, []any{3}},
+		{Or(), `1 = 0`, nil},
+		{InSelect("id", "user_role", "role_id", Eq("user_id", 1)), `"id" IN (SELECT "role_id" FROM "user_role" WHERE "user_id" = ?)`, []any{1}},
+		{InSelect("id", "role", "id"), `"id" IN (SELECT "id" FROM "role")`, nil},
+	}
+	for _, c := range cases {
+		sql, args := c.cond.Build(SqliteDialect)
+		if sql != c.sql || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("got %s %v, want %s %v", sql, args, c.sql, c.args)
+		}
+	}
+}
+
+func TestBuilderWhere(t *testing.T) {
+	b := NewBuilder(nil).
+		WithDialect(PostgresDialect).
+		Where(Contains("username", "ad"), Or(In("id", 1, 2), IsNull("deleted_at"))).
+		WithOrderBy("id")
+	query := b.BuildSelectQuery("user")
+	want := `SELECT  FROM "user" WHERE "username" LIKE $1 ESCAPE '!' AND ("id" IN ($2, $3) OR "deleted_at" IS NULL) ORDER BY id`
+	if query != want {
+		t.Errorf("\n got: %s\nwant: %s", query, want)
+	}
+	if args := b.Args(); !reflect.DeepEqual(args, []any{"%ad%", 1, 2}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestFilterConds(t *testing.T) {
+	username := "ad"
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := input.UserFilter{
+		Username:    &username,
+		Ids:         []int{1, 2},
+		CreatedFrom: &from,
+	}
+	conds, err := FilterConds(&filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBuilder(nil).WithDialect(SqliteDialect).Where(conds...)
+	want := `"username" LIKE ? ESCAPE '!' AND "id" IN (?, ?) AND "created_at" >= ?`
+	if where := b.BuildWhereClauses(" AND "); where != want {
+		t.Errorf("\n got: %s\nwant: %s", where, want)
+	}
+	if args := b.Args(); !reflect.DeepEqual(args, []any{"%ad%", 1, 2, from}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	if conds, err := FilterConds(&input.UserFilter{}); err != nil || len(conds) != 0 {
+		t.Errorf("empty filter should not generate conditions, got %d %v", len(conds), err)
+	}
+
+	// 未知的标签即使字段为空也返回错误，而不是在请求中 panic
+	type badFilter struct {
+		Name *string `db:"name" filter:"contains"`
+	}
+	if _, err := FilterConds(&badFilter{}); err == nil {
+		t.Error("unknown filter tag should return an error")
+	}
+}
@@ -0,0 +1,103 @@
+package dbutil
+
+import (
+	"app/conf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidCursor 游标格式错误或签名校验失败
+var ErrInvalidCursor = errors.New("dbutil: invalid cursor")
+
+// Cursor 键集分页游标，记录翻页位置上一条记录的排序键值
+// 对外是不透明的字符串: base64(payload).base64(hmac-sha256(payload))，使用 conf.Server.Secret 签名，防止客户端篡改
+type Cursor struct {
+	Keys     []json.RawMessage `json:"k"`           // 排序键值，顺序与排序列一致
+	Backward bool              `json:"b,omitempty"` // 是否为向前翻页 (prev) 游标
+}
+
+// EncodeCursor 从记录 row 中读取 columns 列的值生成签名游标
+// e.g., EncodeCursor(&user, false, "created_at", "id")
+func EncodeCursor(row any, backward bool, columns ...string) (string, error) {
+	b := NewBuilder(row)
+	cursor := Cursor{Backward: backward}
+	for _, name := range columns {
+		v, ok := b.Column(name)
+		if !ok {
+			return "", fmt.Errorf("dbutil: cursor column %s not found", name)
+		}
+		key, err := json.Marshal(v.Interface())
+		if err != nil {
+			return "", err
+		}
+		cursor.Keys = append(cursor.Keys, key)
+	}
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return encodeSegment(payload) + "." + encodeSegment(sign(payload)), nil
+}
+
+// DecodeCursor 校验签名并解析游标
+func DecodeCursor(s string) (*Cursor, error) {
+	payloadPart, sigPart, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil || !hmac.Equal(sig, sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &Cursor{}
+	if err := json.Unmarshal(payload, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// Values 按 model 中 columns 列的字段类型解析排序键值，结果可直接作为 Seek 的 values
+// e.g., cursor.Values(&model.User{}, "created_at", "id")
+func (c *Cursor) Values(model any, columns ...string) ([]any, error) {
+	if len(c.Keys) != len(columns) {
+		return nil, ErrInvalidCursor
+	}
+	b := NewBuilder(model)
+	values := make([]any, len(columns))
+	for i, name := range columns {
+		v, ok := b.Column(name)
+		if !ok {
+			return nil, fmt.Errorf("dbutil: cursor column %s not found", name)
+		}
+		t := v.Type()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		value := reflect.New(t)
+		if err := json.Unmarshal(c.Keys[i], value.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = value.Elem().Interface()
+	}
+	return values, nil
+}
+
+func sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(conf.Server.Secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,80 @@
+package dbutil
+
+import (
+	"app/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 6, time.Local)
+	id := 42
+	user := &model.User{Id: &id, CreatedAt: &createdAt}
+
+	s, err := EncodeCursor(user, true, "created_at", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := DecodeCursor(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cursor.Backward {
+		t.Error("cursor direction should be kept")
+	}
+	values, err := cursor.Values(&model.User{}, "created_at", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := values[0].(time.Time); !ok || !got.Equal(createdAt) {
+		t.Errorf("unexpected created_at: %#v", values[0])
+	}
+	if got, ok := values[1].(int); !ok || got != id {
+		t.Errorf("unexpected id: %#v", values[1])
+	}
+	if _, err := cursor.Values(&model.User{}, "id"); err == nil {
+		t.Error("cursor with mismatched columns should be rejected")
+	}
+
+	// 篡改载荷或签名均应校验失败
+	payload, sig, _ := strings.Cut(s, ".")
+	tampered, _ := EncodeCursor(&model.User{Id: &id, CreatedAt: &createdAt}, false, "created_at", "id")
+	for _, invalid := range []string{"", "abc", payload, payload + "." + sig + "x", strings.Split(tampered, ".")[0] + "." + sig} {
+		if _, err := DecodeCursor(invalid); err != ErrInvalidCursor {
+			t.Errorf("cursor %q should be invalid, got %v", invalid, err)
+		}
+	}
+}
+
+func TestSeek(t *testing.T) {
+	values := []any{time.Now(), 10}
+	b := NewBuilder(&model.User{}).WithDialect(SqliteDialect).WithTrashed().OnlyNonZero().
+		Seek([]string{"created_at", "id"}, values, true).WithLimit(3)
+	query := b.BuildSelectQuery("user")
+	if !strings.HasSuffix(query, `WHERE ("created_at", "id") < (?, ?) ORDER BY "created_at" DESC, "id" DESC LIMIT 3`) {
+		t.Errorf("unexpected seek query: %s", query)
+	}
+	if len(b.Args()) != 2 {
+		t.Errorf("unexpected args: %v", b.Args())
+	}
+
+	query = NewBuilder(&model.User{}).WithDialect(PostgresDialect).WithTrashed().OnlyNonZero().
+		Seek([]string{"created_at", "id"}, values, false).BuildSelectQuery("user")
+	if !strings.HasSuffix(query, `WHERE ("created_at", "id") > ($1, $2) ORDER BY "created_at" ASC, "id" ASC`) {
+		t.Errorf("unexpected postgres seek query: %s", query)
+	}
+
+	// 首页没有游标值，只排序
+	query = NewBuilder(&model.User{}).WithDialect(SqliteDialect).WithTrashed().OnlyNonZero().
+		Seek([]string{"created_at", "id"}, nil, true).BuildSelectQuery("user")
+	if strings.Contains(query, "WHERE") || !strings.HasSuffix(query, `ORDER BY "created_at" DESC, "id" DESC`) {
+		t.Errorf("unexpected first page query: %s", query)
+	}
+
+	// SQL Server 不支持行值比较，展开为等价的 OR 条件
+	sql, args := RowLt([]string{"created_at", "id"}, values).Build(SqlServerDialect)
+	if sql != "([created_at] < ? OR ([created_at] = ? AND [id] < ?))" || len(args) != 3 {
+		t.Errorf("unexpected expanded row compare: %s %v", sql, args)
+	}
+}
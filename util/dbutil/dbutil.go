@@ -1,7 +1,6 @@
 package dbutil
 
 import (
-	"app/conf"
 	"fmt"
 	"reflect"
 	"strings"
@@ -33,9 +32,22 @@ type NoOpQuoter struct{}
 
 func (q NoOpQuoter) Quote(s string) string { return s }
 
+// SoftDeleteColumn 软删除列，结构体包含该列时 SELECT 默认排除已删除的记录
+const SoftDeleteColumn = "deleted_at"
+
+// TrashedScope 软删除记录的查询范围
+type TrashedScope int
+
+const (
+	TrashedExclude TrashedScope = iota // 排除已删除记录 (默认)
+	TrashedInclude                     // 包含已删除记录
+	TrashedOnly                        // 只查询已删除记录
+)
+
 // columnInfo 存储从结构体字段中提取的关键信息
 type columnInfo struct {
 	Name  string        // db tag 的值
+	Index int           // 字段在结构体中的下标
 	Value reflect.Value // 字段的 reflect.Value
 	IsPK  bool          // tag中是否包含 "pk"
 }
@@ -48,13 +60,13 @@ func parseStruct(v reflect.Value) []columnInfo {
 	t := v.Type()
 	// 检查类型缓存是否存在，如果存在则直接返回缓存的结果
 	if cached, ok := structCache.Load(t); ok {
-		cachedCols := cached.([]columnInfo)
-		cols := make([]columnInfo, len(cachedCols))
 		typeInfo := cached.([]columnInfo)
+		cols := make([]columnInfo, len(typeInfo))
 		for i := 0; i < len(typeInfo); i++ {
 			cols[i] = columnInfo{
 				Name:  typeInfo[i].Name,
-				Value: v.Field(i),
+				Index: typeInfo[i].Index,
+				Value: v.Field(typeInfo[i].Index),
 				IsPK:  typeInfo[i].IsPK,
 			}
 		}
@@ -78,8 +90,9 @@ func parseStruct(v reflect.Value) []columnInfo {
 			isPK = true
 		}
 		typeInfo = append(typeInfo, columnInfo{
-			Name: colName,
-			IsPK: isPK,
+			Name:  colName,
+			Index: i,
+			IsPK:  isPK,
 		})
 	}
 	structCache.Store(t, typeInfo)
@@ -89,8 +102,9 @@ func parseStruct(v reflect.Value) []columnInfo {
 	for i, info := range typeInfo {
 		cols[i] = columnInfo{
 			Name:  info.Name,
+			Index: info.Index,
 			IsPK:  info.IsPK,
-			Value: v.Field(i),
+			Value: v.Field(info.Index),
 		}
 	}
 	return cols
@@ -111,16 +125,23 @@ type Builder struct {
 	prefix      string
 	filters     []func(c columnInfo) bool
 	customWhere []string
+	conds       []Cond
+	fields      []string
+	trashed     TrashedScope
 	orderBy     string
-	limit       string
+	limit       int
+	offset      int
+	returning   []string
 	quoter      Quoter
+	dialect     Dialect
 }
 
-// NewBuilder 创建一个新的构建器实例
+// NewBuilder 创建一个新的构建器实例，方言根据 conf.DB.Type 自动选择
 func NewBuilder(o any) *Builder {
 	b := &Builder{
-		quoter: NoOpQuoter{},
+		limit: -1,
 	}
+	b.WithDialect(CurrentDialect())
 	if o == nil {
 		return b
 	}
@@ -129,12 +150,23 @@ func NewBuilder(o any) *Builder {
 		panic("dbutil: NewBuilder expects a struct or a pointer to a struct")
 	}
 	b.cols = parseStruct(v)
-	if strings.Contains(conf.DB.Type, "mysql") {
-		b = b.WithQuoter(BacktickQuoter{})
+	return b
+}
+
+// WithDialect 设置数据库方言，同时将标识符引用方式重置为该方言的引用方式
+func (b *Builder) WithDialect(d Dialect) *Builder {
+	if d != nil {
+		b.dialect = d
+		b.quoter = d
 	}
 	return b
 }
 
+// Dialect 返回构建器使用的数据库方言
+func (b *Builder) Dialect() Dialect {
+	return b.dialect
+}
+
 // WithQuoter 设置一个自定义的 Quoter
 func (b *Builder) WithQuoter(q Quoter) *Builder {
 	if q != nil {
@@ -172,6 +204,64 @@ func (b *Builder) WithCustomWhere(clauses ...string) *Builder {
 	return b
 }
 
+// Where 添加类型化的 WHERE 条件，多个条件之间使用 AND 连接，绑定参数通过 Args 获取
+// 条件使用位置占位符，不要与 OnlyNonZero 生成的命名占位符条件混用
+// e.g., Where(dbutil.Contains("username", "ad"), dbutil.In("id", 1, 2, 3))
+func (b *Builder) Where(conds ...Cond) *Builder {
+	b.conds = append(b.conds, conds...)
+	return b
+}
+
+// WithTrashed 查询时包含已软删除的记录
+func (b *Builder) WithTrashed() *Builder {
+	b.trashed = TrashedInclude
+	return b
+}
+
+// OnlyTrashed 查询时只返回已软删除的记录
+func (b *Builder) OnlyTrashed() *Builder {
+	b.trashed = TrashedOnly
+	return b
+}
+
+// WithTrashedScope 设置软删除记录的查询范围
+func (b *Builder) WithTrashedScope(scope TrashedScope) *Builder {
+	b.trashed = scope
+	return b
+}
+
+// IsSoftDelete 结构体是否包含软删除列
+func (b *Builder) IsSoftDelete() bool {
+	_, ok := b.Column(SoftDeleteColumn)
+	return ok
+}
+
+// softDeleteClause 根据查询范围生成软删除条件，结构体不包含软删除列时返回空
+func (b *Builder) softDeleteClause() string {
+	if !b.IsSoftDelete() {
+		return ""
+	}
+	switch b.trashed {
+	case TrashedInclude:
+		return ""
+	case TrashedOnly:
+		return b.prefix + b.quoter.Quote(SoftDeleteColumn) + " IS NOT NULL"
+	default:
+		return b.prefix + b.quoter.Quote(SoftDeleteColumn) + " IS NULL"
+	}
+}
+
+// Args 返回 Where 条件的绑定参数，顺序与生成 SQL 中的占位符一致
+// 自定义条件 (WithCustomWhere) 中 ? 占位符的参数需由调用方放在其前面
+func (b *Builder) Args() []any {
+	var args []any
+	for _, c := range b.conds {
+		_, a := c.Build(b.dialect)
+		args = append(args, a...)
+	}
+	return args
+}
+
 // WithOrderBy 添加ORDER BY子句
 // e.g., WithOrderBy("created_at DESC")
 func (b *Builder) WithOrderBy(orderBy string) *Builder {
@@ -179,18 +269,73 @@ func (b *Builder) WithOrderBy(orderBy string) *Builder {
 	return b
 }
 
-// WithLimit 添加LIMIT子句
+// Seek 键集分页：按 columns 排序并从 values 之后继续查询，values 为空时从头开始
+// desc 为 true 时生成 WHERE (c1, c2) < (?, ?) ORDER BY c1 DESC, c2 DESC，否则为 > 与 ASC
+// e.g., Seek([]string{"created_at", "id"}, []any{t, 10}, true)
+func (b *Builder) Seek(columns []string, values []any, desc bool) *Builder {
+	if len(values) > 0 {
+		if desc {
+			b.Where(RowLt(columns, values))
+		} else {
+			b.Where(RowGt(columns, values))
+		}
+	}
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+	orders := make([]string, len(columns))
+	for i, c := range columns {
+		orders[i] = b.prefix + quoteIdent(b.quoter, c) + direction
+	}
+	return b.WithOrderBy(strings.Join(orders, ", "))
+}
+
+// WithLimit 添加分页子句，具体语法由方言决定 (LIMIT n / OFFSET 0 ROWS FETCH NEXT n ROWS ONLY)
 func (b *Builder) WithLimit(limit int) *Builder {
-	b.limit = fmt.Sprintf("LIMIT %d", limit)
+	b.limit = limit
+	b.offset = 0
 	return b
 }
 
-// WithLimitOffset 添加LIMIT和OFFSET子句
+// WithLimitOffset 添加带偏移量的分页子句，具体语法由方言决定
 func (b *Builder) WithLimitOffset(limit, offset int) *Builder {
-	b.limit = fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+	b.limit = limit
+	b.offset = offset
 	return b
 }
 
+// PrimaryKey 返回标记为 pk 的列名及其字段值
+func (b *Builder) PrimaryKey() (string, reflect.Value, bool) {
+	for _, c := range b.cols {
+		if c.IsPK {
+			return c.Name, c.Value, true
+		}
+	}
+	return "", reflect.Value{}, false
+}
+
+// Column 按列名查找字段值
+func (b *Builder) Column(name string) (reflect.Value, bool) {
+	for _, c := range b.cols {
+		if c.Name == name {
+			return c.Value, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// WithReturning 为 INSERT 语句添加返回列，PostgreSQL 使用 RETURNING 子句，SQL Server 使用 OUTPUT INSERTED 子句
+func (b *Builder) WithReturning(columns ...string) *Builder {
+	b.returning = append(b.returning, columns...)
+	return b
+}
+
+// rebind 将自定义条件中的 ? 占位符转换为当前数据库的占位符形式 (如 PostgreSQL 的 $1)
+func (b *Builder) rebind(query string) string {
+	return b.dialect.Rebind(query)
+}
+
 // applyFilters 执行所有已注册的过滤器
 func (b *Builder) applyFilters() []columnInfo {
 	if b.cols == nil {
@@ -223,7 +368,7 @@ func (b *Builder) BuildColumns(separator string) string {
 	return strings.Join(names, separator)
 }
 
-// BuildColumnsWithAlias 生成带别名的列名列表，用于 SELECT 查询
+// BuildColumnsWithAlias 生成带别名的列名列表，用于 SELECT 查询，设置了 WithFields 时只包含指定的列
 // 用法: builder.WithPrefix("u.").BuildColumnsWithAlias(",") -> "u.id AS "id", u.name AS "name""
 func (b *Builder) BuildColumnsWithAlias(separator string) string {
 	if b.cols == nil {
@@ -231,6 +376,9 @@ func (b *Builder) BuildColumnsWithAlias(separator string) string {
 	}
 	var names []string
 	for _, c := range b.cols {
+		if len(b.fields) > 0 && !contains(b.fields, c.Name) {
+			continue
+		}
 		aliased := fmt.Sprintf(`%s%s AS "%s"`, b.prefix, b.quoter.Quote(c.Name), c.Name)
 		names = append(names, aliased)
 	}
@@ -260,6 +408,7 @@ func (b *Builder) BuildSetClauses(separator string) string {
 }
 
 // BuildWhereClauses 生成用于 WHERE 的条件子句, 智能合并自动生成和自定义的条件
+// 结构体包含软删除列时会按查询范围追加 deleted_at IS NULL / IS NOT NULL
 // 用法: builder.BuildWhereClauses(" AND ") -> "id=:id AND name=:name"
 func (b *Builder) BuildWhereClauses(separator string) string {
 	autoClauses := []string{}
@@ -269,6 +418,13 @@ func (b *Builder) BuildWhereClauses(separator string) string {
 	}
 
 	allClauses := append(autoClauses, b.customWhere...)
+	for _, c := range b.conds {
+		sql, _ := c.Build(b.dialect)
+		allClauses = append(allClauses, sql)
+	}
+	if clause := b.softDeleteClause(); clause != "" {
+		allClauses = append(allClauses, clause)
+	}
 
 	if len(allClauses) == 0 {
 		return ""
@@ -291,7 +447,7 @@ func (b *Builder) BuildSelectQuery(tableName string) string {
 
 	// 2. FROM table
 	sb.WriteString(" FROM ")
-	sb.WriteString(b.quoter.Quote(tableName))
+	sb.WriteString(quoteTable(b.quoter, tableName))
 
 	// 3. WHERE clause
 	whereClause := b.BuildWhereClauses(" AND ")
@@ -300,17 +456,136 @@ func (b *Builder) BuildSelectQuery(tableName string) string {
 		sb.WriteString(whereClause)
 	}
 
-	// 4. ORDER BY clause
-	if b.orderBy != "" {
-		sb.WriteString(" ORDER BY ")
-		sb.WriteString(b.orderBy)
+	// 4. ORDER BY 与分页子句，由方言生成
+	sb.WriteString(b.dialect.OrderLimit(b.orderBy, b.limit, b.offset))
+
+	return b.rebind(sb.String())
+}
+
+// BuildCountQuery 组装一个 COUNT 查询语句，WHERE 子句与 BuildSelectQuery 相同
+func (b *Builder) BuildCountQuery(tableName string) string {
+	if tableName == "" {
+		return ""
+	}
+	sql := "SELECT COUNT(*) AS total FROM " + quoteTable(b.quoter, tableName)
+	whereClause := b.BuildWhereClauses(" AND ")
+	if whereClause != "" {
+		sql += " WHERE " + whereClause
 	}
+	return b.rebind(sql)
+}
 
-	// 5. LIMIT / OFFSET clause
-	if b.limit != "" {
-		sb.WriteString(" ") // LIMIT前通常有个空格
-		sb.WriteString(b.limit)
+// BuildInsertQuery 组装一个 INSERT 语句，列和命名占位符均受过滤器影响
+// 用法: builder.OnlyNonZero().BuildInsertQuery("user") -> "INSERT INTO user(name, email) VALUES (:name, :email)"
+func (b *Builder) BuildInsertQuery(tableName string) string {
+	if tableName == "" {
+		return ""
 	}
+	var output, returning string
+	if len(b.returning) > 0 {
+		var cols []string
+		if b.dialect.InsertReturning() == ReturningOutput {
+			for _, c := range b.returning {
+				cols = append(cols, "INSERTED."+b.quoter.Quote(c))
+			}
+			output = " OUTPUT " + strings.Join(cols, ", ")
+		} else {
+			for _, c := range b.returning {
+				cols = append(cols, b.quoter.Quote(c))
+			}
+			returning = " RETURNING " + strings.Join(cols, ", ")
+		}
+	}
+	return fmt.Sprintf("INSERT INTO %s(%s)%s VALUES (%s)%s",
+		quoteTable(b.quoter, tableName),
+		b.BuildColumns(", "),
+		output,
+		b.BuildNamedPlaceholders(", "),
+		returning)
+}
 
-	return sb.String()
+// BuildUpdateQuery 组装一个 UPDATE 语句，SET 子句受过滤器影响，WHERE 子句只使用自定义条件与 Where 条件
+// 用法: builder.ExcludePK().OnlyNonZero().WithCustomWhere("id = :id").BuildUpdateQuery("user")
+func (b *Builder) BuildUpdateQuery(tableName string) string {
+	if tableName == "" {
+		return ""
+	}
+	sql := fmt.Sprintf("UPDATE %s SET %s", quoteTable(b.quoter, tableName), b.BuildSetClauses(", "))
+	return b.rebind(sql + b.writeWhere())
+}
+
+// BuildUpdateColumnsQuery 组装一个使用位置占位符的 UPDATE 语句，WHERE 子句只使用自定义条件与 Where 条件
+// 绑定参数依次为 columns 的值与 Args()
+// 用法: builder.Where(dbutil.Eq("family", f)).BuildUpdateColumnsQuery("refresh_token", "revoked_at") -> "UPDATE refresh_token SET revoked_at = ? WHERE family = ?"
+func (b *Builder) BuildUpdateColumnsQuery(tableName string, columns ...string) string {
+	if tableName == "" || len(columns) == 0 {
+		return ""
+	}
+	sets := make([]string, len(columns))
+	for i, c := range columns {
+		sets[i] = b.prefix + quoteIdent(b.quoter, c) + " = ?"
+	}
+	sql := fmt.Sprintf("UPDATE %s SET %s", quoteTable(b.quoter, tableName), strings.Join(sets, ", "))
+	return b.rebind(sql + b.writeWhere())
+}
+
+// BuildDeleteQuery 组装一个 DELETE 语句，WHERE 子句只使用自定义条件与 Where 条件
+// 用法: NewBuilder(nil).WithCustomWhere("id = ?").BuildDeleteQuery("user")
+func (b *Builder) BuildDeleteQuery(tableName string) string {
+	if tableName == "" {
+		return ""
+	}
+	sql := "DELETE FROM " + quoteTable(b.quoter, tableName)
+	return b.rebind(sql + b.writeWhere())
+}
+
+// writeWhere 生成写操作的 WHERE 子句，只包含自定义条件与 Where 条件，不包含自动条件与软删除条件
+func (b *Builder) writeWhere() string {
+	clauses := append([]string{}, b.customWhere...)
+	for _, c := range b.conds {
+		sql, _ := c.Build(b.dialect)
+		clauses = append(clauses, sql)
+	}
+	if len(clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(clauses, " AND ")
+}
+
+// BuildSoftDeleteQuery 组装一个软删除语句，第一个 ? 占位符为删除时间，WHERE 子句只使用自定义条件
+// 用法: builder.WithCustomWhere("id = ?").BuildSoftDeleteQuery("user") -> "UPDATE user SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL"
+func (b *Builder) BuildSoftDeleteQuery(tableName string) string {
+	if tableName == "" {
+		return ""
+	}
+	column := b.quoter.Quote(SoftDeleteColumn)
+	where := append(append([]string{}, b.customWhere...), column+" IS NULL")
+	return b.rebind(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s",
+		quoteTable(b.quoter, tableName), column, strings.Join(where, " AND ")))
+}
+
+// BuildRestoreQuery 组装一个恢复软删除记录的语句，WHERE 子句只使用自定义条件
+// 用法: builder.WithCustomWhere("id = ?").BuildRestoreQuery("user") -> "UPDATE user SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL"
+func (b *Builder) BuildRestoreQuery(tableName string) string {
+	if tableName == "" {
+		return ""
+	}
+	column := b.quoter.Quote(SoftDeleteColumn)
+	where := append(append([]string{}, b.customWhere...), column+" IS NOT NULL")
+	return b.rebind(fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s",
+		quoteTable(b.quoter, tableName), column, strings.Join(where, " AND ")))
+}
+
+// BuildUpsertQuery 组装一个插入或更新语句，conflict 为判断冲突的唯一约束列，具体语法由方言决定
+// 用法: builder.OnlyNonZero().BuildUpsertQuery("user", "username")
+func (b *Builder) BuildUpsertQuery(tableName string, conflict ...string) string {
+	if tableName == "" || len(conflict) == 0 {
+		return ""
+	}
+	var columns, placeholders []string
+	for _, c := range b.applyFilters() {
+		columns = append(columns, c.Name)
+		placeholders = append(placeholders, ":"+c.Name)
+	}
+	return b.dialect.Upsert(tableName, columns, placeholders, conflict)
 }
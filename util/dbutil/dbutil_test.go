@@ -1,7 +1,9 @@
 package dbutil
 
 import (
+	"app/conf"
 	"app/model"
+	"strings"
 	"testing"
 )
 
@@ -42,3 +44,75 @@ func TestDbutil(t *testing.T) {
 					BuildSelectQuery("products")
 	t.Log(query2)
 }
+
+func TestBuildWriteQueries(t *testing.T) {
+	user := model.User{Username: new(string)}
+	*user.Username = "username"
+
+	// INSERT INTO "user"("username") VALUES (:username)
+	insert := NewBuilder(&user).OnlyNonZero().BuildInsertQuery("user")
+	t.Log(insert)
+
+	// UPDATE "user" SET "username"=:username WHERE id = :id
+	update := NewBuilder(&user).ExcludePK().OnlyNonZero().WithCustomWhere("id = :id").BuildUpdateQuery("user")
+	t.Log(update)
+
+	// DELETE FROM "user" WHERE id = ?
+	del := NewBuilder(&user).WithCustomWhere("id = ?").BuildDeleteQuery("user")
+	t.Log(del)
+
+	name, _, ok := NewBuilder(&user).PrimaryKey()
+	if !ok || name != "id" {
+		t.Fatalf("unexpected primary key: %s", name)
+	}
+}
+
+func TestPostgresBuilder(t *testing.T) {
+	dbType := conf.DB.Type
+	conf.DB.Type = "postgres"
+	defer func() { conf.DB.Type = dbType }()
+
+	user := model.User{}
+	query := NewBuilder(&user).OnlyNonZero().WithCustomWhere("id = ?").BuildSelectQuery("user")
+	t.Log(query)
+	if !strings.Contains(query, `FROM "user" WHERE id = $1`) {
+		t.Fatalf("unexpected query: %s", query)
+	}
+
+	user.Username = new(string)
+	insert := NewBuilder(&user).OnlyNonZero().WithReturning("id").BuildInsertQuery("user")
+	t.Log(insert)
+	if insert != `INSERT INTO "user"("username") VALUES (:username) RETURNING "id"` {
+		t.Fatalf("unexpected query: %s", insert)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	b := NewBuilder(&model.User{}).WithDialect(SqliteDialect).OnlyNonZero().WithCustomWhere("id = ?")
+	if query := b.BuildSelectQuery("user"); !strings.HasSuffix(query, `WHERE id = ? AND "deleted_at" IS NULL`) {
+		t.Errorf("select should exclude trashed rows: %s", query)
+	}
+	if query := b.WithTrashed().BuildSelectQuery("user"); !strings.HasSuffix(query, `WHERE id = ?`) {
+		t.Errorf("select with trashed should not filter deleted_at: %s", query)
+	}
+	if query := b.OnlyTrashed().BuildCountQuery("user"); query != `SELECT COUNT(*) AS total FROM "user" WHERE id = ? AND "deleted_at" IS NOT NULL` {
+		t.Errorf("unexpected only trashed count query: %s", query)
+	}
+
+	del := NewBuilder(&model.User{}).WithDialect(PostgresDialect).WithCustomWhere("id = ?").BuildSoftDeleteQuery("user")
+	if del != `UPDATE "user" SET "deleted_at" = $1 WHERE id = $2 AND "deleted_at" IS NULL` {
+		t.Errorf("unexpected soft delete query: %s", del)
+	}
+	restore := NewBuilder(&model.User{}).WithDialect(SqliteDialect).WithCustomWhere("id = ?").BuildRestoreQuery("user")
+	if restore != `UPDATE "user" SET "deleted_at" = NULL WHERE id = ? AND "deleted_at" IS NOT NULL` {
+		t.Errorf("unexpected restore query: %s", restore)
+	}
+
+	// 不含 deleted_at 列的结构体不受影响
+	type product struct {
+		Id *int `db:"id,pk"`
+	}
+	if query := NewBuilder(&product{}).OnlyNonZero().BuildSelectQuery("product"); strings.Contains(query, "deleted_at") {
+		t.Errorf("struct without deleted_at should not be soft deleted: %s", query)
+	}
+}
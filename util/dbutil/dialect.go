@@ -0,0 +1,225 @@
+package dbutil
+
+import (
+	"app/conf"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect 定义了不同数据库之间的 SQL 方言差异，使同一套仓库代码可以运行在所有受支持的数据库上
+type Dialect interface {
+	Quoter
+	// Name 方言名称: sqlite, mysql, postgres, sqlserver
+	Name() string
+	// BindType 返回 sqlx 的占位符类型 (sqlx.QUESTION / sqlx.DOLLAR / sqlx.AT)
+	BindType() int
+	// Rebind 将 ? 占位符转换为当前数据库的占位符形式
+	Rebind(query string) string
+	// OrderLimit 生成 ORDER BY 与分页子句，limit < 0 表示不分页
+	OrderLimit(orderBy string, limit, offset int) string
+	// Upsert 生成插入或更新语句，columns/placeholders 一一对应，conflict 为唯一约束列
+	Upsert(table string, columns, placeholders, conflict []string) string
+	// InsertReturning 插入后取回自增主键的方式
+	InsertReturning() Returning
+	// SupportsRowValues 是否支持行值比较 (a, b) < (?, ?)
+	SupportsRowValues() bool
+}
+
+// Returning 插入后取回自增主键的方式
+type Returning int
+
+const (
+	ReturningNone   Returning = iota // 通过 LastInsertId 取回 (sqlite/mysql)
+	ReturningClause                  // 语句末尾的 RETURNING 子句 (postgres)
+	ReturningOutput                  // VALUES 前的 OUTPUT INSERTED 子句 (sqlserver)
+)
+
+var (
+	SqliteDialect    Dialect = sqliteDialect{}
+	MysqlDialect     Dialect = mysqlDialect{}
+	PostgresDialect  Dialect = postgresDialect{}
+	SqlServerDialect Dialect = sqlServerDialect{}
+)
+
+// DialectOf 根据数据库类型 (conf.DB.Type) 返回对应的方言，未知类型默认使用 sqlite
+func DialectOf(dbType string) Dialect {
+	dbType = strings.ToLower(dbType)
+	switch {
+	case strings.Contains(dbType, "mysql"):
+		return MysqlDialect
+	case strings.Contains(dbType, "postgres"):
+		return PostgresDialect
+	case strings.Contains(dbType, "sqlserver"), strings.Contains(dbType, "mssql"):
+		return SqlServerDialect
+	default:
+		return SqliteDialect
+	}
+}
+
+// CurrentDialect 返回当前配置的数据库方言
+func CurrentDialect() Dialect {
+	return DialectOf(conf.DB.Type)
+}
+
+// quoteTable 引用表名，支持 "schema.table" 与 "table alias" 形式
+func quoteTable(q Quoter, table string) string {
+	name, alias, hasAlias := strings.Cut(strings.TrimSpace(table), " ")
+	quoted := quoteIdent(q, name)
+	if hasAlias {
+		quoted += " " + strings.TrimSpace(alias)
+	}
+	return quoted
+}
+
+// limitOffset 生成 LIMIT/OFFSET 形式的分页子句，供 sqlite/mysql/postgres 复用
+func limitOffset(orderBy string, limit, offset int) string {
+	var sb strings.Builder
+	if orderBy != "" {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(orderBy)
+	}
+	if limit >= 0 {
+		sb.WriteString(fmt.Sprintf(" LIMIT %d", limit))
+		if offset > 0 {
+			sb.WriteString(fmt.Sprintf(" OFFSET %d", offset))
+		}
+	}
+	return sb.String()
+}
+
+// onConflict 生成 ON CONFLICT 形式的 upsert 语句，供 sqlite/postgres 复用
+func onConflict(q Quoter, table string, columns, placeholders, conflict []string) string {
+	var sets, quotedCols, quotedConflict []string
+	for _, c := range columns {
+		quotedCols = append(quotedCols, q.Quote(c))
+	}
+	for _, c := range conflict {
+		quotedConflict = append(quotedConflict, q.Quote(c))
+	}
+	for _, c := range columns {
+		if contains(conflict, c) {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = excluded.%s", q.Quote(c), q.Quote(c)))
+	}
+	sql := fmt.Sprintf("INSERT INTO %s(%s) VALUES (%s) ON CONFLICT (%s)",
+		quoteTable(q, table), strings.Join(quotedCols, ", "), strings.Join(placeholders, ", "), strings.Join(quotedConflict, ", "))
+	if len(sets) == 0 {
+		return sql + " DO NOTHING"
+	}
+	return sql + " DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sqliteDialect SQLite 方言
+type sqliteDialect struct{ DoubleQuoteQuoter }
+
+func (sqliteDialect) Name() string               { return "sqlite" }
+func (sqliteDialect) BindType() int              { return sqlx.QUESTION }
+func (sqliteDialect) Rebind(query string) string { return query }
+func (sqliteDialect) InsertReturning() Returning { return ReturningNone }
+func (sqliteDialect) SupportsRowValues() bool    { return true }
+func (sqliteDialect) OrderLimit(orderBy string, limit, offset int) string {
+	return limitOffset(orderBy, limit, offset)
+}
+func (d sqliteDialect) Upsert(table string, columns, placeholders, conflict []string) string {
+	return onConflict(d, table, columns, placeholders, conflict)
+}
+
+// mysqlDialect MySQL/MariaDB 方言
+type mysqlDialect struct{ BacktickQuoter }
+
+func (mysqlDialect) Name() string               { return "mysql" }
+func (mysqlDialect) BindType() int              { return sqlx.QUESTION }
+func (mysqlDialect) Rebind(query string) string { return query }
+func (mysqlDialect) InsertReturning() Returning { return ReturningNone }
+func (mysqlDialect) SupportsRowValues() bool    { return true }
+func (mysqlDialect) OrderLimit(orderBy string, limit, offset int) string {
+	return limitOffset(orderBy, limit, offset)
+}
+func (d mysqlDialect) Upsert(table string, columns, placeholders, conflict []string) string {
+	var sets, quotedCols []string
+	for _, c := range columns {
+		quotedCols = append(quotedCols, d.Quote(c))
+		if !contains(conflict, c) {
+			sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", d.Quote(c), d.Quote(c)))
+		}
+	}
+	if len(sets) == 0 {
+		// 没有可更新的列时更新一个冲突列为自身，等价于 DO NOTHING
+		sets = append(sets, fmt.Sprintf("%s = %s", d.Quote(conflict[0]), d.Quote(conflict[0])))
+	}
+	return fmt.Sprintf("INSERT INTO %s(%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+		quoteTable(d, table), strings.Join(quotedCols, ", "), strings.Join(placeholders, ", "), strings.Join(sets, ", "))
+}
+
+// postgresDialect PostgreSQL 方言
+type postgresDialect struct{ DoubleQuoteQuoter }
+
+func (postgresDialect) Name() string               { return "postgres" }
+func (postgresDialect) BindType() int              { return sqlx.DOLLAR }
+func (postgresDialect) Rebind(query string) string { return sqlx.Rebind(sqlx.DOLLAR, query) }
+func (postgresDialect) InsertReturning() Returning { return ReturningClause }
+func (postgresDialect) SupportsRowValues() bool    { return true }
+func (postgresDialect) OrderLimit(orderBy string, limit, offset int) string {
+	return limitOffset(orderBy, limit, offset)
+}
+func (d postgresDialect) Upsert(table string, columns, placeholders, conflict []string) string {
+	return onConflict(d, table, columns, placeholders, conflict)
+}
+
+// sqlServerDialect SQL Server 方言
+type sqlServerDialect struct{ BracketQuoter }
+
+func (sqlServerDialect) Name() string               { return "sqlserver" }
+func (sqlServerDialect) BindType() int              { return sqlx.AT }
+func (sqlServerDialect) Rebind(query string) string { return sqlx.Rebind(sqlx.AT, query) }
+func (sqlServerDialect) InsertReturning() Returning { return ReturningOutput }
+func (sqlServerDialect) SupportsRowValues() bool    { return false }
+
+// OrderLimit SQL Server 使用 OFFSET ... FETCH 分页，且分页时必须带有 ORDER BY
+func (sqlServerDialect) OrderLimit(orderBy string, limit, offset int) string {
+	if limit < 0 {
+		if orderBy == "" {
+			return ""
+		}
+		return " ORDER BY " + orderBy
+	}
+	if orderBy == "" {
+		orderBy = "(SELECT NULL)"
+	}
+	return fmt.Sprintf(" ORDER BY %s OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", orderBy, offset, limit)
+}
+
+// Upsert SQL Server 没有 ON CONFLICT，使用 MERGE 实现
+func (d sqlServerDialect) Upsert(table string, columns, placeholders, conflict []string) string {
+	var source, on, sets, quotedCols, values []string
+	for i, c := range columns {
+		source = append(source, fmt.Sprintf("%s AS %s", placeholders[i], d.Quote(c)))
+		quotedCols = append(quotedCols, d.Quote(c))
+		values = append(values, "source."+d.Quote(c))
+		if !contains(conflict, c) {
+			sets = append(sets, fmt.Sprintf("target.%s = source.%s", d.Quote(c), d.Quote(c)))
+		}
+	}
+	for _, c := range conflict {
+		on = append(on, fmt.Sprintf("target.%s = source.%s", d.Quote(c), d.Quote(c)))
+	}
+	sql := fmt.Sprintf("MERGE INTO %s AS target USING (SELECT %s) AS source ON %s",
+		quoteTable(d, table), strings.Join(source, ", "), strings.Join(on, " AND "))
+	if len(sets) > 0 {
+		sql += " WHEN MATCHED THEN UPDATE SET " + strings.Join(sets, ", ")
+	}
+	return sql + fmt.Sprintf(" WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s);",
+		strings.Join(quotedCols, ", "), strings.Join(values, ", "))
+}
@@ -0,0 +1,97 @@
+package dbutil
+
+import (
+	"app/model"
+	"testing"
+)
+
+func TestDialectOf(t *testing.T) {
+	cases := map[string]string{
+		"sqlite":     "sqlite",
+		"mysql":      "mysql",
+		"postgres":   "postgres",
+		"postgresql": "postgres",
+		"sqlserver":  "sqlserver",
+		"mssql":      "sqlserver",
+		"":           "sqlite",
+	}
+	for dbType, name := range cases {
+		if got := DialectOf(dbType).Name(); got != name {
+			t.Errorf("DialectOf(%q) = %s, want %s", dbType, got, name)
+		}
+	}
+}
+
+func TestDialectSelect(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{SqliteDialect, `SELECT "id" AS "id" FROM "user" WHERE id = ? ORDER BY id LIMIT 10 OFFSET 20`},
+		{MysqlDialect, "SELECT `id` AS \"id\" FROM `user` WHERE id = ? ORDER BY id LIMIT 10 OFFSET 20"},
+		{PostgresDialect, `SELECT "id" AS "id" FROM "user" WHERE id = $1 ORDER BY id LIMIT 10 OFFSET 20`},
+		{SqlServerDialect, `SELECT [id] AS "id" FROM [user] WHERE id = @p1 ORDER BY id OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`},
+	}
+	for _, c := range cases {
+		query := NewBuilder(&struct {
+			Id *int `db:"id,pk"`
+		}{}).
+			WithDialect(c.dialect).
+			OnlyNonZero().
+			WithCustomWhere("id = ?").
+			WithOrderBy("id").
+			WithLimitOffset(10, 20).
+			BuildSelectQuery("user")
+		if query != c.want {
+			t.Errorf("%s:\n got: %s\nwant: %s", c.dialect.Name(), query, c.want)
+		}
+	}
+
+	// SQL Server 分页必须带 ORDER BY
+	query := NewBuilder(nil).WithDialect(SqlServerDialect).WithLimit(5).BuildSelectQuery("user")
+	if query != "SELECT  FROM [user] ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY" {
+		t.Errorf("unexpected sqlserver query: %s", query)
+	}
+}
+
+func TestDialectUpsert(t *testing.T) {
+	username, password := "username", "password"
+	user := model.User{Username: &username, Password: &password}
+	cases := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{SqliteDialect, `INSERT INTO "user"("username", "password") VALUES (:username, :password) ON CONFLICT ("username") DO UPDATE SET "password" = excluded."password"`},
+		{MysqlDialect, "INSERT INTO `user`(`username`, `password`) VALUES (:username, :password) ON DUPLICATE KEY UPDATE `password` = VALUES(`password`)"},
+		{PostgresDialect, `INSERT INTO "user"("username", "password") VALUES (:username, :password) ON CONFLICT ("username") DO UPDATE SET "password" = excluded."password"`},
+		{SqlServerDialect, "MERGE INTO [user] AS target USING (SELECT :username AS [username], :password AS [password]) AS source ON target.[username] = source.[username] WHEN MATCHED THEN UPDATE SET target.[password] = source.[password] WHEN NOT MATCHED THEN INSERT ([username], [password]) VALUES (source.[username], source.[password]);"},
+	}
+	for _, c := range cases {
+		query := NewBuilder(&user).WithDialect(c.dialect).OnlyNonZero().BuildUpsertQuery("user", "username")
+		if query != c.want {
+			t.Errorf("%s:\n got: %s\nwant: %s", c.dialect.Name(), query, c.want)
+		}
+	}
+}
+
+func TestDialectInsertReturning(t *testing.T) {
+	var user model.User
+	user.Username = new(string)
+	cases := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{SqliteDialect, `INSERT INTO "user"("username") VALUES (:username) RETURNING "id"`},
+		{PostgresDialect, `INSERT INTO "user"("username") VALUES (:username) RETURNING "id"`},
+		{SqlServerDialect, `INSERT INTO [user]([username]) OUTPUT INSERTED.[id] VALUES (:username)`},
+	}
+	for _, c := range cases {
+		query := NewBuilder(&user).WithDialect(c.dialect).OnlyNonZero().WithReturning("id").BuildInsertQuery("user")
+		if query != c.want {
+			t.Errorf("%s:\n got: %s\nwant: %s", c.dialect.Name(), query, c.want)
+		}
+	}
+	if MysqlDialect.InsertReturning() != ReturningNone || SqlServerDialect.InsertReturning() != ReturningOutput {
+		t.Error("mysql should use LastInsertId and sqlserver OUTPUT INSERTED")
+	}
+}
@@ -0,0 +1,108 @@
+package dbutil
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrInvalidSort 排序参数包含不允许的列
+	ErrInvalidSort = errors.New("dbutil: invalid sort")
+	// ErrInvalidFields 字段选择参数包含不允许的列
+	ErrInvalidFields = errors.New("dbutil: invalid fields")
+)
+
+// Order 排序项
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// ListOptions 列表查询的排序与字段选择，列名均已通过白名单校验
+type ListOptions struct {
+	Orders []Order  // 排序，为空时使用仓库默认排序
+	Fields []string // 查询的列，为空时查询所有列
+}
+
+// ParseListOptions 解析 ?sort=-created_at,username 与 ?fields=id,username 形式的参数
+// 白名单为 allowlist 结构体的 db 标签，通常传入接口的输出结构体，避免按敏感列排序或查询
+func ParseListOptions(allowlist any, sort, fields string) (*ListOptions, error) {
+	allowed := Columns(allowlist)
+	opts := &ListOptions{}
+	for _, item := range splitList(sort) {
+		order := Order{Column: item}
+		if strings.HasPrefix(item, "-") {
+			order = Order{Column: item[1:], Desc: true}
+		} else if strings.HasPrefix(item, "+") {
+			order.Column = item[1:]
+		}
+		if !contains(allowed, order.Column) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSort, order.Column)
+		}
+		opts.Orders = append(opts.Orders, order)
+	}
+	for _, field := range splitList(fields) {
+		if !contains(allowed, field) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFields, field)
+		}
+		if !contains(opts.Fields, field) {
+			opts.Fields = append(opts.Fields, field)
+		}
+	}
+	return opts, nil
+}
+
+// Columns 返回结构体 db 标签中的列名
+func Columns(o any) []string {
+	v := deReference(o)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var columns []string
+	for _, c := range parseStruct(v) {
+		columns = append(columns, c.Name)
+	}
+	return columns
+}
+
+// WithOrders 按已校验的排序项设置 ORDER BY 子句
+func (b *Builder) WithOrders(orders ...Order) *Builder {
+	var items []string
+	for _, o := range orders {
+		item := b.prefix + quoteIdent(b.quoter, o.Column)
+		if o.Desc {
+			item += " DESC"
+		}
+		items = append(items, item)
+	}
+	return b.WithOrderBy(strings.Join(items, ", "))
+}
+
+// WithFields 限制 SELECT 查询的列，不在结构体中的列会被忽略
+func (b *Builder) WithFields(fields ...string) *Builder {
+	b.fields = append(b.fields, fields...)
+	return b
+}
+
+// WithListOptions 应用列表查询的排序与字段选择，opts 为空时不做修改
+func (b *Builder) WithListOptions(opts *ListOptions) *Builder {
+	if opts == nil {
+		return b
+	}
+	if len(opts.Orders) > 0 {
+		b.WithOrders(opts.Orders...)
+	}
+	return b.WithFields(opts.Fields...)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
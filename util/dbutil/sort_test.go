@@ -0,0 +1,59 @@
+package dbutil
+
+import (
+	"app/model"
+	"app/model/output"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseListOptions(t *testing.T) {
+	opts, err := ParseListOptions(&output.UserOutput{}, "-created_at, +username,id", "id,username,id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedOrders := []Order{{Column: "created_at", Desc: true}, {Column: "username"}, {Column: "id"}}
+	if !reflect.DeepEqual(opts.Orders, expectedOrders) {
+		t.Errorf("unexpected orders: %v", opts.Orders)
+	}
+	if !reflect.DeepEqual(opts.Fields, []string{"id", "username"}) {
+		t.Errorf("unexpected fields: %v", opts.Fields)
+	}
+
+	opts, err = ParseListOptions(&output.UserOutput{}, "", "")
+	if err != nil || len(opts.Orders) != 0 || len(opts.Fields) != 0 {
+		t.Errorf("empty params should produce empty options: %v %v", opts, err)
+	}
+
+	// 不在白名单中的列与 SQL 注入均被拒绝
+	for _, sort := range []string{"password", "id;drop table user", "id desc", "-"} {
+		if _, err := ParseListOptions(&output.UserOutput{}, sort, ""); !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("sort %q should be rejected, got %v", sort, err)
+		}
+	}
+	if _, err := ParseListOptions(&output.UserOutput{}, "", "id,password"); !errors.Is(err, ErrInvalidFields) {
+		t.Errorf("fields with password should be rejected, got %v", err)
+	}
+}
+
+func TestBuilderListOptions(t *testing.T) {
+	opts := &ListOptions{
+		Orders: []Order{{Column: "created_at", Desc: true}, {Column: "username"}},
+		Fields: []string{"id", "username"},
+	}
+	query := NewBuilder(&model.User{}).WithDialect(MysqlDialect).OnlyNonZero().
+		WithOrderBy("id desc").WithListOptions(opts).BuildSelectQuery("user")
+	expected := "SELECT `id` AS \"id\", `username` AS \"username\" FROM `user` WHERE `deleted_at` IS NULL ORDER BY `created_at` DESC, `username`"
+	if query != expected {
+		t.Errorf("unexpected query:\n%s\nexpected:\n%s", query, expected)
+	}
+
+	// 未指定排序时保留默认排序
+	query = NewBuilder(&model.User{}).WithDialect(SqliteDialect).OnlyNonZero().
+		WithOrderBy("id desc").WithListOptions(&ListOptions{}).BuildSelectQuery("user")
+	if !strings.HasSuffix(query, "ORDER BY id desc") || !strings.Contains(query, `"password"`) {
+		t.Errorf("empty options should keep default order and all columns: %s", query)
+	}
+}
@@ -3,14 +3,18 @@ package httputil
 import (
 	"app/code"
 	"app/i18n"
+	"app/log"
+	"errors"
 	"github.com/gofiber/fiber/v2"
 	"net/http"
 )
 
 type Response struct {
-	TraceId string `json:"traceId"` // 请求ID
-	Msg     string `json:"msg" `    // success或错误信息
-	Data    any    `json:"data" `   // 返回数据
+	TraceId string `json:"traceId"`           // 请求ID
+	Code    int    `json:"code"`              // 业务码，成功为 0
+	Msg     string `json:"msg" `              // success或错误信息
+	Data    any    `json:"data" `             // 返回数据
+	Details any    `json:"details,omitempty"` // 错误详细信息，如参数校验失败的字段
 }
 
 func JsonSuccess(c *fiber.Ctx, data any) error {
@@ -21,16 +25,36 @@ func JsonSuccess(c *fiber.Ctx, data any) error {
 	return c.Status(http.StatusOK).JSON(response)
 }
 
+// JsonErrorParse 将错误转换为结构化响应，HTTP 状态码与业务码由 code 包的错误登记表决定
 func JsonErrorParse(c *fiber.Ctx, err error) error {
-	errMsg := i18n.LocalizeWithCtx(c, err.Error())
+	bizErr := ToBizError(err)
+	if bizErr.Status >= http.StatusInternalServerError {
+		log.F(c).Error(err)
+	}
+	errMsg := i18n.LocalizeWithCtx(c, bizErr.Err.String())
 	if errMsg == "" {
-		errMsg = err.Error()
+		errMsg = bizErr.Err.String()
 	}
 	response := Response{}
 	response.TraceId = GetTraceId(c)
+	response.Code = bizErr.Code
 	response.Msg = errMsg
 	response.Data = nil
-	return c.Status(http.StatusOK).JSON(response)
+	response.Details = bizErr.Details
+	return c.Status(bizErr.Status).JSON(response)
+}
+
+// ToBizError 在 code.From 的基础上转换参数校验错误与 fiber 的错误 (如路由不存在)
+func ToBizError(err error) *code.BizError {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return code.ParamError.WithDetails(validationErr.Fields)
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return &code.BizError{Err: code.Error(fiberErr.Message), Status: fiberErr.Code, Code: fiberErr.Code, Cause: err}
+	}
+	return code.From(err)
 }
 
 func GetTraceId(c *fiber.Ctx) string {
@@ -0,0 +1,71 @@
+package httputil
+
+import (
+	"app/code"
+	"app/conf"
+	"app/i18n"
+	"app/log"
+	"app/model/input"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestJsonErrorParse(t *testing.T) {
+	conf.Initialize()
+	log.Initialize()
+	i18n.Initialize()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(code.TraceIdKey, "trace")
+		if err := c.Next(); err != nil {
+			return JsonErrorParse(c, err)
+		}
+		return nil
+	})
+	app.Get("/auth", func(c *fiber.Ctx) error { return code.AuthFailed })
+	app.Get("/db", func(c *fiber.Ctx) error { return errors.New("connection refused") })
+	app.Post("/register", func(c *fiber.Ctx) error {
+		_, err := BindBody[input.UserRegister](c)
+		return err
+	})
+
+	tests := []struct {
+		method, path, body string
+		status, code       int
+		msg                string
+	}{
+		{fiber.MethodGet, "/auth", "", http.StatusUnauthorized, code.AuthFailed.Code(), "认证失败"},
+		{fiber.MethodGet, "/db", "", http.StatusInternalServerError, code.ServerError.Code(), "服务器错误"},
+		{fiber.MethodPost, "/register", `{}`, http.StatusBadRequest, code.ParamError.Code(), "参数错误"},
+		{fiber.MethodGet, "/missing", "", http.StatusNotFound, http.StatusNotFound, "Cannot GET /missing"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderAcceptLanguage, "zh")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Response
+			Details []FieldError `json:"details"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.status || body.Code != test.code || body.Msg != test.msg || body.TraceId != "trace" {
+			t.Errorf("%s %s: unexpected response %d %+v", test.method, test.path, resp.StatusCode, body)
+		}
+		if test.path == "/register" && len(body.Details) != 2 {
+			t.Errorf("validation errors should be returned in details: %+v", body.Details)
+		}
+	}
+}
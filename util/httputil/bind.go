@@ -0,0 +1,122 @@
+package httputil
+
+import (
+	"app/code"
+	"app/i18n"
+	"app/log"
+	"errors"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// validate 全局校验器，字段名使用 json/query/uri 标签，与客户端提交的参数名一致
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "query", "uri", "params"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	return v
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`   // 参数名
+	Tag     string `json:"tag"`     // 未通过的校验规则，如 required, min
+	Message string `json:"message"` // 按请求语言翻译后的错误信息
+}
+
+// ValidationError 请求参数校验失败，Fields 列出每个不合法的字段
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return code.ParamError.Error()
+}
+
+// Unwrap 使 errors.Is(err, code.ParamError) 成立
+func (e *ValidationError) Unwrap() error {
+	return code.ParamError
+}
+
+// BindBody 解析请求体到 T 并按 validate 标签校验
+// e.g., user, err := httputil.BindBody[input.UserRegister](c)
+func BindBody[T any](c *fiber.Ctx) (*T, error) {
+	return bind[T](c, c.BodyParser)
+}
+
+// BindQuery 解析查询参数到 T 并按 validate 标签校验
+func BindQuery[T any](c *fiber.Ctx) (*T, error) {
+	return bind[T](c, c.QueryParser)
+}
+
+// BindParams 解析路径参数到 T 并按 validate 标签校验
+func BindParams[T any](c *fiber.Ctx) (*T, error) {
+	return bind[T](c, c.ParamsParser)
+}
+
+func bind[T any](c *fiber.Ctx, parse func(out any) error) (*T, error) {
+	t := new(T)
+	if err := parse(t); err != nil {
+		log.F(c).Error(err)
+		return nil, code.ParamError
+	}
+	if err := Validate(c, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Validate 按 validate 标签校验结构体，失败时返回 *ValidationError，错误信息按请求语言翻译
+func Validate(c *fiber.Ctx, o any) error {
+	err := validate.Struct(o)
+	if err == nil {
+		return nil
+	}
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		log.F(c).Error(err)
+		return code.ParamError
+	}
+	result := &ValidationError{}
+	for _, e := range errs {
+		result.Fields = append(result.Fields, FieldError{
+			Field:   e.Field(),
+			Tag:     e.Tag(),
+			Message: fieldMessage(c, e),
+		})
+	}
+	log.F(c).Warn(result.Fields)
+	return result
+}
+
+// fieldMessage 翻译校验错误，消息 ID 为 Validate + 规则名 (如 ValidateRequired)，未配置的规则使用 ValidateDefault
+func fieldMessage(c *fiber.Ctx, e validator.FieldError) string {
+	data := map[string]any{"Field": e.Field(), "Param": e.Param()}
+	tag := []rune(e.Tag())
+	if len(tag) > 0 {
+		tag[0] = unicode.ToUpper(tag[0])
+	}
+	if msg := i18n.LocalizeWithCtxData(c, "Validate"+string(tag), data); msg != "" {
+		return msg
+	}
+	if msg := i18n.LocalizeWithCtxData(c, "ValidateDefault", data); msg != "" {
+		return msg
+	}
+	return e.Error()
+}
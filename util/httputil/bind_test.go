@@ -0,0 +1,72 @@
+package httputil
+
+import (
+	"app/conf"
+	"app/i18n"
+	"app/log"
+	"app/model/input"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBindBody(t *testing.T) {
+	conf.Initialize()
+	log.Initialize()
+	i18n.Initialize()
+
+	var bindErr error
+	app := fiber.New()
+	app.Post("/register", func(c *fiber.Ctx) error {
+		_, bindErr = BindBody[input.UserRegister](c)
+		return nil
+	})
+	post := func(body, lang string) {
+		req := httptest.NewRequest(fiber.MethodPost, "/register", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderAcceptLanguage, lang)
+		if _, err := app.Test(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	post(`{"username":"admin","password":"123456"}`, "zh")
+	if bindErr != nil {
+		t.Fatalf("valid body should pass: %v", bindErr)
+	}
+
+	// 缺少字段时不应 panic，而是返回每个字段的错误
+	post(`{}`, "en")
+	var validationErr *ValidationError
+	if !errors.As(bindErr, &validationErr) || len(validationErr.Fields) != 2 {
+		t.Fatalf("unexpected error: %#v", bindErr)
+	}
+	expected := []FieldError{
+		{Field: "username", Tag: "required", Message: "username is required"},
+		{Field: "password", Tag: "required", Message: "password is required"},
+	}
+	for i, e := range expected {
+		if validationErr.Fields[i] != e {
+			t.Errorf("unexpected field error %+v, expected %+v", validationErr.Fields[i], e)
+		}
+	}
+
+	// 密码强度由 password 配置决定，不在参数校验中重复
+	post(`{"username":"admin","password":"1"}`, "en")
+	if bindErr != nil {
+		t.Fatalf("password policy should not be validated on bind: %v", bindErr)
+	}
+
+	post(`{"username":"ab","password":"123456"}`, "zh-CN,zh;q=0.9")
+	if !errors.As(bindErr, &validationErr) || validationErr.Fields[0].Message != "username 长度或数值不能小于 3" {
+		t.Fatalf("message should be localized by Accept-Language: %#v", bindErr)
+	}
+
+	post(`{"username":`, "zh")
+	if bindErr == nil || errors.As(bindErr, &validationErr) {
+		t.Fatalf("malformed body should be a param error: %v", bindErr)
+	}
+}
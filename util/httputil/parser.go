@@ -0,0 +1,50 @@
+package httputil
+
+import (
+	"app/code"
+	"app/conf"
+	"app/log"
+	"app/util/dbutil"
+	"github.com/gofiber/fiber/v2"
+	"reflect"
+	"time"
+)
+
+// timeLayouts 查询参数中支持的时间格式
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// RegisterParserDecoder 为 QueryParser/ParamsParser 等注册自定义类型的解析器 (如 time.Time)
+func RegisterParserDecoder() {
+	fiber.SetParserDecoder(fiber.ParserConfig{
+		IgnoreUnknownKeys: true,
+		ZeroEmpty:         true,
+		ParserType: []fiber.ParserType{
+			{Customtype: time.Time{}, Converter: timeConverter},
+		},
+	})
+}
+
+// timeConverter 按 timeLayouts 依次尝试解析时间，不带时区的时间使用 conf.Timezone
+func timeConverter(value string) reflect.Value {
+	loc, err := time.LoadLocation(conf.Timezone)
+	if err != nil {
+		loc = time.Local
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return reflect.ValueOf(t)
+		}
+	}
+	return reflect.Value{}
+}
+
+// ParseListOptions 解析列表接口的 ?sort=-created_at,username 与 ?fields=id,username 参数
+// 列名以 allowlist 结构体的 db 标签为白名单 (通常为接口的输出结构体)，不在白名单中时返回 code.ParamError
+func ParseListOptions(c *fiber.Ctx, allowlist any) (*dbutil.ListOptions, error) {
+	opts, err := dbutil.ParseListOptions(allowlist, c.Query("sort"), c.Query("fields"))
+	if err != nil {
+		log.F(c).Error(err)
+		return nil, code.ParamError
+	}
+	return opts, nil
+}
@@ -0,0 +1,192 @@
+package jwtutil
+
+import (
+	"app/conf"
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnknownKey 令牌的 kid 不在验证密钥中，或签名算法与密钥不匹配
+var ErrUnknownKey = errors.New("jwtutil: unknown signing key")
+
+// Key 签名/验证密钥，Private 为空时只用于验证 (轮换后保留的旧密钥)
+type Key struct {
+	Id      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey // *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey 或 HMAC 的 []byte
+	Public  crypto.PublicKey  // *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey 或 HMAC 的 []byte
+}
+
+// KeySet 签名密钥与所有验证密钥
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+var (
+	current *KeySet
+	mu      sync.Mutex
+)
+
+// Initialize 按 conf.Jwt 加载密钥，配置错误时 panic
+func Initialize() {
+	keySet := mustLoad()
+	mu.Lock()
+	current = keySet
+	mu.Unlock()
+}
+
+// Current 返回当前使用的密钥，未初始化时按配置加载
+func Current() *KeySet {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		current = mustLoad()
+	}
+	return current
+}
+
+func mustLoad() *KeySet {
+	keySet, err := Load(conf.Jwt, conf.Server.Secret)
+	if err != nil {
+		panic(fmt.Sprintf("Fatal error: unable to load jwt keys: %v", err))
+	}
+	return keySet
+}
+
+// Load 加载 jwt.keys 中配置的密钥，jwt.signingKey 指定签名使用的密钥
+// 未配置 jwt.keys 时使用 secret 进行 HS256 签名，令牌不带 kid
+func Load(c conf.JwtConf, secret string) (*KeySet, error) {
+	if len(c.Keys) == 0 {
+		key := &Key{Method: jwt.SigningMethodHS256, Private: []byte(secret), Public: []byte(secret)}
+		return &KeySet{signing: key, keys: map[string]*Key{"": key}}, nil
+	}
+	keySet := &KeySet{keys: make(map[string]*Key, len(c.Keys))}
+	for _, kc := range c.Keys {
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: %w", kc.Id, err)
+		}
+		if _, ok := keySet.keys[key.Id]; ok {
+			return nil, fmt.Errorf("jwt key %s: duplicate id", kc.Id)
+		}
+		keySet.keys[key.Id] = key
+	}
+	signingKey := c.SigningKey
+	if signingKey == "" {
+		signingKey = c.Keys[0].Id
+	}
+	keySet.signing = keySet.keys[signingKey]
+	if keySet.signing == nil || keySet.signing.Private == nil {
+		return nil, fmt.Errorf("jwt signing key %s not found or has no private key", signingKey)
+	}
+	return keySet, nil
+}
+
+func loadKey(c conf.JwtKeyConf) (*Key, error) {
+	if c.Id == "" {
+		return nil, errors.New("id is required")
+	}
+	method := jwt.GetSigningMethod(c.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported algorithm %q", c.Algorithm)
+	}
+	key := &Key{Id: c.Id, Method: method}
+	if c.PrivateKey != "" {
+		pem, err := readPEM(c.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		if key.Private, err = parsePrivateKey(method, pem); err != nil {
+			return nil, err
+		}
+		key.Public = key.Private.(crypto.Signer).Public()
+	}
+	if c.PublicKey != "" {
+		pem, err := readPEM(c.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		if key.Public, err = parsePublicKey(method, pem); err != nil {
+			return nil, err
+		}
+	}
+	if key.Public == nil {
+		return nil, errors.New("privateKey or publicKey is required")
+	}
+	return key, nil
+}
+
+// readPEM 读取 PEM 文件，相对路径相对于应用根目录
+func readPEM(path string) ([]byte, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(conf.RootPath, path)
+	}
+	return os.ReadFile(path)
+}
+
+func parsePrivateKey(method jwt.SigningMethod, pem []byte) (crypto.PrivateKey, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPrivateKeyFromPEM(pem)
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPrivateKeyFromPEM(pem)
+	case *jwt.SigningMethodEd25519:
+		return jwt.ParseEdPrivateKeyFromPEM(pem)
+	}
+	return nil, fmt.Errorf("unsupported algorithm %q", method.Alg())
+}
+
+func parsePublicKey(method jwt.SigningMethod, pem []byte) (crypto.PublicKey, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPublicKeyFromPEM(pem)
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPublicKeyFromPEM(pem)
+	case *jwt.SigningMethodEd25519:
+		return jwt.ParseEdPublicKeyFromPEM(pem)
+	}
+	return nil, fmt.Errorf("unsupported algorithm %q", method.Alg())
+}
+
+// Sign 使用签名密钥签发令牌，非对称密钥在头部写入 kid
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	if s.signing.Id != "" {
+		token.Header["kid"] = s.signing.Id
+	}
+	return token.SignedString(s.signing.Private)
+}
+
+// Keyfunc 按令牌头部的 kid 选择验证密钥，并校验签名算法与密钥一致
+func (s *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok || token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnknownKey
+	}
+	return key.Public, nil
+}
+
+// Parse 校验并解析令牌
+func (s *KeySet) Parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, s.Keyfunc)
+}
+
+// JWKS 所有非对称验证密钥的公钥，HMAC 密钥不会公开
+func (s *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range s.keys {
+		if jwk, ok := toJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sortJWKs(set.Keys)
+	return set
+}
@@ -0,0 +1,171 @@
+package jwtutil
+
+import (
+	"app/conf"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey 生成 PKCS#8 私钥 PEM 文件
+func writeKey(t *testing.T, dir, name string, key crypto.PrivateKey) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writePublicKey 生成 PKIX 公钥 PEM 文件
+func writePublicKey(t *testing.T, dir, name string, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name+".pub.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadHMAC(t *testing.T) {
+	keySet, err := Load(conf.JwtConf{}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := keySet.Sign(jwt.MapClaims{"uid": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := keySet.Parse(signed)
+	if err != nil || token.Header["kid"] != nil || token.Method != jwt.SigningMethodHS256 {
+		t.Fatalf("unexpected token: %+v %v", token, err)
+	}
+	if len(keySet.JWKS().Keys) != 0 {
+		t.Fatal("hmac secret should not be published")
+	}
+}
+
+func TestAsymmetricKeys(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	cases := []struct {
+		alg string
+		key crypto.PrivateKey
+		kty string
+	}{
+		{"RS256", rsaKey, "RSA"},
+		{"ES256", ecKey, "EC"},
+		{"EdDSA", edKey, "OKP"},
+	}
+	for _, c := range cases {
+		t.Run(c.alg, func(t *testing.T) {
+			keySet, err := Load(conf.JwtConf{Keys: []conf.JwtKeyConf{
+				{Id: c.alg, Algorithm: c.alg, PrivateKey: writeKey(t, dir, c.alg, c.key)},
+			}}, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			signed, err := keySet.Sign(jwt.MapClaims{"uid": 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := keySet.Parse(signed)
+			if err != nil || token.Header["kid"] != c.alg || token.Method.Alg() != c.alg {
+				t.Fatalf("unexpected token: %+v %v", token, err)
+			}
+			jwks := keySet.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Kty != c.kty || jwks.Keys[0].Kid != c.alg || jwks.Keys[0].Alg != c.alg {
+				t.Fatalf("unexpected jwks: %+v", jwks)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	oldSet, err := Load(conf.JwtConf{Keys: []conf.JwtKeyConf{
+		{Id: "old", Algorithm: "RS256", PrivateKey: writeKey(t, dir, "old", oldKey)},
+	}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := oldSet.Sign(jwt.MapClaims{"uid": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换：新密钥签名，旧密钥只保留公钥用于验证
+	rotated, err := Load(conf.JwtConf{SigningKey: "new", Keys: []conf.JwtKeyConf{
+		{Id: "old", Algorithm: "RS256", PublicKey: writePublicKey(t, dir, "old", &oldKey.PublicKey)},
+		{Id: "new", Algorithm: "EdDSA", PrivateKey: writeKey(t, dir, "new", newKey)},
+	}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotated.Parse(oldToken); err != nil {
+		t.Fatalf("token signed by old key should still be valid: %v", err)
+	}
+	newToken, err := rotated.Sign(jwt.MapClaims{"uid": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, err := rotated.Parse(newToken); err != nil || token.Header["kid"] != "new" {
+		t.Fatalf("token should be signed by new key: %+v %v", token, err)
+	}
+	if _, err := oldSet.Parse(newToken); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("unknown kid should be rejected, got %v", err)
+	}
+	if jwks := rotated.JWKS(); len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "new" || jwks.Keys[1].Kid != "old" {
+		t.Fatalf("unexpected jwks: %+v", jwks)
+	}
+
+	// 只有公钥的密钥不能用于签名
+	if _, err := Load(conf.JwtConf{SigningKey: "old", Keys: []conf.JwtKeyConf{
+		{Id: "old", Algorithm: "RS256", PublicKey: writePublicKey(t, dir, "old", &oldKey.PublicKey)},
+	}}, ""); err == nil {
+		t.Fatal("signing key without private key should fail")
+	}
+}
+
+func TestAlgorithmMismatch(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keySet, err := Load(conf.JwtConf{Keys: []conf.JwtKeyConf{
+		{Id: "k1", Algorithm: "RS256", PrivateKey: writeKey(t, dir, "k1", rsaKey)},
+	}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 使用公钥作为 HMAC 密钥伪造令牌
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"uid": 1})
+	forged.Header["kid"] = "k1"
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	signed, err := forged.SignedString(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keySet.Parse(signed); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("algorithm mismatch should be rejected, got %v", err)
+	}
+}